@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/arneph/mercury/logic/chips"
+)
+
+func runChips(args []string) int {
+	flags := newFlagSet("chips")
+	format := flags.String("format", "bom", "output format: bom, kicad")
+	args, ok := parseFlags(flags, args, 2, 2)
+	if !ok {
+		return 1
+	}
+	system, _, ok := loadSystem(args[0])
+	if !ok {
+		return 1
+	}
+	c, ok := lookupComponent(system, args[1])
+	if !ok {
+		return 1
+	}
+	board, err := chips.Pack(c)
+	if err != nil {
+		fmt.Printf("Could not pack %s: %v\n", c.Name(), err)
+		return 1
+	}
+	switch *format {
+	case "bom":
+		err = board.WriteBOM(os.Stdout)
+	case "kicad":
+		err = board.WriteKiCad(os.Stdout)
+	default:
+		fmt.Printf("Unknown chips format: %s\n", *format)
+		return 1
+	}
+	if err != nil {
+		fmt.Printf("Could not write %s: %v\n", *format, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/arneph/mercury/logic/dot"
+)
+
+func runDot(args []string) int {
+	flags := newFlagSet("dot")
+	flat := flags.Bool("flat", false, "render the collapsed NAND netlist instead of the component's instances")
+	args, ok := parseFlags(flags, args, 2, 2)
+	if !ok {
+		return 1
+	}
+	system, _, ok := loadSystem(args[0])
+	if !ok {
+		return 1
+	}
+	c, ok := lookupComponent(system, args[1])
+	if !ok {
+		return 1
+	}
+	var err error
+	if *flat {
+		err = dot.WriteFlat(os.Stdout, c)
+	} else {
+		err = dot.Write(os.Stdout, c)
+	}
+	if err != nil {
+		fmt.Printf("Could not render %s: %v\n", c.Name(), err)
+		return 1
+	}
+	return 0
+}
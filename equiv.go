@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/arneph/mercury/logic/equiv"
+)
+
+func runEquiv(args []string) int {
+	flags := newFlagSet("equiv")
+	args, ok := parseFlags(flags, args, 3, 3)
+	if !ok {
+		return 1
+	}
+	system, _, ok := loadSystem(args[0])
+	if !ok {
+		return 1
+	}
+	a, ok := lookupComponent(system, args[1])
+	if !ok {
+		return 1
+	}
+	b, ok := lookupComponent(system, args[2])
+	if !ok {
+		return 1
+	}
+	counterexample, err := equiv.Check(a, b)
+	if err != nil {
+		fmt.Printf("Could not check equivalence: %v\n", err)
+		return 1
+	} else if counterexample != nil {
+		fmt.Println(counterexample)
+		return 1
+	}
+	fmt.Printf("%s and %s are equivalent\n", a.Name(), b.Name())
+	return 0
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/aiger"
+	"github.com/arneph/mercury/logic/blif"
+	"github.com/arneph/mercury/logic/json"
+	"github.com/arneph/mercury/logic/logisim"
+	"github.com/arneph/mercury/logic/print"
+	"github.com/arneph/mercury/logic/sat"
+	"github.com/arneph/mercury/logic/verilog"
+)
+
+func runExport(args []string) int {
+	flags := newFlagSet("export")
+	format := flags.String("format", "verilog", "output format: verilog, blif, aag, aig, circ, dimacs, smtlib, json, mercury")
+	flat := flags.Bool("flat", false, "export the collapsed NAND netlist instead of the component hierarchy")
+	args, ok := parseFlags(flags, args, 2, 3)
+	if !ok {
+		return 1
+	}
+	system, _, ok := loadSystem(args[0])
+	if !ok {
+		return 1
+	}
+	c, ok := lookupComponent(system, args[1])
+	if !ok {
+		return 1
+	}
+	if len(args) == 3 {
+		return exportMiter(*format, system, c, args[2])
+	}
+	var err error
+	switch *format {
+	case "verilog":
+		if *flat {
+			err = verilog.WriteFlat(os.Stdout, c)
+		} else {
+			err = verilog.Write(os.Stdout, c)
+		}
+	case "blif":
+		err = blif.Write(os.Stdout, c)
+	case "aag":
+		err = aiger.Write(os.Stdout, c)
+	case "aig":
+		err = aiger.WriteBinary(os.Stdout, c)
+	case "circ":
+		err = logisim.Write(os.Stdout, system, c.Name())
+	case "dimacs", "smtlib":
+		f := sat.NewCNF()
+		var circuit *sat.Circuit
+		circuit, err = sat.Encode(f, c)
+		if err == nil {
+			err = writeCNF(*format, f, circuit)
+		}
+	case "mercury":
+		if *flat {
+			err = print.Write(os.Stdout, c.Collapse(c.Name()))
+		} else {
+			err = print.Write(os.Stdout, c)
+		}
+	case "json":
+		subset := logic.NewSystem()
+		subset.AddComponent(c)
+		var data []byte
+		data, err = json.Marshal(subset)
+		if err == nil {
+			_, err = fmt.Println(string(data))
+		}
+	default:
+		fmt.Printf("Unknown export format: %s\n", *format)
+		return 1
+	}
+	if err != nil {
+		fmt.Printf("Could not export %s: %v\n", c.Name(), err)
+		return 1
+	}
+	return 0
+}
+
+func exportMiter(format string, system *logic.System, a *logic.Component, name string) int {
+	b, ok := lookupComponent(system, name)
+	if !ok {
+		return 1
+	} else if format != "dimacs" && format != "smtlib" {
+		fmt.Printf("Export format %s does not support a miter of two components\n", format)
+		return 1
+	}
+	f := sat.NewCNF()
+	ca, cb, err := sat.Miter(f, a, b)
+	if err == nil {
+		err = writeCNF(format, f, ca, cb)
+	}
+	if err != nil {
+		fmt.Printf("Could not export miter of %s and %s: %v\n", a.Name(), b.Name(), err)
+		return 1
+	}
+	return 0
+}
+
+func writeCNF(format string, f *sat.CNF, circuits ...*sat.Circuit) error {
+	if format == "smtlib" {
+		return sat.WriteSMTLIB(os.Stdout, f, circuits...)
+	}
+	return sat.WriteDIMACS(os.Stdout, f, circuits...)
+}
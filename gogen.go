@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/arneph/mercury/logic/gogen"
+)
+
+func runGenGo(args []string) int {
+	flags := newFlagSet("gen-go")
+	pkg := flags.String("package", "main", "name of the generated Go package")
+	args, ok := parseFlags(flags, args, 2, 2)
+	if !ok {
+		return 1
+	}
+	system, _, ok := loadSystem(args[0])
+	if !ok {
+		return 1
+	}
+	c, ok := lookupComponent(system, args[1])
+	if !ok {
+		return 1
+	}
+	if err := gogen.Write(os.Stdout, c, *pkg); err != nil {
+		fmt.Printf("Could not generate Go source for %s: %v\n", c.Name(), err)
+		return 1
+	}
+	return 0
+}
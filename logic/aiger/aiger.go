@@ -0,0 +1,12 @@
+package aiger
+
+type latch struct {
+	lit  uint
+	next uint
+}
+
+type and struct {
+	lhs  uint
+	rhs0 uint
+	rhs1 uint
+}
@@ -0,0 +1,230 @@
+package aiger
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/internal/logictest"
+	"github.com/arneph/mercury/logic/simulation"
+)
+
+const src = logictest.Gates + logictest.Add1 + logictest.Add4 + logictest.Memory1 + `
+component Flags(a)(one, zero, na) {
+    one: 1
+    zero: 0
+    na: Not(a)
+}
+`
+
+func TestWrite(t *testing.T) {
+	system := logictest.Build(t, src)
+	var sb strings.Builder
+	if err := Write(&sb, system.Components["Memory1"]); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	expected := `
+aag 5 2 1 2 2
+2
+4
+6 11
+6
+9
+8 6 5
+10 9 3
+i0 s
+i1 r
+l0 q
+o0 q
+o1 'q
+`[1:]
+	if actual := sb.String(); actual != expected {
+		t.Errorf("Write() = %q; want %q", actual, expected)
+	}
+}
+
+func TestWriteBinary(t *testing.T) {
+	system := logictest.Build(t, src)
+	var buf bytes.Buffer
+	if err := WriteBinary(&buf, system.Components["And"]); err != nil {
+		t.Fatalf("WriteBinary() failed: %v", err)
+	}
+	expected := "aig 3 2 0 1 1\n6\n\x02\x02i0 a\ni1 b\no0 r\n"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("WriteBinary() = %q; want %q", actual, expected)
+	}
+}
+
+func wireValues(m, n int) *logic.Constants {
+	values := make([]logic.Value, n)
+	for i := range values {
+		values[i] = logic.Value{(m>>i)&1 == 1}
+	}
+	return logic.NewConstants(values)
+}
+
+func checkEquivalent(t *testing.T, c, result *logic.Component) {
+	t.Helper()
+	if result.InputWires() != c.InputWires() || result.OutputWires() != c.OutputWires() {
+		t.Fatalf("Read() produced %d inputs and %d outputs; want %d and %d",
+			result.InputWires(), result.OutputWires(), c.InputWires(), c.OutputWires())
+	}
+	inputs := make([]*logic.Constants, 1<<c.InputWires())
+	for m := range inputs {
+		inputs[m] = wireValues(m, c.InputWires())
+	}
+	expected, err := simulation.SimulateBatch(c.Collapse(c.Name()), inputs)
+	if err != nil {
+		t.Fatalf("SimulateBatch() failed: %v", err)
+	}
+	actual, err := simulation.SimulateBatch(result.Collapse(result.Name()), inputs)
+	if err != nil {
+		t.Fatalf("SimulateBatch() failed: %v", err)
+	}
+	for m := range inputs {
+		if e, a := expected[m].String(), actual[m].String(); e != a {
+			t.Errorf("input %#x: got %s; want %s", m, a, e)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	system := logictest.Build(t, src)
+	for _, name := range []string{"Add1", "Add4", "Flags"} {
+		c := system.Components[name]
+		for _, write := range []func(*bytes.Buffer, *logic.Component) error{
+			func(buf *bytes.Buffer, c *logic.Component) error { return Write(buf, c) },
+			func(buf *bytes.Buffer, c *logic.Component) error { return WriteBinary(buf, c) },
+		} {
+			var buf bytes.Buffer
+			if err := write(&buf, c); err != nil {
+				t.Fatalf("Write() failed: %v", err)
+			}
+			result, err := Read(&buf, name)
+			if err != nil {
+				t.Fatalf("Read() failed: %v", err)
+			}
+			checkEquivalent(t, c, result)
+		}
+	}
+}
+
+func TestRoundTripLatch(t *testing.T) {
+	system := logictest.Build(t, src)
+	c := system.Components["Memory1"]
+	var buf bytes.Buffer
+	if err := Write(&buf, c); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	result, err := Read(&buf, "Memory1")
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if len(result.Instances) != 4 {
+		t.Errorf("Read() produced %d instances; want 4:\n%s", len(result.Instances), result)
+	}
+	if len(logic.FindLatches(result)) != 1 {
+		t.Errorf("Read() did not reconstruct the latch:\n%s", result)
+	}
+	var states []*simulation.ComponentState
+	for _, c := range []*logic.Component{c, result} {
+		s, err := simulation.NewComponentState(c.Collapse(c.Name()))
+		if err != nil {
+			t.Fatalf("NewComponentState() failed: %v", err)
+		}
+		states = append(states, s)
+	}
+	for i, m := range []int{1, 0, 2, 0, 1, 0} {
+		for _, s := range states {
+			if err := s.SetInputs(wireValues(m, 2)); err != nil {
+				t.Fatalf("SetInputs() failed: %v", err)
+			}
+		}
+		if e, a := states[0].Outputs().String(), states[1].Outputs().String(); e != a {
+			t.Errorf("step %d: got %s; want %s", i, a, e)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	src := `aag 7 2 0 4 3
+2
+4
+7
+1
+4
+14
+6 2 4
+12 3 5
+14 7 13
+o0 nand
+o2 b
+c
+comment
+`
+	c, err := Read(strings.NewReader(src), "Gates")
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if names := strings.Join(c.InputBusNames, " "); names != "i0 i1" {
+		t.Errorf("unexpected input names: %s", names)
+	}
+	if names := strings.Join(c.OutputBusNames, " "); names != "nand o1 b o3" {
+		t.Errorf("unexpected output names: %s", names)
+	}
+	inputs := make([]*logic.Constants, 4)
+	for m := range inputs {
+		inputs[m] = wireValues(m, 2)
+	}
+	results, err := simulation.SimulateBatch(c, inputs)
+	if err != nil {
+		t.Fatalf("SimulateBatch() failed: %v", err)
+	}
+	for m, result := range results {
+		a, b := m&1 == 1, m&2 == 2
+		outputs := result.Values()
+		expected := []bool{!(a && b), true, b, a != b}
+		for i := range expected {
+			if outputs[i][0] != expected[i] {
+				t.Errorf("input %d: output %d = %v; want %v", m, i, outputs[i][0], expected[i])
+			}
+		}
+	}
+}
+
+func TestReadClockedLatch(t *testing.T) {
+	f, err := os.Open("testdata/toggle.aag")
+	if err != nil {
+		t.Fatalf("could not open toggle.aag: %v", err)
+	}
+	defer f.Close()
+	if _, err := Read(f, "Toggle"); err == nil || !strings.Contains(err.Error(), "latch q") {
+		t.Errorf("Read() = %v; want error for latch q", err)
+	}
+	register := "aag 2 1 1 1 0\n2\n4 2\n4\n"
+	if _, err := Read(strings.NewReader(register), "Register"); err == nil {
+		t.Errorf("Read() succeeded for %q", register)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"aag 1 1 0 0\n2\n",
+		"aag 1 1 0 1 0\n2\n4\n",
+		"aag 1 1 0 0 0\n3\n",
+		"aag 2 1 0 1 1\n2\n4\n4 4 2\n",
+		"aag 2 2 0 0 0\n2\n2\n",
+		"aig 3 2 0 1 0\n6\n",
+		"aig 1 1 0 1 1\n2\n\x02",
+		"aag 1 1 0 0 0 1\n2\n",
+		"aag 1 1 0 0 0\n2\nx0 a\n",
+		"aag 1 0 0 1 0\n2\n",
+	} {
+		if _, err := Read(strings.NewReader(src), "Bad"); err == nil {
+			t.Errorf("Read() succeeded for %q", src)
+		}
+	}
+}
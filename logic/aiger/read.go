@@ -0,0 +1,484 @@
+package aiger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/synth"
+)
+
+func Read(r io.Reader, name string) (*logic.Component, error) {
+	f, err := parse(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	return newBuilder(f, name).build()
+}
+
+type file struct {
+	maxVar      uint
+	inputs      []uint
+	latches     []latch
+	outputs     []uint
+	ands        map[uint]and
+	inputNames  map[int]string
+	latchNames  map[int]string
+	outputNames map[int]string
+}
+
+type parser struct {
+	r    *bufio.Reader
+	line int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) readLine() (string, bool, error) {
+	line, err := p.r.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return "", false, nil
+	} else if err != nil && err != io.EOF {
+		return "", false, err
+	}
+	p.line++
+	return strings.TrimRight(line, "\r\n"), true, nil
+}
+
+func (p *parser) readNumbers(n int, optional int) ([]uint, error) {
+	line, ok, err := p.readLine()
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("unexpected end of file")
+	}
+	fields := strings.Fields(line)
+	if len(fields) < n || len(fields) > n+optional {
+		return nil, p.errorf("expected %d numbers, got: %q", n, line)
+	}
+	numbers := make([]uint, len(fields))
+	for i, field := range fields {
+		x, err := strconv.ParseUint(field, 10, 0)
+		if err != nil {
+			return nil, p.errorf("invalid number: %s", field)
+		}
+		numbers[i] = uint(x)
+	}
+	return numbers, nil
+}
+
+func parse(r *bufio.Reader) (*file, error) {
+	p := &parser{r: r}
+	line, ok, err := p.readLine()
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("empty file")
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 6 || (fields[0] != "aag" && fields[0] != "aig") {
+		return nil, p.errorf("invalid header: %q", line)
+	}
+	var header [5]uint
+	for i := range header {
+		x, err := strconv.ParseUint(fields[1+i], 10, 0)
+		if err != nil {
+			return nil, p.errorf("invalid header: %q", line)
+		}
+		header[i] = uint(x)
+	}
+	for _, field := range fields[6:] {
+		if field != "0" {
+			return nil, p.errorf("unsupported header extension: %q", line)
+		}
+	}
+	binary := fields[0] == "aig"
+	f := &file{
+		maxVar:      header[0],
+		ands:        make(map[uint]and),
+		inputNames:  make(map[int]string),
+		latchNames:  make(map[int]string),
+		outputNames: make(map[int]string),
+	}
+	numInputs, numLatches, numOutputs, numAnds := header[1], header[2], header[3], header[4]
+	if binary && f.maxVar != numInputs+numLatches+numAnds {
+		return nil, p.errorf("maximum variable index %d does not match %d inputs, %d latches and %d and gates", f.maxVar, numInputs, numLatches, numAnds)
+	}
+	checkLit := func(lit uint) error {
+		if lit > 2*f.maxVar+1 {
+			return p.errorf("literal %d exceeds maximum variable index %d", lit, f.maxVar)
+		}
+		return nil
+	}
+	defined := make(map[uint]bool)
+	define := func(lit uint) error {
+		if err := checkLit(lit); err != nil {
+			return err
+		} else if lit < 2 || lit&1 == 1 {
+			return p.errorf("invalid definition of literal %d", lit)
+		} else if defined[lit/2] {
+			return p.errorf("redefinition of variable %d", lit/2)
+		}
+		defined[lit/2] = true
+		return nil
+	}
+	for i := uint(0); i < numInputs; i++ {
+		lit := 2 * (i + 1)
+		if !binary {
+			numbers, err := p.readNumbers(1, 0)
+			if err != nil {
+				return nil, err
+			}
+			lit = numbers[0]
+		}
+		if err := define(lit); err != nil {
+			return nil, err
+		}
+		f.inputs = append(f.inputs, lit)
+	}
+	for i := uint(0); i < numLatches; i++ {
+		var l latch
+		if binary {
+			numbers, err := p.readNumbers(1, 1)
+			if err != nil {
+				return nil, err
+			}
+			l = latch{lit: 2 * (numInputs + i + 1), next: numbers[0]}
+		} else {
+			numbers, err := p.readNumbers(2, 1)
+			if err != nil {
+				return nil, err
+			}
+			l = latch{lit: numbers[0], next: numbers[1]}
+		}
+		if err := define(l.lit); err != nil {
+			return nil, err
+		} else if err := checkLit(l.next); err != nil {
+			return nil, err
+		}
+		f.latches = append(f.latches, l)
+	}
+	for i := uint(0); i < numOutputs; i++ {
+		numbers, err := p.readNumbers(1, 0)
+		if err != nil {
+			return nil, err
+		} else if err := checkLit(numbers[0]); err != nil {
+			return nil, err
+		}
+		f.outputs = append(f.outputs, numbers[0])
+	}
+	for i := uint(0); i < numAnds; i++ {
+		var a and
+		if binary {
+			a.lhs = 2 * (numInputs + numLatches + i + 1)
+			delta0, err := readDelta(p.r)
+			if err != nil {
+				return nil, err
+			}
+			delta1, err := readDelta(p.r)
+			if err != nil {
+				return nil, err
+			}
+			if delta0 > a.lhs || delta1 > a.lhs-delta0 {
+				return nil, fmt.Errorf("invalid delta encoding of and gate %d", a.lhs)
+			}
+			a.rhs0 = a.lhs - delta0
+			a.rhs1 = a.rhs0 - delta1
+		} else {
+			numbers, err := p.readNumbers(3, 0)
+			if err != nil {
+				return nil, err
+			}
+			a = and{numbers[0], numbers[1], numbers[2]}
+		}
+		if err := define(a.lhs); err != nil {
+			return nil, err
+		} else if err := checkLit(a.rhs0); err != nil {
+			return nil, err
+		} else if err := checkLit(a.rhs1); err != nil {
+			return nil, err
+		}
+		f.ands[a.lhs/2] = a
+	}
+	for {
+		line, ok, err := p.readLine()
+		if err != nil {
+			return nil, err
+		} else if !ok || line == "c" {
+			break
+		}
+		kind, name, ok := strings.Cut(line, " ")
+		if !ok || len(kind) < 2 {
+			return nil, p.errorf("invalid symbol: %q", line)
+		}
+		index, err := strconv.Atoi(kind[1:])
+		if err != nil || index < 0 {
+			return nil, p.errorf("invalid symbol: %q", line)
+		}
+		var names map[int]string
+		var count int
+		switch kind[0] {
+		case 'i':
+			names, count = f.inputNames, len(f.inputs)
+		case 'l':
+			names, count = f.latchNames, len(f.latches)
+		case 'o':
+			names, count = f.outputNames, len(f.outputs)
+		case 'b', 'c', 'j', 'f':
+			return nil, p.errorf("unsupported symbol kind: %q", line)
+		default:
+			return nil, p.errorf("invalid symbol: %q", line)
+		}
+		if index >= count {
+			return nil, p.errorf("symbol index out of range: %q", line)
+		}
+		names[index] = name
+	}
+	return f, nil
+}
+
+func readDelta(r *bufio.Reader) (uint, error) {
+	var x uint
+	for shift := 0; ; shift += 7 {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return 0, fmt.Errorf("unexpected end of file in and gates")
+		} else if err != nil {
+			return 0, err
+		} else if shift > 56 {
+			return 0, fmt.Errorf("invalid delta encoding")
+		}
+		x |= uint(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return x, nil
+		}
+	}
+}
+
+type builder struct {
+	file      *file
+	component *logic.Component
+	used      map[string]bool
+	pos       map[uint]logic.BusWire
+	neg       map[uint]logic.BusWire
+	claims    map[uint]logic.BusWire
+	constants [2]*logic.BusWire
+	visiting  map[uint]bool
+}
+
+func newBuilder(f *file, name string) *builder {
+	b := &builder{
+		file:     f,
+		used:     make(map[string]bool),
+		pos:      make(map[uint]logic.BusWire),
+		neg:      make(map[uint]logic.BusWire),
+		claims:   make(map[uint]logic.BusWire),
+		visiting: make(map[uint]bool),
+	}
+	inputs := b.ports(f.inputs, f.inputNames, "i")
+	outputs := b.ports(f.outputs, f.outputNames, "o")
+	b.component = logic.NewComponent(synth.Identifier(name), inputs, outputs)
+	for i, input := range f.inputs {
+		b.pos[input/2] = logic.BusWire{Bus: inputs[i]}
+	}
+	return b
+}
+
+func (b *builder) ports(lits []uint, names map[int]string, prefix string) []*logic.Bus {
+	buses := make([]*logic.Bus, len(lits))
+	for i := range lits {
+		name, ok := names[i]
+		if !ok {
+			name = prefix + strconv.Itoa(i)
+		}
+		buses[i] = logic.NewBus(b.uniqueName(name), 1)
+	}
+	return buses
+}
+
+func (b *builder) uniqueName(name string) string {
+	name = synth.Identifier(name)
+	unique := name
+	for i := 1; b.used[unique]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	b.used[unique] = true
+	return unique
+}
+
+func (b *builder) build() (*logic.Component, error) {
+	for i, l := range b.file.latches {
+		if !b.file.isNandLatch(l) {
+			name, ok := b.file.latchNames[i]
+			if !ok {
+				name = "l" + strconv.Itoa(i)
+			}
+			return nil, fmt.Errorf("latch %s is not a cross-coupled NAND latch: clocked latches are not supported", name)
+		}
+	}
+	outputs := make([]logic.BusWire, len(b.file.outputs))
+	for i, name := range b.component.OutputBusNames {
+		outputs[i] = logic.BusWire{Bus: b.component.Buses[name]}
+	}
+	for i, l := range b.file.latches {
+		for j, output := range b.file.outputs {
+			if _, ok := b.pos[l.lit/2]; !ok && output == l.lit {
+				b.pos[l.lit/2] = outputs[j]
+			}
+		}
+		if _, ok := b.pos[l.lit/2]; !ok {
+			name, ok := b.file.latchNames[i]
+			if !ok {
+				name = "l" + strconv.Itoa(i)
+			}
+			b.pos[l.lit/2] = b.newWire(name)
+		}
+	}
+	for _, l := range b.file.latches {
+		b.claim(l.next, b.pos[l.lit/2])
+	}
+	for i, output := range b.file.outputs {
+		b.claim(output, outputs[i])
+	}
+	for _, l := range b.file.latches {
+		if err := b.drive(l.next, b.pos[l.lit/2]); err != nil {
+			return nil, err
+		}
+	}
+	for i, output := range b.file.outputs {
+		if err := b.drive(output, outputs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return b.component, nil
+}
+
+func (f *file) isNandLatch(l latch) bool {
+	if l.next&1 == 0 {
+		return false
+	}
+	a, ok := f.ands[l.next/2]
+	if !ok {
+		return false
+	}
+	for _, rhs := range []uint{a.rhs0, a.rhs1} {
+		if rhs&1 == 0 {
+			continue
+		}
+		if other, ok := f.ands[rhs/2]; ok && (other.rhs0 == l.lit || other.rhs1 == l.lit) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *builder) claim(lit uint, wire logic.BusWire) {
+	if _, ok := b.claims[lit]; !ok {
+		b.claims[lit] = wire
+	}
+}
+
+func (b *builder) target(lit uint, name string) logic.BusWire {
+	if wire, ok := b.claims[lit]; ok {
+		delete(b.claims, lit)
+		return wire
+	}
+	return b.newWire(name)
+}
+
+func (b *builder) newWire(name string) logic.BusWire {
+	bus := logic.NewBus(b.uniqueName(name), 1)
+	b.component.Buses[bus.Name] = bus
+	return logic.BusWire{Bus: bus}
+}
+
+func (b *builder) drive(lit uint, wire logic.BusWire) error {
+	if lit < 2 {
+		b.addConstant(lit, wire)
+		return nil
+	}
+	actual, err := b.literal(lit)
+	if err != nil {
+		return err
+	} else if actual == wire {
+		return nil
+	}
+	inverted := b.newWire("'" + wire.Bus.Name)
+	b.nand(actual, actual, inverted)
+	b.nand(inverted, inverted, wire)
+	return nil
+}
+
+func (b *builder) addConstant(lit uint, wire logic.BusWire) {
+	b.component.Instances = append(b.component.Instances, &logic.Instance{
+		Definition: logic.NewConstants([]logic.Value{{lit == 1}}),
+		Outputs:    []logic.BusWire{wire},
+	})
+}
+
+func (b *builder) literal(lit uint) (logic.BusWire, error) {
+	if lit < 2 {
+		if b.constants[lit] == nil {
+			wire := b.newWire([]string{"zero", "one"}[lit])
+			b.addConstant(lit, wire)
+			b.constants[lit] = &wire
+		}
+		return *b.constants[lit], nil
+	}
+	v := lit / 2
+	cache := b.pos
+	if lit&1 == 1 {
+		cache = b.neg
+	}
+	if wire, ok := cache[v]; ok {
+		return wire, nil
+	}
+	a, ok := b.file.ands[v]
+	var wire logic.BusWire
+	switch {
+	case !ok && lit&1 == 0:
+		return logic.BusWire{}, fmt.Errorf("undefined variable %d", v)
+	case !ok || lit&1 == 0:
+		other, err := b.literal(lit ^ 1)
+		if err != nil {
+			return logic.BusWire{}, err
+		}
+		if lit&1 == 0 {
+			wire = b.target(lit, "g"+strconv.Itoa(int(v)))
+		} else {
+			wire = b.target(lit, "'"+other.Bus.Name)
+		}
+		b.nand(other, other, wire)
+	default:
+		if b.visiting[v] {
+			return logic.BusWire{}, fmt.Errorf("cyclic definition of and gate %d", a.lhs)
+		}
+		b.visiting[v] = true
+		defer delete(b.visiting, v)
+		rhs0, err := b.literal(a.rhs0)
+		if err != nil {
+			return logic.BusWire{}, err
+		}
+		rhs1, err := b.literal(a.rhs1)
+		if err != nil {
+			return logic.BusWire{}, err
+		}
+		wire = b.target(lit, "'g"+strconv.Itoa(int(v)))
+		b.nand(rhs0, rhs1, wire)
+	}
+	cache[v] = wire
+	return wire, nil
+}
+
+func (b *builder) nand(a, c, r logic.BusWire) {
+	b.component.Instances = append(b.component.Instances, &logic.Instance{
+		Definition: logic.Nand,
+		Inputs:     []logic.BusWire{a, c},
+		Outputs:    []logic.BusWire{r},
+	})
+}
@@ -0,0 +1,200 @@
+package aiger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/arneph/mercury/logic"
+)
+
+func Write(w io.Writer, c *logic.Component) error {
+	g, err := newGraph(c)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "aag %d %d %d %d %d\n", g.maxVar(), len(g.inputs), len(g.latches), len(g.outputs), len(g.ands))
+	for _, input := range g.inputs {
+		fmt.Fprintf(bw, "%d\n", input)
+	}
+	for _, l := range g.latches {
+		fmt.Fprintf(bw, "%d %d\n", l.lit, l.next)
+	}
+	for _, output := range g.outputs {
+		fmt.Fprintf(bw, "%d\n", output)
+	}
+	for _, a := range g.ands {
+		fmt.Fprintf(bw, "%d %d %d\n", a.lhs, a.rhs0, a.rhs1)
+	}
+	g.writeSymbols(bw)
+	return bw.Flush()
+}
+
+func WriteBinary(w io.Writer, c *logic.Component) error {
+	g, err := newGraph(c)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "aig %d %d %d %d %d\n", g.maxVar(), len(g.inputs), len(g.latches), len(g.outputs), len(g.ands))
+	for _, l := range g.latches {
+		fmt.Fprintf(bw, "%d\n", l.next)
+	}
+	for _, output := range g.outputs {
+		fmt.Fprintf(bw, "%d\n", output)
+	}
+	for _, a := range g.ands {
+		writeDelta(bw, a.lhs-a.rhs0)
+		writeDelta(bw, a.rhs0-a.rhs1)
+	}
+	g.writeSymbols(bw)
+	return bw.Flush()
+}
+
+func writeDelta(bw *bufio.Writer, x uint) {
+	for x >= 0x80 {
+		bw.WriteByte(byte(x&0x7f | 0x80))
+		x >>= 7
+	}
+	bw.WriteByte(byte(x))
+}
+
+func (g *graph) writeSymbols(bw *bufio.Writer) {
+	for _, kind := range []struct {
+		prefix string
+		names  []string
+	}{
+		{"i", g.inputNames},
+		{"l", g.latchNames},
+		{"o", g.outputNames},
+	} {
+		for i, name := range kind.names {
+			fmt.Fprintf(bw, "%s%d %s\n", kind.prefix, i, name)
+		}
+	}
+}
+
+type graph struct {
+	component   *logic.Component
+	drivers     map[*logic.Bus]*logic.Instance
+	lits        map[*logic.Bus]uint
+	visiting    map[*logic.Instance]bool
+	inputs      []uint
+	latches     []latch
+	outputs     []uint
+	ands        []and
+	inputNames  []string
+	latchNames  []string
+	outputNames []string
+}
+
+func newGraph(c *logic.Component) (*graph, error) {
+	if c.HasTriGates() {
+		return nil, fmt.Errorf("%s: tri-state buffers are not supported", c.Name())
+	}
+	collapsed := c.Collapse(c.Name())
+	g := &graph{
+		component: collapsed,
+		drivers:   make(map[*logic.Bus]*logic.Instance),
+		lits:      make(map[*logic.Bus]uint),
+		visiting:  make(map[*logic.Instance]bool),
+	}
+	for _, instance := range collapsed.Instances {
+		for _, output := range instance.Outputs {
+			g.drivers[output.Bus] = instance
+		}
+	}
+	for _, name := range collapsed.InputBusNames {
+		lit := 2 * uint(1+len(g.inputs))
+		g.lits[collapsed.Buses[name]] = lit
+		g.inputs = append(g.inputs, lit)
+		g.inputNames = append(g.inputNames, name)
+	}
+	latches := logic.FindLatches(collapsed)
+	for _, l := range latches {
+		lit := 2 * uint(1+len(g.inputs)+len(g.latches))
+		g.lits[l.Q.Outputs[0].Bus] = lit
+		g.latches = append(g.latches, latch{lit: lit})
+		g.latchNames = append(g.latchNames, l.Q.Outputs[0].Bus.Name)
+	}
+	for i, l := range latches {
+		next, err := g.and(l.Q)
+		if err != nil {
+			return nil, err
+		}
+		g.latches[i].next = next
+	}
+	for _, name := range collapsed.OutputBusNames {
+		lit, err := g.lit(logic.BusWire{Bus: collapsed.Buses[name]})
+		if err != nil {
+			return nil, err
+		}
+		g.outputs = append(g.outputs, lit)
+		g.outputNames = append(g.outputNames, name)
+	}
+	return g, nil
+}
+
+func (g *graph) maxVar() int {
+	return len(g.inputs) + len(g.latches) + len(g.ands)
+}
+
+func (g *graph) lit(wire logic.BusWire) (uint, error) {
+	if lit, ok := g.lits[wire.Bus]; ok {
+		return lit, nil
+	}
+	instance, ok := g.drivers[wire.Bus]
+	if !ok {
+		return 0, fmt.Errorf("component %s has undriven bus %s", g.component.Name(), wire.Bus.Name)
+	} else if g.visiting[instance] {
+		return 0, fmt.Errorf("component %s contains a feedback loop through bus %s", g.component.Name(), wire.Bus.Name)
+	}
+	g.visiting[instance] = true
+	defer delete(g.visiting, instance)
+	var lit uint
+	switch def := instance.Definition.(type) {
+	case *logic.Constants:
+		var bits []bool
+		for _, value := range def.Values() {
+			bits = append(bits, value...)
+		}
+		for i, output := range instance.Outputs {
+			if output.Bus == wire.Bus && bits[i] {
+				lit = 1
+			}
+		}
+	case logic.NandGate:
+		var err error
+		if instance.Inputs[0] == instance.Inputs[1] {
+			lit, err = g.lit(instance.Inputs[0])
+			lit ^= 1
+		} else {
+			lit, err = g.and(instance)
+		}
+		if err != nil {
+			return 0, err
+		}
+	default:
+		panic(fmt.Errorf("unexpected logic.Definition: %t", def))
+	}
+	g.lits[wire.Bus] = lit
+	return lit, nil
+}
+
+func (g *graph) and(instance *logic.Instance) (uint, error) {
+	rhs0, err := g.lit(instance.Inputs[0])
+	if err != nil {
+		return 0, err
+	}
+	rhs1, err := g.lit(instance.Inputs[1])
+	if err != nil {
+		return 0, err
+	}
+	if rhs0 < rhs1 {
+		rhs0, rhs1 = rhs1, rhs0
+	}
+	lhs := 2 * uint(1+g.maxVar())
+	g.ands = append(g.ands, and{lhs, rhs0, rhs1})
+	return lhs + 1, nil
+}
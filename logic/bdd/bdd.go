@@ -0,0 +1,283 @@
+package bdd
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+type Node int32
+
+const (
+	False Node = 0
+	True  Node = 1
+)
+
+const terminalLevel = math.MaxInt32
+
+type node struct {
+	level     int32
+	low, high Node
+}
+
+type iteKey struct {
+	f, g, h Node
+}
+
+type restrictKey struct {
+	f     Node
+	level int32
+	value bool
+}
+
+type Manager struct {
+	vars          int
+	nodes         []node
+	unique        map[node]Node
+	iteCache      map[iteKey]Node
+	restrictCache map[restrictKey]Node
+	maxNodes      int
+	exceeded      bool
+}
+
+const DefaultMaxNodes = 1 << 22
+
+func NewManager(vars int) *Manager {
+	return &Manager{
+		vars: vars,
+		nodes: []node{
+			{level: terminalLevel, low: False, high: False},
+			{level: terminalLevel, low: True, high: True},
+		},
+		unique:        make(map[node]Node),
+		iteCache:      make(map[iteKey]Node),
+		restrictCache: make(map[restrictKey]Node),
+		maxNodes:      DefaultMaxNodes,
+	}
+}
+
+func (m *Manager) SetMaxNodes(maxNodes int) {
+	m.maxNodes = maxNodes
+}
+
+func (m *Manager) Vars() int {
+	return m.vars
+}
+
+func (m *Manager) Nodes() int {
+	return len(m.nodes)
+}
+
+func (m *Manager) Err() error {
+	if m.exceeded {
+		return fmt.Errorf("BDD exceeds node limit of %d", m.maxNodes)
+	}
+	return nil
+}
+
+func (m *Manager) mk(level int32, low, high Node) Node {
+	if low == high {
+		return low
+	}
+	n := node{level: level, low: low, high: high}
+	if id, ok := m.unique[n]; ok {
+		return id
+	}
+	if len(m.nodes) >= m.maxNodes {
+		m.exceeded = true
+		return False
+	}
+	id := Node(len(m.nodes))
+	m.nodes = append(m.nodes, n)
+	m.unique[n] = id
+	return id
+}
+
+func (m *Manager) Var(i int) Node {
+	if i < 0 || i >= m.vars {
+		panic(fmt.Errorf("variable out of range: %d", i))
+	}
+	return m.mk(int32(i), False, True)
+}
+
+func (m *Manager) Level(f Node) int {
+	return int(m.nodes[f].level)
+}
+
+func (m *Manager) Low(f Node) Node {
+	return m.nodes[f].low
+}
+
+func (m *Manager) High(f Node) Node {
+	return m.nodes[f].high
+}
+
+func (m *Manager) cofactors(f Node, level int32) (Node, Node) {
+	n := m.nodes[f]
+	if n.level != level {
+		return f, f
+	}
+	return n.low, n.high
+}
+
+func (m *Manager) ITE(f, g, h Node) Node {
+	switch {
+	case f == True:
+		return g
+	case f == False:
+		return h
+	case g == h:
+		return g
+	case g == True && h == False:
+		return f
+	}
+	key := iteKey{f, g, h}
+	if r, ok := m.iteCache[key]; ok {
+		return r
+	}
+	level := min(m.nodes[f].level, m.nodes[g].level, m.nodes[h].level)
+	f0, f1 := m.cofactors(f, level)
+	g0, g1 := m.cofactors(g, level)
+	h0, h1 := m.cofactors(h, level)
+	r := m.mk(level, m.ITE(f0, g0, h0), m.ITE(f1, g1, h1))
+	m.iteCache[key] = r
+	return r
+}
+
+func (m *Manager) Not(f Node) Node {
+	return m.ITE(f, False, True)
+}
+
+func (m *Manager) And(f, g Node) Node {
+	return m.ITE(f, g, False)
+}
+
+func (m *Manager) Or(f, g Node) Node {
+	return m.ITE(f, True, g)
+}
+
+func (m *Manager) Xor(f, g Node) Node {
+	return m.ITE(f, m.Not(g), g)
+}
+
+func (m *Manager) Nand(f, g Node) Node {
+	return m.ITE(f, m.Not(g), True)
+}
+
+type Op func(a, b bool) bool
+
+func (m *Manager) Apply(op Op, f, g Node) Node {
+	var table [2][2]Node
+	for a := 0; a < 2; a++ {
+		for b := 0; b < 2; b++ {
+			if op(a == 1, b == 1) {
+				table[a][b] = True
+			} else {
+				table[a][b] = False
+			}
+		}
+	}
+	return m.ITE(f, m.ITE(g, table[1][1], table[1][0]), m.ITE(g, table[0][1], table[0][0]))
+}
+
+func (m *Manager) Restrict(f Node, v int, value bool) Node {
+	n := m.nodes[f]
+	if n.level > int32(v) {
+		return f
+	} else if n.level == int32(v) {
+		if value {
+			return n.high
+		}
+		return n.low
+	}
+	key := restrictKey{f, int32(v), value}
+	if r, ok := m.restrictCache[key]; ok {
+		return r
+	}
+	r := m.mk(n.level, m.Restrict(n.low, v, value), m.Restrict(n.high, v, value))
+	m.restrictCache[key] = r
+	return r
+}
+
+func (m *Manager) Eval(f Node, assignment []bool) bool {
+	for f != True && f != False {
+		n := m.nodes[f]
+		if assignment[n.level] {
+			f = n.high
+		} else {
+			f = n.low
+		}
+	}
+	return f == True
+}
+
+func (m *Manager) SatCount(f Node) *big.Int {
+	counts := make(map[Node]*big.Int)
+	var count func(f Node) *big.Int
+	count = func(f Node) *big.Int {
+		if f == False {
+			return big.NewInt(0)
+		} else if f == True {
+			return big.NewInt(1)
+		} else if c, ok := counts[f]; ok {
+			return c
+		}
+		n := m.nodes[f]
+		c := new(big.Int).Lsh(count(n.low), uint(m.levelOf(n.low)-int(n.level)-1))
+		c.Add(c, new(big.Int).Lsh(count(n.high), uint(m.levelOf(n.high)-int(n.level)-1)))
+		counts[f] = c
+		return c
+	}
+	return new(big.Int).Lsh(count(f), uint(m.levelOf(f)))
+}
+
+func (m *Manager) levelOf(f Node) int {
+	if f == True || f == False {
+		return m.vars
+	}
+	return int(m.nodes[f].level)
+}
+
+func (m *Manager) Size(f Node) int {
+	visited := make(map[Node]struct{})
+	var visit func(f Node)
+	visit = func(f Node) {
+		if _, ok := visited[f]; ok {
+			return
+		}
+		visited[f] = struct{}{}
+		if f != True && f != False {
+			visit(m.nodes[f].low)
+			visit(m.nodes[f].high)
+		}
+	}
+	visit(f)
+	return len(visited)
+}
+
+type Cube []int8
+
+func (m *Manager) Cubes(f Node) []Cube {
+	var cubes []Cube
+	cube := make(Cube, m.vars)
+	for i := range cube {
+		cube[i] = -1
+	}
+	var visit func(f Node)
+	visit = func(f Node) {
+		if f == False {
+			return
+		} else if f == True {
+			cubes = append(cubes, append(Cube(nil), cube...))
+			return
+		}
+		n := m.nodes[f]
+		cube[n.level] = 0
+		visit(n.low)
+		cube[n.level] = 1
+		visit(n.high)
+		cube[n.level] = -1
+	}
+	visit(f)
+	return cubes
+}
@@ -0,0 +1,180 @@
+package bdd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+const src = logictest.Gates + logictest.Xor2 + `
+component Or3(a, b, c)(r) {
+    i: Or(a, b)
+    r: Or(i, c)
+}
+
+component Add1(a, b, ci)(r, co) {
+    i1: Xor(a, b)
+    r: Xor(i1, ci)
+    i2: And(a, b)
+    i3: And(a, ci)
+    i4: And(b, ci)
+    co: Or3(i2, i3, i4)
+}
+
+component Tautology(a)(r, z) {
+    'a: Not(a)
+    r: nand(a, 'a)
+    z: Not(r)
+}
+
+component Add8(a[8], b[8])(r[8], c) {
+    define d[9]
+    'a: Not(a[0])
+    d[0]: And(a[0], 'a)
+    for i from 0 to 7 {
+        r[i], d[i + 1]: Add1(a[i], b[i], d[i])
+    }
+    c: Or(d[8], d[0])
+}
+`
+
+func TestOperations(t *testing.T) {
+	m := NewManager(3)
+	a, b, c := m.Var(0), m.Var(1), m.Var(2)
+	f := m.Or(m.And(a, b), c)
+	if got := m.SatCount(f).Int64(); got != 5 {
+		t.Errorf("SatCount(a & b | c) = %d; want 5", got)
+	}
+	if got := m.Restrict(f, 2, true); got != True {
+		t.Errorf("Restrict(a & b | c, c = 1) = %d; want True", got)
+	}
+	if got, want := m.Restrict(f, 0, true), m.Or(b, c); got != want {
+		t.Errorf("Restrict(a & b | c, a = 1) = %d; want %d", got, want)
+	}
+	if got, want := m.Xor(a, b), m.Apply(func(x, y bool) bool { return x != y }, a, b); got != want {
+		t.Errorf("Xor(a, b) = %d; Apply(!=, a, b) = %d", got, want)
+	}
+	if got := m.And(a, m.Not(a)); got != False {
+		t.Errorf("And(a, !a) = %d; want False", got)
+	}
+	if got := m.ITE(a, b, b); got != b {
+		t.Errorf("ITE(a, b, b) = %d; want %d", got, b)
+	}
+	if !m.Eval(f, []bool{true, true, false}) || m.Eval(f, []bool{true, false, false}) {
+		t.Errorf("Eval(a & b | c) returned wrong values")
+	}
+	if got := len(m.Cubes(f)); got != 3 {
+		t.Errorf("len(Cubes(a & b | c)) = %d; want 3", got)
+	}
+}
+
+func TestBuildsOutputFunctions(t *testing.T) {
+	system := logictest.Build(t, src)
+	for _, ordering := range []Ordering{DECLARATION_ORDER, INTERLEAVED_ORDER, FANIN_ORDER} {
+		c, err := Build(system.Components["Add1"], ordering)
+		if err != nil {
+			t.Fatalf("Build(Add1, %v) failed: %v", ordering, err)
+		}
+		m := c.Manager
+		a, b, ci := c.Inputs[0], c.Inputs[1], c.Inputs[2]
+		r := m.Xor(m.Xor(a, b), ci)
+		co := m.Or(m.And(a, b), m.And(ci, m.Xor(a, b)))
+		if c.Outputs[0] != r {
+			t.Errorf("Build(Add1, %v) r does not match a ^ b ^ ci", ordering)
+		}
+		if c.Outputs[1] != co {
+			t.Errorf("Build(Add1, %v) co does not match majority(a, b, ci)", ordering)
+		}
+	}
+}
+
+func TestDetectsConstantOutputs(t *testing.T) {
+	system := logictest.Build(t, src)
+	c, err := Build(system.Components["Tautology"], DECLARATION_ORDER)
+	if err != nil {
+		t.Fatalf("Build(Tautology) failed: %v", err)
+	}
+	if value, ok := c.IsConstant(0); !ok || !value {
+		t.Errorf("IsConstant(r) = %v, %v; want true, true", value, ok)
+	}
+	if value, ok := c.IsConstant(1); !ok || value {
+		t.Errorf("IsConstant(z) = %v, %v; want false, true", value, ok)
+	}
+
+	c, err = Build(system.Components["Or3"], DECLARATION_ORDER)
+	if err != nil {
+		t.Fatalf("Build(Or3) failed: %v", err)
+	}
+	if _, ok := c.IsConstant(0); ok {
+		t.Errorf("IsConstant(r) of Or3 = true; want false")
+	}
+}
+
+func TestComparesFunctions(t *testing.T) {
+	system := logictest.Build(t, src)
+	equivalent, err := Equivalent(system.Components["Xor"], system.Components["Xor2"], FANIN_ORDER)
+	if err != nil {
+		t.Fatalf("Equivalent() failed: %v", err)
+	}
+	if !equivalent {
+		t.Errorf("Equivalent(Xor, Xor2) = false; want true")
+	}
+	equivalent, err = Equivalent(system.Components["Xor"], system.Components["And"], FANIN_ORDER)
+	if err != nil {
+		t.Fatalf("Equivalent() failed: %v", err)
+	}
+	if equivalent {
+		t.Errorf("Equivalent(Xor, And) = true; want false")
+	}
+	if _, err := Equivalent(system.Components["Xor"], system.Components["Not"], FANIN_ORDER); err == nil {
+		t.Errorf("Equivalent(Xor, Not) succeeded; want error")
+	}
+}
+
+func TestInterleavedOrderKeepsAddersSmall(t *testing.T) {
+	system := logictest.Build(t, src)
+	interleaved, err := Build(system.Components["Add8"], INTERLEAVED_ORDER)
+	if err != nil {
+		t.Fatalf("Build(Add8, interleaved) failed: %v", err)
+	}
+	declaration, err := Build(system.Components["Add8"], DECLARATION_ORDER)
+	if err != nil {
+		t.Fatalf("Build(Add8, declaration) failed: %v", err)
+	}
+	if interleaved.Size() >= declaration.Size() {
+		t.Errorf("interleaved size = %d, declaration size = %d; want interleaved to be smaller", interleaved.Size(), declaration.Size())
+	}
+	if got := interleaved.Manager.SatCount(interleaved.Outputs[8]).Int64(); got != (1<<16-1<<8)/2 {
+		t.Errorf("SatCount(c) = %d; want %d", got, (1<<16-1<<8)/2)
+	}
+}
+
+func TestRespectsNodeLimit(t *testing.T) {
+	system := logictest.Build(t, src)
+	m := NewManager(16)
+	m.SetMaxNodes(100)
+	vars := make([]int, 16)
+	for i := range vars {
+		vars[i] = i
+	}
+	if _, err := m.Build(system.Components["Add8"], vars); err == nil {
+		t.Errorf("Build() succeeded; want node limit error")
+	}
+}
+
+func TestWritesTruthTable(t *testing.T) {
+	system := logictest.Build(t, src)
+	c, err := Build(system.Components["Xor"], INTERLEAVED_ORDER)
+	if err != nil {
+		t.Fatalf("Build(Xor) failed: %v", err)
+	}
+	var sb strings.Builder
+	if err := c.WriteTruthTable(&sb); err != nil {
+		t.Fatalf("WriteTruthTable() failed: %v", err)
+	}
+	expected := "a, b, r\n0, 0, 0\n1, 0, 1\n0, 1, 1\n1, 1, 0\n"
+	if sb.String() != expected {
+		t.Errorf("WriteTruthTable() = %q; want %q", sb.String(), expected)
+	}
+}
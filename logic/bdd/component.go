@@ -0,0 +1,264 @@
+package bdd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+type Ordering int
+
+const (
+	DECLARATION_ORDER Ordering = iota
+	INTERLEAVED_ORDER
+	FANIN_ORDER
+)
+
+func (o Ordering) String() string {
+	switch o {
+	case DECLARATION_ORDER:
+		return "declaration"
+	case INTERLEAVED_ORDER:
+		return "interleaved"
+	case FANIN_ORDER:
+		return "fanin"
+	default:
+		panic(fmt.Errorf("unexpected bdd.Ordering: %d", o))
+	}
+}
+
+type Circuit struct {
+	Component *logic.Component
+	Manager   *Manager
+	Inputs    []Node
+	Outputs   []Node
+}
+
+func Build(c *logic.Component, ordering Ordering) (*Circuit, error) {
+	collapsed := c.Collapse(c.Name())
+	vars, err := variableOrder(c, collapsed, ordering)
+	if err != nil {
+		return nil, err
+	}
+	m := NewManager(len(vars))
+	return m.build(c, collapsed, vars)
+}
+
+func (m *Manager) Build(c *logic.Component, vars []int) (*Circuit, error) {
+	if len(vars) != c.InputWires() {
+		return nil, fmt.Errorf("wrong number of variables for %s: expected %d, got %d", c.Name(), c.InputWires(), len(vars))
+	}
+	return m.build(c, c.Collapse(c.Name()), vars)
+}
+
+func (m *Manager) build(c, collapsed *logic.Component, vars []int) (*Circuit, error) {
+	if c.HasTriGates() {
+		return nil, fmt.Errorf("%s: tri-state buffers are not supported", c.Name())
+	}
+	levels, err := collapsed.Levelize()
+	if err != nil {
+		return nil, err
+	}
+	circuit := &Circuit{
+		Component: c,
+		Manager:   m,
+	}
+	values := make(map[*logic.Bus]Node, len(collapsed.Buses))
+	for i, name := range collapsed.InputBusNames {
+		input := m.Var(vars[i])
+		values[collapsed.Buses[name]] = input
+		circuit.Inputs = append(circuit.Inputs, input)
+	}
+	for _, level := range levels {
+		for _, instance := range level {
+			switch def := instance.Definition.(type) {
+			case logic.NandGate:
+				a := values[instance.Inputs[0].Bus]
+				b := values[instance.Inputs[1].Bus]
+				values[instance.Outputs[0].Bus] = m.Nand(a, b)
+			case *logic.Constants:
+				var bits []bool
+				for _, value := range def.Values() {
+					bits = append(bits, value...)
+				}
+				for i, output := range instance.Outputs {
+					if bits[i] {
+						values[output.Bus] = True
+					} else {
+						values[output.Bus] = False
+					}
+				}
+			default:
+				return nil, fmt.Errorf("unexpected logic.Definition: %t", def)
+			}
+			if err := m.Err(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, name := range collapsed.OutputBusNames {
+		circuit.Outputs = append(circuit.Outputs, values[collapsed.Buses[name]])
+	}
+	return circuit, nil
+}
+
+func variableOrder(c, collapsed *logic.Component, ordering Ordering) ([]int, error) {
+	n := len(collapsed.InputBusNames)
+	vars := make([]int, n)
+	switch ordering {
+	case DECLARATION_ORDER:
+		for i := range vars {
+			vars[i] = i
+		}
+	case INTERLEAVED_ORDER:
+		type key struct{ wire, bus, index int }
+		var keys []key
+		for busIndex, name := range c.InputBusNames {
+			for wire := 0; wire < c.Buses[name].Wires(); wire++ {
+				keys = append(keys, key{wire, busIndex, len(keys)})
+			}
+		}
+		sort.SliceStable(keys, func(i, j int) bool {
+			if keys[i].wire != keys[j].wire {
+				return keys[i].wire < keys[j].wire
+			}
+			return keys[i].bus < keys[j].bus
+		})
+		for v, k := range keys {
+			vars[k.index] = v
+		}
+	case FANIN_ORDER:
+		inputIndices := make(map[*logic.Bus]int)
+		for i, name := range collapsed.InputBusNames {
+			inputIndices[collapsed.Buses[name]] = i
+			vars[i] = -1
+		}
+		drivers := make(map[*logic.Bus]*logic.Instance)
+		for _, instance := range collapsed.Instances {
+			for _, output := range instance.Outputs {
+				drivers[output.Bus] = instance
+			}
+		}
+		next := 0
+		visited := make(map[*logic.Bus]bool)
+		var visit func(bus *logic.Bus)
+		visit = func(bus *logic.Bus) {
+			if visited[bus] {
+				return
+			}
+			visited[bus] = true
+			if i, ok := inputIndices[bus]; ok {
+				vars[i] = next
+				next++
+			} else if driver, ok := drivers[bus]; ok {
+				for _, input := range driver.Inputs {
+					visit(input.Bus)
+				}
+			}
+		}
+		for _, name := range collapsed.OutputBusNames {
+			visit(collapsed.Buses[name])
+		}
+		for i := range vars {
+			if vars[i] < 0 {
+				vars[i] = next
+				next++
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unexpected bdd.Ordering: %d", ordering)
+	}
+	return vars, nil
+}
+
+func Equivalent(a, b *logic.Component, ordering Ordering) (bool, error) {
+	if err := logic.ComparePorts(a, b); err != nil {
+		return false, err
+	}
+	vars, err := variableOrder(a, a.Collapse(a.Name()), ordering)
+	if err != nil {
+		return false, err
+	}
+	m := NewManager(len(vars))
+	ca, err := m.Build(a, vars)
+	if err != nil {
+		return false, err
+	}
+	cb, err := m.Build(b, vars)
+	if err != nil {
+		return false, err
+	}
+	for i := range ca.Outputs {
+		if ca.Outputs[i] != cb.Outputs[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (c *Circuit) IsConstant(output int) (value bool, ok bool) {
+	switch c.Outputs[output] {
+	case True:
+		return true, true
+	case False:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+func (c *Circuit) Size() int {
+	visited := 0
+	seen := make(map[Node]struct{})
+	for _, output := range c.Outputs {
+		if _, ok := seen[output]; ok {
+			continue
+		}
+		seen[output] = struct{}{}
+		visited += c.Manager.Size(output)
+	}
+	return visited
+}
+
+const MaxTruthTableInputs = 20
+
+func (c *Circuit) WriteTruthTable(w io.Writer) error {
+	if len(c.Inputs) > MaxTruthTableInputs {
+		return fmt.Errorf("too many input wires for truth table: %d > %d", len(c.Inputs), MaxTruthTableInputs)
+	}
+	names := append(append([]string(nil), c.Component.InputBusNames...), c.Component.OutputBusNames...)
+	if _, err := fmt.Fprintln(w, strings.Join(names, ", ")); err != nil {
+		return err
+	}
+	assignment := make([]bool, c.Manager.Vars())
+	inputs := make([]bool, len(c.Inputs))
+	outputs := make([]bool, len(c.Outputs))
+	for x := 0; x < 1<<len(c.Inputs); x++ {
+		for i, input := range c.Inputs {
+			inputs[i] = (x>>i)%2 == 1
+			assignment[c.Manager.Level(input)] = inputs[i]
+		}
+		for i, output := range c.Outputs {
+			outputs[i] = c.Manager.Eval(output, assignment)
+		}
+		row := append(group(inputs, c.Component, c.Component.InputBusNames), group(outputs, c.Component, c.Component.OutputBusNames)...)
+		if _, err := fmt.Fprintln(w, strings.Join(row, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func group(wires []bool, c *logic.Component, busNames []string) []string {
+	cells := make([]string, len(busNames))
+	wireIndex := 0
+	for i, name := range busNames {
+		bus := c.Buses[name]
+		cells[i] = logic.Value(wires[wireIndex : wireIndex+bus.Wires()]).String()
+		wireIndex += bus.Wires()
+	}
+	return cells
+}
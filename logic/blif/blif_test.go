@@ -0,0 +1,215 @@
+package blif
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/equiv"
+	"github.com/arneph/mercury/logic/internal/logictest"
+	"github.com/arneph/mercury/logic/simulation"
+)
+
+const src = logictest.Gates + logictest.Add1 + logictest.Add4 + logictest.Memory1 + `
+component Flags(a[2])(z, one, zero) {
+    i: Or(a[0], a[1])
+    z: Not(i)
+    one, zero: 1, 0
+}
+`
+
+func TestWrite(t *testing.T) {
+	system := logictest.Build(t, src)
+	var sb strings.Builder
+	if err := Write(&sb, system.Components["Flags"]); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	expected := `
+.model Flags
+.inputs a[0] a[1]
+.outputs z one zero
+.names a[0] Or_i1_'a
+0 1
+.names a[1] Or_i1_'b
+0 1
+.names Or_i1_'a Or_i1_'b i
+0- 1
+-0 1
+.names i z
+0 1
+.names one
+1
+.names zero
+.end
+`[1:]
+	if actual := sb.String(); actual != expected {
+		t.Errorf("Write() = %q; want %q", actual, expected)
+	}
+}
+
+func TestWriteLatch(t *testing.T) {
+	system := logictest.Build(t, src)
+	var sb strings.Builder
+	if err := Write(&sb, system.Components["Memory1"]); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if !strings.Contains(sb.String(), ".names 's 'r q q_next\n0-- 1\n-11 1\n.latch q_next q 3\n") {
+		t.Errorf("Write() did not emit latch for Memory1:\n%s", sb.String())
+	}
+}
+
+func roundTrip(t *testing.T, c *logic.Component) *logic.Component {
+	t.Helper()
+	var sb strings.Builder
+	if err := Write(&sb, c); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	system, err := Read(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatalf("Read() failed: %v\n%s", err, sb.String())
+	}
+	result, ok := system.Components[c.Name()]
+	if !ok {
+		t.Fatalf("Read() did not define component %s", c.Name())
+	}
+	return result
+}
+
+func TestRoundTrip(t *testing.T) {
+	system := logictest.Build(t, src)
+	for _, name := range []string{"Add1", "Add4", "Flags"} {
+		c := system.Components[name]
+		result := roundTrip(t, c)
+		if err := logic.ComparePorts(c, result); err != nil {
+			t.Errorf("round trip of %s changed ports: %v", name, err)
+			continue
+		}
+		counterexample, err := equiv.Check(c, result)
+		if err != nil {
+			t.Errorf("equiv.Check() failed for %s: %v", name, err)
+		} else if counterexample != nil {
+			t.Errorf("round trip of %s changed function: %v", name, counterexample)
+		}
+	}
+}
+
+func TestRoundTripLatch(t *testing.T) {
+	system := logictest.Build(t, src)
+	c := system.Components["Memory1"]
+	result := roundTrip(t, c)
+	var states []*simulation.ComponentState
+	for _, c := range []*logic.Component{c, result} {
+		s, err := simulation.NewComponentState(c.Collapse(c.Name()))
+		if err != nil {
+			t.Fatalf("NewComponentState() failed: %v", err)
+		}
+		states = append(states, s)
+	}
+	for i, step := range [][2]bool{{true, false}, {false, false}, {false, true}, {false, false}, {true, false}, {false, false}} {
+		for _, s := range states {
+			if err := s.SetInputs(logic.NewConstants([]logic.Value{{step[0]}, {step[1]}})); err != nil {
+				t.Fatalf("SetInputs() failed: %v", err)
+			}
+		}
+		e := states[0].Outputs().Values()[0][0]
+		a := states[1].Outputs().Values()[0][0]
+		if e != a {
+			t.Errorf("step %d: q = %v; want %v", i, a, e)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	src := `
+# hierarchical design
+.model top
+.inputs x[0] x[1] x[2] en
+.outputs y[0] y[1] done
+.subckt maj a=x[0] b=x[1] c=x[2] m=y[0]
+.subckt maj a=x[2] b=en c=x[0] \
+    m=y[1]
+.names y[0] y[1] done
+00 0
+.end
+
+.model maj
+.inputs a b c
+.outputs m
+.names a b c m
+11- 1
+1-1 1
+-11 1
+.end
+`
+	system, err := Read(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	top := system.Components["top"]
+	if top == nil {
+		t.Fatalf("Read() did not define top")
+	}
+	if len(top.InputBusNames) != 2 || top.Buses["x"].Wires() != 3 || top.Buses["y"].Wires() != 2 {
+		t.Errorf("unexpected ports: %v -> %v", top.InputBusNames, top.OutputBusNames)
+	}
+	maj := system.Components["maj"]
+	count := 0
+	for _, instance := range top.Instances {
+		if instance.Definition == maj {
+			count++
+		}
+	}
+	if count != 2 {
+		t.Errorf("expected 2 instances of maj, got %d", count)
+	}
+	inputs := make([]*logic.Constants, 16)
+	for m := range inputs {
+		inputs[m] = logic.NewConstants([]logic.Value{{m&1 == 1, m&2 == 2, m&4 == 4}, {m&8 == 8}})
+	}
+	results, err := simulation.SimulateBatch(top, inputs)
+	if err != nil {
+		t.Fatalf("SimulateBatch() failed: %v", err)
+	}
+	for m, result := range results {
+		x := logic.Value{m&1 == 1, m&2 == 2, m&4 == 4}
+		en := m&8 == 8
+		outputs := result.Values()
+		majority := func(a, b, c bool) bool { return (a && b) || (a && c) || (b && c) }
+		y0 := majority(x[0], x[1], x[2])
+		y1 := majority(x[2], en, x[0])
+		if outputs[0][0] != y0 || outputs[0][1] != y1 || outputs[1][0] != (y0 || y1) {
+			t.Errorf("input %#x: got %v", m, outputs)
+		}
+	}
+}
+
+func TestReadClockedLatch(t *testing.T) {
+	src := `
+# toggle flip-flop
+.model toggle
+.outputs q
+.latch nq q 0
+.names q nq
+0 1
+.end
+`
+	if _, err := Read(strings.NewReader(src)); err == nil || !strings.Contains(err.Error(), "latch q") {
+		t.Errorf("Read() = %v; want error for latch q", err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, src := range []string{
+		".names a b\n1 1\n",
+		".model m\n.inputs a\n.outputs b\n.names a b\n1 1\n0 0\n.end\n",
+		".model m\n.inputs a\n.outputs b\n.subckt n x=a\n.end\n",
+		".model m\n.inputs a\n.outputs b\n.latch a b re clk 0\n.end\n",
+		".model m\n.inputs a\n.outputs b\n.latch a b 0\n.end\n",
+		".model m\n.inputs a\n.outputs b\n.gate nand2 a=a b=a O=b\n.end\n",
+		".model m\n.inputs a\n.outputs b\n.names a b\n2 1\n.end\n",
+	} {
+		if _, err := Read(strings.NewReader(src)); err == nil {
+			t.Errorf("Read() succeeded for:\n%s", src)
+		}
+	}
+}
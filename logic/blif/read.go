@@ -0,0 +1,500 @@
+package blif
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/synth"
+)
+
+func Read(r io.Reader) (*logic.System, error) {
+	models, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+	system := logic.NewSystem()
+	builders := make(map[string]*modelBuilder)
+	for _, m := range models {
+		if _, ok := builders[m.name]; ok {
+			return nil, fmt.Errorf("line %d: redefinition of model: %s", m.line, m.name)
+		}
+		b, err := newModelBuilder(m)
+		if err != nil {
+			return nil, err
+		}
+		builders[m.name] = b
+		system.AddComponent(b.component)
+	}
+	for _, m := range models {
+		if err := builders[m.name].build(builders); err != nil {
+			return nil, err
+		}
+	}
+	return system, nil
+}
+
+type model struct {
+	name    string
+	line    int
+	inputs  []string
+	outputs []string
+	items   []item
+}
+
+type item interface {
+	itemLine() int
+}
+
+type names struct {
+	line   int
+	inputs []string
+	output string
+	rows   [][2]string
+}
+
+func (n *names) itemLine() int { return n.line }
+
+type latch struct {
+	line    int
+	input   string
+	output  string
+	kind    string
+	control string
+}
+
+func (l *latch) itemLine() int { return l.line }
+
+type subckt struct {
+	line        int
+	model       string
+	connections map[string]string
+}
+
+func (s *subckt) itemLine() int { return s.line }
+
+func parse(r io.Reader) ([]*model, error) {
+	var models []*model
+	var current *model
+	var currentNames *names
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		start := lineNumber
+		for strings.HasSuffix(line, "\\") && scanner.Scan() {
+			lineNumber++
+			line = line[:len(line)-1] + " " + scanner.Text()
+		}
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		errorf := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %s", start, fmt.Sprintf(format, args...))
+		}
+		if !strings.HasPrefix(fields[0], ".") {
+			if currentNames == nil {
+				return nil, errorf("unexpected cover row outside of .names")
+			}
+			var row [2]string
+			switch len(fields) {
+			case 1:
+				if len(currentNames.inputs) != 0 {
+					return nil, errorf("expected input and output plane")
+				}
+				row[1] = fields[0]
+			case 2:
+				row = [2]string{fields[0], fields[1]}
+			default:
+				return nil, errorf("expected input and output plane")
+			}
+			if len(row[0]) != len(currentNames.inputs) || (row[1] != "0" && row[1] != "1") {
+				return nil, errorf("invalid cover row for %s", currentNames.output)
+			}
+			currentNames.rows = append(currentNames.rows, row)
+			continue
+		}
+		currentNames = nil
+		if fields[0] != ".model" && current == nil {
+			return nil, errorf("directive %s outside of .model", fields[0])
+		}
+		switch fields[0] {
+		case ".model":
+			if current != nil {
+				return nil, errorf("missing .end for model %s", current.name)
+			} else if len(fields) != 2 {
+				return nil, errorf("expected model name")
+			}
+			current = &model{name: fields[1], line: start}
+		case ".inputs":
+			current.inputs = append(current.inputs, fields[1:]...)
+		case ".outputs":
+			current.outputs = append(current.outputs, fields[1:]...)
+		case ".names":
+			if len(fields) < 2 {
+				return nil, errorf("expected output signal for .names")
+			}
+			currentNames = &names{
+				line:   start,
+				inputs: fields[1 : len(fields)-1],
+				output: fields[len(fields)-1],
+			}
+			current.items = append(current.items, currentNames)
+		case ".latch":
+			l := &latch{line: start}
+			switch len(fields) {
+			case 3, 4:
+				l.input, l.output = fields[1], fields[2]
+			case 5, 6:
+				l.input, l.output, l.kind, l.control = fields[1], fields[2], fields[3], fields[4]
+			default:
+				return nil, errorf("invalid .latch")
+			}
+			current.items = append(current.items, l)
+		case ".subckt":
+			if len(fields) < 2 {
+				return nil, errorf("expected model name for .subckt")
+			}
+			s := &subckt{line: start, model: fields[1], connections: make(map[string]string)}
+			for _, connection := range fields[2:] {
+				formal, actual, ok := strings.Cut(connection, "=")
+				if !ok {
+					return nil, errorf("invalid .subckt connection: %s", connection)
+				}
+				s.connections[formal] = actual
+			}
+			current.items = append(current.items, s)
+		case ".end":
+			models = append(models, current)
+			current = nil
+		case ".clock", ".area", ".delay", ".wire_load_slope", ".wire", ".input_arrival",
+			".default_input_arrival", ".output_required", ".default_output_required",
+			".input_drive", ".default_input_drive", ".output_load", ".default_output_load":
+			break
+		default:
+			return nil, errorf("unsupported directive: %s", fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if current != nil {
+		models = append(models, current)
+	}
+	return models, nil
+}
+
+var indexedSignal = regexp.MustCompile(`^(.*)\[(\d+)\]$`)
+
+type modelBuilder struct {
+	model     *model
+	component *logic.Component
+	signals   map[string]logic.BusWire
+	counts    map[string]int
+}
+
+func newModelBuilder(m *model) (*modelBuilder, error) {
+	b := &modelBuilder{
+		model:   m,
+		signals: make(map[string]logic.BusWire),
+		counts:  make(map[string]int),
+	}
+	used := make(map[string]bool)
+	inputs, err := b.groupPorts(m.inputs, used)
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := b.groupPorts(m.outputs, used)
+	if err != nil {
+		return nil, err
+	}
+	b.component = logic.NewComponent(synth.Identifier(m.name), inputs, outputs)
+	return b, nil
+}
+
+func (b *modelBuilder) groupPorts(signals []string, used map[string]bool) ([]*logic.Bus, error) {
+	var bases []string
+	indexed := make(map[string][]string)
+	plain := make(map[string]bool)
+	for _, signal := range signals {
+		if _, ok := b.signals[signal]; ok {
+			return nil, fmt.Errorf("line %d: repeated port %s in model %s", b.model.line, signal, b.model.name)
+		}
+		b.signals[signal] = logic.BusWire{}
+		base := signal
+		if m := indexedSignal.FindStringSubmatch(signal); m != nil {
+			base = m[1]
+			indexed[base] = append(indexed[base], signal)
+		} else {
+			plain[base] = true
+		}
+		if len(indexed[base]) <= 1 {
+			bases = append(bases, base)
+		}
+	}
+	var buses []*logic.Bus
+	seen := make(map[string]bool)
+	for _, base := range bases {
+		if seen[base] {
+			continue
+		}
+		seen[base] = true
+		group := indexed[base]
+		if plain[base] && len(group) == 0 {
+			buses = append(buses, b.addPort(base, []string{base}, used))
+		} else if !plain[base] && contiguous(base, group) {
+			buses = append(buses, b.addPort(base, group, used))
+		} else {
+			if plain[base] {
+				buses = append(buses, b.addPort(base, []string{base}, used))
+			}
+			for _, signal := range group {
+				buses = append(buses, b.addPort(signal, []string{signal}, used))
+			}
+		}
+	}
+	return buses, nil
+}
+
+func contiguous(base string, group []string) bool {
+	present := make([]bool, len(group))
+	for _, signal := range group {
+		i, err := strconv.Atoi(signal[len(base)+1 : len(signal)-1])
+		if err != nil || i >= len(group) || present[i] || strconv.Itoa(i) != signal[len(base)+1:len(signal)-1] {
+			return false
+		}
+		present[i] = true
+	}
+	return true
+}
+
+func (b *modelBuilder) addPort(name string, signals []string, used map[string]bool) *logic.Bus {
+	bus := logic.NewBus(uniqueName(synth.Identifier(name), used), len(signals))
+	for _, signal := range signals {
+		index := 0
+		if len(signals) > 1 {
+			index, _ = strconv.Atoi(signal[len(name)+1 : len(signal)-1])
+		}
+		b.signals[signal] = logic.BusWire{Bus: bus, WireIndex: logic.WireIndex(index)}
+	}
+	return bus
+}
+
+func uniqueName(name string, used map[string]bool) string {
+	unique := name
+	for i := 1; used[unique]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
+
+func (b *modelBuilder) signal(name string) logic.BusWire {
+	if wire, ok := b.signals[name]; ok {
+		return wire
+	}
+	used := make(map[string]bool, len(b.component.Buses))
+	for busName := range b.component.Buses {
+		used[busName] = true
+	}
+	bus := logic.NewBus(uniqueName(synth.Identifier(name), used), 1)
+	b.component.Buses[bus.Name] = bus
+	wire := logic.BusWire{Bus: bus, WireIndex: 0}
+	b.signals[name] = wire
+	return wire
+}
+
+func (b *modelBuilder) fresh(prefix string) logic.BusWire {
+	for {
+		name := prefix + strconv.Itoa(b.counts[prefix])
+		b.counts[prefix]++
+		if _, ok := b.component.Buses[name]; !ok {
+			return b.signal(name)
+		}
+	}
+}
+
+func (b *modelBuilder) build(builders map[string]*modelBuilder) error {
+	for _, it := range b.model.items {
+		var err error
+		switch it := it.(type) {
+		case *names:
+			err = b.buildNames(it)
+		case *latch:
+			err = b.buildLatch(it)
+		case *subckt:
+			err = b.buildSubckt(it, builders)
+		default:
+			panic(fmt.Errorf("unexpected blif.item: %t", it))
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %v", it.itemLine(), err)
+		}
+	}
+	return nil
+}
+
+func (b *modelBuilder) buildNames(n *names) error {
+	if len(n.inputs) > synth.MaxInputs {
+		return fmt.Errorf("too many inputs for %s: %d > %d", n.output, len(n.inputs), synth.MaxInputs)
+	}
+	inputs := make([]logic.BusWire, len(n.inputs))
+	for i, input := range n.inputs {
+		inputs[i] = b.signal(input)
+	}
+	output := b.signal(n.output)
+	f := synth.Function{Inputs: len(n.inputs)}
+	phase := "1"
+	for i, row := range n.rows {
+		if i == 0 {
+			phase = row[1]
+		} else if row[1] != phase {
+			return fmt.Errorf("mixed on-set and off-set rows for %s", n.output)
+		}
+		var c synth.Cube
+		for j, ch := range row[0] {
+			bit := uint64(1) << j
+			switch ch {
+			case '0':
+				c.Care |= bit
+			case '1':
+				c.Care |= bit
+				c.Value |= bit
+			case '-':
+				break
+			default:
+				return fmt.Errorf("invalid input plane character: %q", ch)
+			}
+		}
+		f.On = append(f.On, c)
+	}
+	cover := synth.Minimize(f)
+	if phase == "1" {
+		synth.Implement(b.component, inputs, []logic.BusWire{output}, [][]synth.Cube{cover})
+		return nil
+	}
+	complement := b.fresh("'" + output.Bus.Name)
+	synth.Implement(b.component, inputs, []logic.BusWire{complement}, [][]synth.Cube{cover})
+	b.nand(complement, complement, output)
+	return nil
+}
+
+func (b *modelBuilder) buildLatch(l *latch) error {
+	input := b.signal(l.input)
+	output := b.signal(l.output)
+	switch l.kind {
+	case "":
+		if !b.isNandLatch(l) {
+			return fmt.Errorf("latch %s is not a set-reset latch: clocked latches are not supported", l.output)
+		}
+		synth.Implement(b.component, []logic.BusWire{input}, []logic.BusWire{output}, [][]synth.Cube{
+			{{Care: 1, Value: 1}},
+		})
+	case "ah", "al":
+		enabled := uint64(1)
+		if l.kind == "al" {
+			enabled = 0
+		}
+		control := b.signal(l.control)
+		synth.Implement(b.component, []logic.BusWire{control, input, output}, []logic.BusWire{output}, [][]synth.Cube{
+			{
+				{Care: 0b011, Value: 0b010 | enabled},
+				{Care: 0b101, Value: 0b100 | (enabled ^ 1)},
+				{Care: 0b110, Value: 0b110},
+			},
+		})
+	default:
+		return fmt.Errorf("unsupported latch type: %s", l.kind)
+	}
+	return nil
+}
+
+func (b *modelBuilder) isNandLatch(l *latch) bool {
+	for _, it := range b.model.items {
+		if n, ok := it.(*names); ok && n.output == l.input {
+			return len(n.inputs) == 3 && n.inputs[2] == l.output &&
+				len(n.rows) == 2 && n.rows[0] == [2]string{"0--", "1"} && n.rows[1] == [2]string{"-11", "1"}
+		}
+	}
+	return false
+}
+
+func (b *modelBuilder) buildSubckt(s *subckt, builders map[string]*modelBuilder) error {
+	child, ok := builders[s.model]
+	if !ok {
+		return fmt.Errorf("undefined model: %s", s.model)
+	}
+	connect := func(ports []string) ([]logic.BusWire, error) {
+		wires := make([]logic.BusWire, len(ports))
+		for _, port := range ports {
+			actual, ok := s.connections[port]
+			if !ok {
+				return nil, fmt.Errorf("unconnected port %s of model %s", port, s.model)
+			}
+			wires[child.portIndex(port)] = b.signal(actual)
+		}
+		return wires, nil
+	}
+	for formal := range s.connections {
+		if _, ok := child.signals[formal]; !ok || !child.isPort(formal) {
+			return fmt.Errorf("model %s has no port %s", s.model, formal)
+		}
+	}
+	inputs, err := connect(child.model.inputs)
+	if err != nil {
+		return err
+	}
+	outputs, err := connect(child.model.outputs)
+	if err != nil {
+		return err
+	}
+	b.component.Instances = append(b.component.Instances, &logic.Instance{
+		Definition: child.component,
+		Inputs:     inputs,
+		Outputs:    outputs,
+	})
+	return nil
+}
+
+func (b *modelBuilder) isPort(signal string) bool {
+	for _, names := range [][]string{b.model.inputs, b.model.outputs} {
+		for _, name := range names {
+			if name == signal {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (b *modelBuilder) portIndex(signal string) int {
+	wire := b.signals[signal]
+	for _, names := range [][]string{b.component.InputBusNames, b.component.OutputBusNames} {
+		offset := 0
+		for _, name := range names {
+			bus := b.component.Buses[name]
+			if bus == wire.Bus {
+				return offset + int(wire.WireIndex)
+			}
+			offset += bus.Wires()
+		}
+	}
+	panic(fmt.Errorf("not a port: %s", signal))
+}
+
+func (b *modelBuilder) nand(a, c, r logic.BusWire) {
+	b.component.Instances = append(b.component.Instances, &logic.Instance{
+		Definition: logic.Nand,
+		Inputs:     []logic.BusWire{a, c},
+		Outputs:    []logic.BusWire{r},
+	})
+}
@@ -0,0 +1,131 @@
+package blif
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+func Write(w io.Writer, c *logic.Component) error {
+	if c.HasTriGates() {
+		return fmt.Errorf("%s: tri-state buffers are not supported", c.Name())
+	}
+	collapsed := c.Collapse(c.Name())
+	bw := &writer{
+		signals: make(map[string]string),
+		used:    make(map[string]bool),
+	}
+	for name := range collapsed.Buses {
+		bw.used[name] = true
+	}
+	var inputs, outputs []string
+	for _, kind := range []struct {
+		busNames []string
+		signals  *[]string
+	}{
+		{c.InputBusNames, &inputs},
+		{c.OutputBusNames, &outputs},
+	} {
+		for _, name := range kind.busNames {
+			bus := c.Buses[name]
+			for i := 0; i < bus.Wires(); i++ {
+				signal := name
+				if bus.Wires() > 1 {
+					signal = fmt.Sprintf("%s[%d]", name, i)
+				}
+				bw.signals[logic.CollapsedWireName(bus, logic.WireIndex(i))] = signal
+				bw.used[signal] = true
+				*kind.signals = append(*kind.signals, signal)
+			}
+		}
+	}
+	latches := make(map[*logic.Instance]*logic.Latch)
+	for _, latch := range logic.FindLatches(collapsed) {
+		latches[latch.Q] = latch
+	}
+
+	bw.sb.WriteString(".model ")
+	bw.sb.WriteString(c.Name())
+	bw.sb.WriteString("\n")
+	if len(inputs) > 0 {
+		bw.sb.WriteString(".inputs ")
+		bw.sb.WriteString(strings.Join(inputs, " "))
+		bw.sb.WriteString("\n")
+	}
+	if len(outputs) > 0 {
+		bw.sb.WriteString(".outputs ")
+		bw.sb.WriteString(strings.Join(outputs, " "))
+		bw.sb.WriteString("\n")
+	}
+	for _, instance := range collapsed.Instances {
+		switch def := instance.Definition.(type) {
+		case *logic.Constants:
+			bw.writeConstants(def, instance)
+		case logic.NandGate:
+			if latch, ok := latches[instance]; ok {
+				bw.writeLatch(latch)
+			} else {
+				bw.writeNand(instance)
+			}
+		default:
+			panic(fmt.Errorf("unexpected logic.Definition: %t", def))
+		}
+	}
+	bw.sb.WriteString(".end\n")
+	_, err := io.WriteString(w, bw.sb.String())
+	return err
+}
+
+type writer struct {
+	sb      strings.Builder
+	signals map[string]string
+	used    map[string]bool
+}
+
+func (bw *writer) signal(wire logic.BusWire) string {
+	if signal, ok := bw.signals[wire.Bus.Name]; ok {
+		return signal
+	}
+	return wire.Bus.Name
+}
+
+func (bw *writer) writeConstants(def *logic.Constants, instance *logic.Instance) {
+	var bits []bool
+	for _, value := range def.Values() {
+		bits = append(bits, value...)
+	}
+	for i, output := range instance.Outputs {
+		bw.sb.WriteString(".names ")
+		bw.sb.WriteString(bw.signal(output))
+		bw.sb.WriteString("\n")
+		if bits[i] {
+			bw.sb.WriteString("1\n")
+		}
+	}
+}
+
+func (bw *writer) writeNand(instance *logic.Instance) {
+	a := bw.signal(instance.Inputs[0])
+	b := bw.signal(instance.Inputs[1])
+	r := bw.signal(instance.Outputs[0])
+	if a == b {
+		fmt.Fprintf(&bw.sb, ".names %s %s\n0 1\n", a, r)
+	} else {
+		fmt.Fprintf(&bw.sb, ".names %s %s %s\n0- 1\n-0 1\n", a, b, r)
+	}
+}
+
+func (bw *writer) writeLatch(latch *logic.Latch) {
+	q := bw.signal(latch.Q.Outputs[0])
+	next := q + "_next"
+	for bw.used[next] {
+		next += "_"
+	}
+	bw.used[next] = true
+	setBar := bw.signal(latch.SetBar())
+	resetBar := bw.signal(latch.ResetBar())
+	fmt.Fprintf(&bw.sb, ".names %s %s %s %s\n0-- 1\n-11 1\n", setBar, resetBar, q, next)
+	fmt.Fprintf(&bw.sb, ".latch %s %s 3\n", next, q)
+}
@@ -0,0 +1,52 @@
+package chips
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func (b *Board) WriteBOM(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Bill of materials for %s\n\n", b.Name)
+	fmt.Fprintf(&sb, "%-4s %-10s %-12s %s\n", "Qty", "Reference", "Value", "Description")
+	if len(b.Chips) > 0 {
+		refs := b.Chips[0].Ref
+		if len(b.Chips) > 1 {
+			refs += "-" + b.Chips[len(b.Chips)-1].Ref
+		}
+		fmt.Fprintf(&sb, "%-4d %-10s %-12s %s\n", len(b.Chips), refs, "74HC00", "quad 2-input NAND, DIP-14")
+	}
+	fmt.Fprintf(&sb, "%-4d %-10s %-12s %s\n", 1, "J1", fmt.Sprintf("Conn_01x%02d", len(b.Connector)), "pin header: "+strings.Join(b.Connector, ", "))
+
+	gates := 0
+	for _, chip := range b.Chips {
+		gates += chip.used()
+	}
+	fmt.Fprintf(&sb, "\n%d gates on %d chips, %d unused\n", gates, len(b.Chips), GatesPerChip*len(b.Chips)-gates)
+	for _, chip := range b.Chips {
+		for slot, gate := range chip.Gates {
+			pins := gatePins[slot]
+			fmt.Fprintf(&sb, "  %s gate %d (pins %d, %d -> %d): ", chip.Ref, slot+1, pins[0], pins[1], pins[2])
+			if gate == nil {
+				sb.WriteString("unused, inputs tied to GND\n")
+			} else {
+				fmt.Fprintf(&sb, "%s = nand(%s, %s)\n",
+					b.netOf[gate.Outputs[0].Bus].Name, b.netOf[gate.Inputs[0].Bus].Name, b.netOf[gate.Inputs[1].Bus].Name)
+			}
+		}
+	}
+
+	wires := 0
+	for _, net := range b.Nets {
+		wires += max(len(net.Pins)-1, 0)
+	}
+	fmt.Fprintf(&sb, "\n%d wires\n", wires)
+	for _, net := range b.Nets {
+		for i := 1; i < len(net.Pins); i++ {
+			fmt.Fprintf(&sb, "  %-8s %-8s %s\n", net.Pins[i-1], net.Pins[i], net.Name)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,308 @@
+package chips
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/arneph/mercury/logic"
+)
+
+const GatesPerChip = 4
+
+var gatePins = [GatesPerChip][3]int{
+	{1, 2, 3},
+	{4, 5, 6},
+	{9, 10, 8},
+	{12, 13, 11},
+}
+
+const (
+	gndPin = 7
+	vccPin = 14
+)
+
+type Chip struct {
+	Ref   string
+	Gates [GatesPerChip]*logic.Instance
+}
+
+func (c *Chip) used() int {
+	n := 0
+	for _, gate := range c.Gates {
+		if gate != nil {
+			n++
+		}
+	}
+	return n
+}
+
+type Pin struct {
+	Ref    string
+	Number int
+}
+
+func (p Pin) String() string {
+	return p.Ref + "." + strconv.Itoa(p.Number)
+}
+
+type Net struct {
+	Name string
+	Pins []Pin
+}
+
+type Board struct {
+	Name       string
+	Chips      []*Chip
+	Connector  []string
+	Nets       []*Net
+	collapsed  *logic.Component
+	portLabels map[string]string
+	netOf      map[*logic.Bus]*Net
+}
+
+func Pack(c *logic.Component) (*Board, error) {
+	if c.HasTriGates() {
+		return nil, fmt.Errorf("%s: tri-state buffers are not supported", c.Name())
+	}
+	collapsed := c.Collapse(c.Name())
+	p := &packer{gates: make(map[*logic.Component]int)}
+	if p.count(c) <= GatesPerChip {
+		p.walk(c, p.newGroup())
+	} else {
+		p.walk(c, -1)
+	}
+	if len(p.leaves) != len(collapsed.Instances) {
+		panic(fmt.Errorf("collapsed component %s has %d instances, expected %d", c.Name(), len(collapsed.Instances), len(p.leaves)))
+	}
+	groups := make([][]*logic.Instance, p.groups)
+	for i, group := range p.leaves {
+		if group >= 0 {
+			groups[group] = append(groups[group], collapsed.Instances[i])
+		}
+	}
+	b := &Board{
+		Name:       c.Name(),
+		collapsed:  collapsed,
+		portLabels: make(map[string]string),
+		netOf:      make(map[*logic.Bus]*Net),
+	}
+	for _, group := range groups {
+		for len(group) >= GatesPerChip {
+			b.place(b.newChip(), group[:GatesPerChip])
+			group = group[GatesPerChip:]
+		}
+		if len(group) > 0 {
+			b.place(b.chipFor(len(group)), group)
+		}
+	}
+	b.compact()
+	b.Connector = []string{"VCC", "GND"}
+	for _, names := range [][]string{c.InputBusNames, c.OutputBusNames} {
+		for _, name := range names {
+			bus := c.Buses[name]
+			for i := 0; i < bus.Wires(); i++ {
+				label := name
+				if bus.Wires() > 1 {
+					label = fmt.Sprintf("%s[%d]", name, i)
+				}
+				b.portLabels[logic.CollapsedWireName(bus, logic.WireIndex(i))] = label
+				b.Connector = append(b.Connector, label)
+			}
+		}
+	}
+	b.connect()
+	return b, nil
+}
+
+type packer struct {
+	gates  map[*logic.Component]int
+	leaves []int
+	groups int
+}
+
+func (p *packer) count(c *logic.Component) int {
+	if n, ok := p.gates[c]; ok {
+		return n
+	}
+	n := 0
+	for _, instance := range c.Instances {
+		switch def := instance.Definition.(type) {
+		case logic.NandGate:
+			n++
+		case *logic.Component:
+			n += p.count(def)
+		}
+	}
+	p.gates[c] = n
+	return n
+}
+
+func (p *packer) newGroup() int {
+	p.groups++
+	return p.groups - 1
+}
+
+func (p *packer) walk(c *logic.Component, group int) {
+	direct := group
+	for _, instance := range c.Instances {
+		switch def := instance.Definition.(type) {
+		case *logic.Constants:
+			p.leaves = append(p.leaves, -1)
+		case logic.NandGate:
+			if direct < 0 {
+				direct = p.newGroup()
+			}
+			p.leaves = append(p.leaves, direct)
+		case *logic.Component:
+			if group >= 0 {
+				p.walk(def, group)
+			} else if p.count(def) <= GatesPerChip {
+				p.walk(def, p.newGroup())
+			} else {
+				p.walk(def, -1)
+			}
+		default:
+			panic(fmt.Errorf("unexpected logic.Definition: %t", def))
+		}
+	}
+}
+
+func (b *Board) newChip() *Chip {
+	chip := &Chip{Ref: "U" + strconv.Itoa(len(b.Chips)+1)}
+	b.Chips = append(b.Chips, chip)
+	return chip
+}
+
+func (b *Board) chipFor(gates int) *Chip {
+	var best *Chip
+	for _, chip := range b.Chips {
+		free := GatesPerChip - chip.used()
+		if free >= gates && (best == nil || free < GatesPerChip-best.used()) {
+			best = chip
+		}
+	}
+	if best == nil {
+		best = b.newChip()
+	}
+	return best
+}
+
+func (b *Board) place(chip *Chip, gates []*logic.Instance) {
+	slot := chip.used()
+	for _, gate := range gates {
+		chip.Gates[slot] = gate
+		slot++
+	}
+}
+
+func (b *Board) compact() {
+	gates := 0
+	for _, chip := range b.Chips {
+		gates += chip.used()
+	}
+	for len(b.Chips) > (gates+GatesPerChip-1)/GatesPerChip {
+		lightest := len(b.Chips) - 1
+		for i := len(b.Chips) - 1; i >= 0; i-- {
+			if b.Chips[i].used() < b.Chips[lightest].used() {
+				lightest = i
+			}
+		}
+		removed := b.Chips[lightest]
+		b.Chips = append(b.Chips[:lightest], b.Chips[lightest+1:]...)
+		targets := append([]*Chip(nil), b.Chips...)
+		sort.SliceStable(targets, func(i, j int) bool {
+			return targets[i].used() < targets[j].used()
+		})
+		var moved []*logic.Instance
+		for _, gate := range removed.Gates {
+			if gate != nil {
+				moved = append(moved, gate)
+			}
+		}
+		for _, target := range targets {
+			n := min(GatesPerChip-target.used(), len(moved))
+			b.place(target, moved[:n])
+			moved = moved[n:]
+		}
+	}
+	for i, chip := range b.Chips {
+		chip.Ref = "U" + strconv.Itoa(i+1)
+	}
+}
+
+func (b *Board) connect() {
+	nets := make(map[string]*Net)
+	netFor := func(bus *logic.Bus) *Net {
+		name := bus.Name
+		if label, ok := b.portLabels[name]; ok {
+			name = label
+		}
+		net, ok := nets[name]
+		if !ok {
+			net = &Net{Name: name}
+			nets[name] = net
+		}
+		b.netOf[bus] = net
+		return net
+	}
+	gnd := &Net{Name: "GND"}
+	vcc := &Net{Name: "VCC"}
+	nets["GND"], nets["VCC"] = gnd, vcc
+	for _, instance := range b.collapsed.Instances {
+		def, ok := instance.Definition.(*logic.Constants)
+		if !ok {
+			continue
+		}
+		var bits []bool
+		for _, value := range def.Values() {
+			bits = append(bits, value...)
+		}
+		for i, output := range instance.Outputs {
+			net := gnd
+			if bits[i] {
+				net = vcc
+			}
+			nets[netFor(output.Bus).Name] = net
+			b.netOf[output.Bus] = net
+		}
+	}
+	var order []*Net
+	seen := make(map[*Net]bool)
+	add := func(net *Net, pin Pin) {
+		net.Pins = append(net.Pins, pin)
+		if !seen[net] {
+			seen[net] = true
+			order = append(order, net)
+		}
+	}
+	add(vcc, Pin{"J1", 1})
+	add(gnd, Pin{"J1", 2})
+	number := 3
+	for _, names := range [][]string{b.collapsed.InputBusNames, b.collapsed.OutputBusNames} {
+		for _, name := range names {
+			add(netFor(b.collapsed.Buses[name]), Pin{"J1", number})
+			number++
+		}
+	}
+	for _, chip := range b.Chips {
+		add(vcc, Pin{chip.Ref, vccPin})
+		add(gnd, Pin{chip.Ref, gndPin})
+		for slot, gate := range chip.Gates {
+			pins := gatePins[slot]
+			if gate == nil {
+				add(gnd, Pin{chip.Ref, pins[0]})
+				add(gnd, Pin{chip.Ref, pins[1]})
+				continue
+			}
+			add(netFor(gate.Inputs[0].Bus), Pin{chip.Ref, pins[0]})
+			add(netFor(gate.Inputs[1].Bus), Pin{chip.Ref, pins[1]})
+			add(netFor(gate.Outputs[0].Bus), Pin{chip.Ref, pins[2]})
+		}
+	}
+	for _, net := range order {
+		if len(net.Pins) > 1 || net == vcc || net == gnd {
+			b.Nets = append(b.Nets, net)
+		}
+	}
+}
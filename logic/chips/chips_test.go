@@ -0,0 +1,221 @@
+package chips
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/equiv"
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+const src = logictest.Gates + logictest.Add1 + `
+component Add2(a[2], b[2], ci)(r[2], co) {
+    define c
+    r[0], c: Add1(a[0], b[0], ci)
+    r[1], co: Add1(a[1], b[1], c)
+}
+
+component Tied(a)(r, one, zero) {
+    one: 1
+    zero: 0
+    r: And(a, one)
+}
+`
+
+func rebuild(t *testing.T, c *logic.Component, board *Board) *logic.Component {
+	t.Helper()
+	nets := make(map[Pin]*Net)
+	for _, net := range board.Nets {
+		for _, pin := range net.Pins {
+			if other, ok := nets[pin]; ok {
+				t.Fatalf("pin %s is part of nets %s and %s", pin, other.Name, net.Name)
+			}
+			nets[pin] = net
+		}
+	}
+	var inputs, outputs []*logic.Bus
+	wires := make(map[*Net]logic.BusWire)
+	number := 3
+	for _, kind := range []struct {
+		names []string
+		buses *[]*logic.Bus
+	}{
+		{c.InputBusNames, &inputs},
+		{c.OutputBusNames, &outputs},
+	} {
+		for _, name := range kind.names {
+			bus := logic.NewBus(name, c.Buses[name].Wires())
+			*kind.buses = append(*kind.buses, bus)
+			for i := 0; i < bus.Wires(); i++ {
+				net := nets[Pin{"J1", number}]
+				number++
+				if _, ok := wires[net]; ok {
+					t.Fatalf("net %s connects several ports", net.Name)
+				}
+				wires[net] = logic.BusWire{Bus: bus, WireIndex: logic.WireIndex(i)}
+			}
+		}
+	}
+	result := logic.NewComponent(c.Name(), inputs, outputs)
+	wire := func(pin Pin) logic.BusWire {
+		net, ok := nets[pin]
+		if !ok {
+			net = &Net{Name: "unconnected " + pin.String()}
+			nets[pin] = net
+		}
+		if w, ok := wires[net]; ok {
+			return w
+		}
+		bus := logic.NewBus(net.Name, 1)
+		result.Buses[bus.Name] = bus
+		wires[net] = logic.BusWire{Bus: bus}
+		return wires[net]
+	}
+	result.Instances = append(result.Instances, &logic.Instance{
+		Definition: logic.NewConstants([]logic.Value{{true}, {false}}),
+		Outputs:    []logic.BusWire{wire(Pin{"J1", 1}), wire(Pin{"J1", 2})},
+	})
+	for _, chip := range board.Chips {
+		if nets[Pin{chip.Ref, vccPin}].Name != "VCC" || nets[Pin{chip.Ref, gndPin}].Name != "GND" {
+			t.Errorf("chip %s is not powered", chip.Ref)
+		}
+		for slot, gate := range chip.Gates {
+			pins := gatePins[slot]
+			if gate == nil {
+				continue
+			}
+			result.Instances = append(result.Instances, &logic.Instance{
+				Definition: logic.Nand,
+				Inputs:     []logic.BusWire{wire(Pin{chip.Ref, pins[0]}), wire(Pin{chip.Ref, pins[1]})},
+				Outputs:    []logic.BusWire{wire(Pin{chip.Ref, pins[2]})},
+			})
+		}
+	}
+	return result
+}
+
+func pack(t *testing.T, c *logic.Component) *Board {
+	t.Helper()
+	board, err := Pack(c)
+	if err != nil {
+		t.Fatalf("Pack(%s) failed: %v", c.Name(), err)
+	}
+	return board
+}
+
+func TestPack(t *testing.T) {
+	system := logictest.Build(t, src)
+	for _, name := range []string{"Xor", "Add1", "Add2", "Tied"} {
+		c := system.Components[name]
+		board := pack(t, c)
+		gates := 0
+		for _, instance := range c.Collapse(name).Instances {
+			if _, ok := instance.Definition.(logic.NandGate); ok {
+				gates++
+			}
+		}
+		if chips := (gates + GatesPerChip - 1) / GatesPerChip; len(board.Chips) != chips {
+			t.Errorf("Pack(%s) used %d chips; want %d", name, len(board.Chips), chips)
+		}
+		result := rebuild(t, c, board)
+		counterexample, err := equiv.Check(c, result)
+		if err != nil {
+			t.Errorf("equiv.Check() failed for %s: %v", name, err)
+		} else if counterexample != nil {
+			t.Errorf("board for %s differs: %v", name, counterexample)
+		}
+	}
+}
+
+func TestPackKeepsInstancesTogether(t *testing.T) {
+	system := logictest.Build(t, src)
+	board := pack(t, system.Components["Add2"])
+	chipOf := make(map[*logic.Instance]*Chip)
+	for _, chip := range board.Chips {
+		for _, gate := range chip.Gates {
+			if gate != nil {
+				chipOf[gate] = chip
+			}
+		}
+	}
+	gates := board.collapsed.Instances
+	for _, start := range []int{0, 4, 15, 19} {
+		chip := chipOf[gates[start]]
+		for _, gate := range gates[start+1 : start+4] {
+			if chipOf[gate] != chip {
+				t.Errorf("Xor instance starting at gate %d is split across chips %s and %s", start, chip.Ref, chipOf[gate].Ref)
+			}
+		}
+	}
+}
+
+func TestWriteKiCad(t *testing.T) {
+	system := logictest.Build(t, src)
+	var sb strings.Builder
+	if err := pack(t, system.Components["Tied"]).WriteKiCad(&sb); err != nil {
+		t.Fatalf("WriteKiCad() failed: %v", err)
+	}
+	expected := `
+(export (version "E")
+  (design
+    (source "Tied")
+    (tool "mercury"))
+  (components
+    (comp (ref "U1")
+      (value "74HC00")
+      (footprint "Package_DIP:DIP-14_W7.62mm")
+      (libsource (lib "74xx") (part "74HC00") (description "quad 2-input NAND")))
+    (comp (ref "J1")
+      (value "Conn_01x06")
+      (footprint "Connector_PinHeader_2.54mm:PinHeader_1x06_P2.54mm_Vertical")
+      (libsource (lib "Connector_Generic") (part "Conn_01x06") (description "power and Tied ports"))))
+  (nets
+    (net (code "1") (name "VCC")
+      (node (ref "J1") (pin "1"))
+      (node (ref "J1") (pin "5"))
+      (node (ref "U1") (pin "14"))
+      (node (ref "U1") (pin "2")))
+    (net (code "2") (name "GND")
+      (node (ref "J1") (pin "2"))
+      (node (ref "J1") (pin "6"))
+      (node (ref "U1") (pin "7"))
+      (node (ref "U1") (pin "9"))
+      (node (ref "U1") (pin "10"))
+      (node (ref "U1") (pin "12"))
+      (node (ref "U1") (pin "13")))
+    (net (code "3") (name "a")
+      (node (ref "J1") (pin "3"))
+      (node (ref "U1") (pin "1")))
+    (net (code "4") (name "r")
+      (node (ref "J1") (pin "4"))
+      (node (ref "U1") (pin "6")))
+    (net (code "5") (name "And_i1_i")
+      (node (ref "U1") (pin "3"))
+      (node (ref "U1") (pin "4"))
+      (node (ref "U1") (pin "5")))))
+`[1:]
+	if actual := sb.String(); actual != expected {
+		t.Errorf("WriteKiCad() = %s; want %s", actual, expected)
+	}
+}
+
+func TestWriteBOM(t *testing.T) {
+	system := logictest.Build(t, src)
+	var sb strings.Builder
+	if err := pack(t, system.Components["Add1"]).WriteBOM(&sb); err != nil {
+		t.Fatalf("WriteBOM() failed: %v", err)
+	}
+	actual := sb.String()
+	for _, line := range []string{
+		"4    U1-U4      74HC00       quad 2-input NAND, DIP-14\n",
+		"1    J1         Conn_01x07   pin header: VCC, GND, a, b, ci, r, co\n",
+		"15 gates on 4 chips, 1 unused\n",
+		"  U2 gate 4 (pins 12, 13 -> 11): r = nand(Xor_i2_i2, Xor_i2_i3)\n",
+		"  J1.3     U1.1     a\n",
+	} {
+		if !strings.Contains(actual, line) {
+			t.Errorf("WriteBOM() is missing %q:\n%s", line, actual)
+		}
+	}
+}
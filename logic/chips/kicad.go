@@ -0,0 +1,48 @@
+package chips
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func (b *Board) WriteKiCad(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("(export (version \"E\")\n")
+	sb.WriteString("  (design\n")
+	fmt.Fprintf(&sb, "    (source %s)\n", quote(b.Name))
+	sb.WriteString("    (tool \"mercury\"))\n")
+	sb.WriteString("  (components\n")
+	for _, chip := range b.Chips {
+		fmt.Fprintf(&sb, "    (comp (ref %s)\n", quote(chip.Ref))
+		sb.WriteString("      (value \"74HC00\")\n")
+		sb.WriteString("      (footprint \"Package_DIP:DIP-14_W7.62mm\")\n")
+		sb.WriteString("      (libsource (lib \"74xx\") (part \"74HC00\") (description \"quad 2-input NAND\")))\n")
+	}
+	pins := strconv.Itoa(len(b.Connector))
+	if len(b.Connector) < 10 {
+		pins = "0" + pins
+	}
+	sb.WriteString("    (comp (ref \"J1\")\n")
+	fmt.Fprintf(&sb, "      (value \"Conn_01x%s\")\n", pins)
+	fmt.Fprintf(&sb, "      (footprint \"Connector_PinHeader_2.54mm:PinHeader_1x%s_P2.54mm_Vertical\")\n", pins)
+	fmt.Fprintf(&sb, "      (libsource (lib \"Connector_Generic\") (part \"Conn_01x%s\") (description \"power and %s ports\"))))\n", pins, b.Name)
+	sb.WriteString("  (nets")
+	for i, net := range b.Nets {
+		fmt.Fprintf(&sb, "\n    (net (code \"%d\") (name %s)", i+1, quote(net.Name))
+		for _, pin := range net.Pins {
+			fmt.Fprintf(&sb, "\n      (node (ref %s) (pin \"%d\"))", quote(pin.Ref), pin.Number)
+		}
+		sb.WriteString(")")
+	}
+	sb.WriteString("))\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
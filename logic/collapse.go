@@ -8,11 +8,13 @@ import (
 func (c *Component) Collapse(newName string) *Component {
 	cl := &collapser{
 		result: &Component{
-			name:      newName,
-			Buses:     make(map[string]*Bus),
-			Instances: nil,
+			name:              newName,
+			Buses:             make(map[string]*Bus),
+			Instances:         nil,
+			hierarchicalNames: make(map[string]string),
 		},
 		componentInstanceCounts: make(map[string]int),
+		instancePaths:           make(map[collapsedInstanceName]string),
 		wireLookup:              make(map[collapsedInstanceWire]BusWire),
 	}
 	instanceName := cl.defineInstance(c.Name())
@@ -20,7 +22,7 @@ func (c *Component) Collapse(newName string) *Component {
 	cl.collapseBuses(instanceName, c.Buses, make(map[string]struct{}))
 	for _, childInstance := range c.Instances {
 		inputWires, outputWires := cl.ioWiresForInstance(childInstance, instanceName)
-		cl.collapseInstance(childInstance, inputWires, outputWires)
+		cl.collapseInstance(childInstance, instanceName, inputWires, outputWires)
 	}
 	for _, name := range c.InputBusNames {
 		bus := c.Buses[name]
@@ -56,6 +58,7 @@ type collapser struct {
 	result                  *Component
 	resultInstanceName      collapsedInstanceName
 	componentInstanceCounts map[string]int
+	instancePaths           map[collapsedInstanceName]string
 	wireLookup              map[collapsedInstanceWire]BusWire
 }
 
@@ -81,6 +84,17 @@ func (c *collapser) lookupBusWire(instanceName collapsedInstanceName, busWire Bu
 	})
 }
 
+func (c *collapser) hierarchicalName(instanceName collapsedInstanceName, bus *Bus, i WireIndex) string {
+	name := bus.Name
+	if bus.Wires() > 1 {
+		name += "[" + strconv.Itoa(int(i)) + "]"
+	}
+	if path := c.instancePaths[instanceName]; path != "" {
+		name = path + "/" + name
+	}
+	return name
+}
+
 func (c *collapser) rememberWire(wire collapsedInstanceWire, busWire BusWire) {
 	if old, ok := c.wireLookup[wire]; ok {
 		panic(fmt.Errorf("already defined collapsed instance wire: old %v, new %v", old, wire))
@@ -90,39 +104,40 @@ func (c *collapser) rememberWire(wire collapsedInstanceWire, busWire BusWire) {
 }
 
 func (c *collapser) collapseBus(instanceName collapsedInstanceName, bus *Bus) {
-	if bus.Wires() == 1 {
-		var newBusName string
-		if instanceName == c.resultInstanceName {
-			newBusName = bus.Name
-		} else {
-			newBusName = string(instanceName) + "_" + bus.Name
+	prefix := ""
+	if instanceName != c.resultInstanceName {
+		prefix = string(instanceName) + "_"
+	}
+	for i := 0; i < bus.Wires(); i++ {
+		wire := BusWire{
+			Bus:       NewBus(collapsedWireName(prefix, bus, WireIndex(i)), 1),
+			WireIndex: 0,
 		}
 		c.rememberWire(collapsedInstanceWire{
 			instanceName: instanceName,
 			busName:      bus.Name,
-			wireIndex:    0,
-		}, BusWire{
-			Bus:       NewBus(newBusName, 1),
-			WireIndex: 0,
-		})
-	} else {
-		for i := 0; i < bus.Wires(); i++ {
-			var newBusName string
-			if instanceName == c.resultInstanceName {
-				newBusName = bus.Name + strconv.Itoa(i)
-			} else {
-				newBusName = string(instanceName) + "_" + bus.Name + strconv.Itoa(i)
-			}
-			c.rememberWire(collapsedInstanceWire{
-				instanceName: instanceName,
-				busName:      bus.Name,
-				wireIndex:    WireIndex(i),
-			}, BusWire{
-				Bus:       NewBus(newBusName, 1),
-				WireIndex: 0,
-			})
-		}
+			wireIndex:    WireIndex(i),
+		}, wire)
+		c.result.hierarchicalNames[wire.Bus.Name] = c.hierarchicalName(instanceName, bus, WireIndex(i))
+	}
+}
+
+func (c *Component) HierarchicalName(busName string) string {
+	if name, ok := c.hierarchicalNames[busName]; ok {
+		return name
 	}
+	return busName
+}
+
+func CollapsedWireName(bus *Bus, i WireIndex) string {
+	return collapsedWireName("", bus, i)
+}
+
+func collapsedWireName(prefix string, bus *Bus, i WireIndex) string {
+	if bus.Wires() == 1 {
+		return prefix + bus.Name
+	}
+	return prefix + bus.Name + strconv.Itoa(int(i))
 }
 
 func (c *collapser) collapseBuses(instanceName collapsedInstanceName, buses map[string]*Bus, exclude map[string]struct{}) {
@@ -146,22 +161,28 @@ func (c *collapser) ioWiresForInstance(instance *Instance, parentInstanceName co
 	return inputWires, outputWires
 }
 
-func (c *collapser) collapseInstance(instance *Instance, inputWires []BusWire, outputWires []BusWire) {
+func (c *collapser) collapseInstance(instance *Instance, parentInstanceName collapsedInstanceName, inputWires []BusWire, outputWires []BusWire) {
 	switch def := instance.Definition.(type) {
 	case *Constants:
 		c.result.Instances = append(c.result.Instances, &Instance{
 			Definition: NewConstants(def.values),
 			Inputs:     nil,
 			Outputs:    outputWires,
+			Pos:        instance.Pos,
 		})
-	case NandGate:
+	case NandGate, TriGate:
 		c.result.Instances = append(c.result.Instances, &Instance{
-			Definition: Nand,
+			Definition: def,
 			Inputs:     inputWires,
 			Outputs:    outputWires,
+			Pos:        instance.Pos,
 		})
 	case *Component:
 		instanceName := c.defineInstance(def.name)
+		c.instancePaths[instanceName] = string(instanceName)
+		if path := c.instancePaths[parentInstanceName]; path != "" {
+			c.instancePaths[instanceName] = path + "/" + string(instanceName)
+		}
 		ioBusNames := make(map[string]struct{})
 		inputWireIndex := 0
 		for _, busName := range def.InputBusNames {
@@ -192,7 +213,7 @@ func (c *collapser) collapseInstance(instance *Instance, inputWires []BusWire, o
 		c.collapseBuses(instanceName, def.Buses, ioBusNames)
 		for _, childInstance := range def.Instances {
 			childInputWires, childOutputWires := c.ioWiresForInstance(childInstance, instanceName)
-			c.collapseInstance(childInstance, childInputWires, childOutputWires)
+			c.collapseInstance(childInstance, instanceName, childInputWires, childOutputWires)
 		}
 	default:
 		panic(fmt.Errorf("unexpected logic.Definition: %t", def))
@@ -2,6 +2,7 @@ package logic
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -12,6 +13,8 @@ type Component struct {
 	InputBusNames  []string
 	OutputBusNames []string
 	Instances      []*Instance
+
+	hierarchicalNames map[string]string
 }
 
 func NewComponent(name string, inputs, outputs []*Bus) *Component {
@@ -70,6 +73,27 @@ func (c *Component) OutputWires() int {
 	return wires
 }
 
+func ComparePorts(a, b *Component) error {
+	if err := checkBusWidths(a, b, a.InputBusNames, b.InputBusNames, "input"); err != nil {
+		return err
+	}
+	return checkBusWidths(a, b, a.OutputBusNames, b.OutputBusNames, "output")
+}
+
+func checkBusWidths(a, b *Component, aNames, bNames []string, kind string) error {
+	if len(aNames) != len(bNames) {
+		return fmt.Errorf("different number of %s buses: %s has %d, %s has %d", kind, a.Name(), len(aNames), b.Name(), len(bNames))
+	}
+	for i := range aNames {
+		aWires := a.Buses[aNames[i]].Wires()
+		bWires := b.Buses[bNames[i]].Wires()
+		if aWires != bWires {
+			return fmt.Errorf("different widths of %s bus %d: %s has %d, %s has %d", kind, i, a.Name(), aWires, b.Name(), bWires)
+		}
+	}
+	return nil
+}
+
 func (c *Component) String() string {
 	var sb strings.Builder
 	sb.WriteString("component ")
@@ -104,7 +128,13 @@ func (c *Component) String() string {
 		}
 	}
 	sb.WriteString(") {\n")
-	for name, bus := range c.Buses {
+	var names []string
+	for name := range c.Buses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		bus := c.Buses[name]
 		if bus.Wires() == 1 {
 			continue
 		} else if _, ok := ioBusNames[name]; ok {
@@ -0,0 +1,241 @@
+package dot
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+func Write(w io.Writer, c *logic.Component) error {
+	g := newGraph(c)
+	g.addInstances(c)
+	return g.write(w)
+}
+
+func WriteFlat(w io.Writer, c *logic.Component) error {
+	g := newGraph(c)
+	for _, names := range [][]string{c.InputBusNames, c.OutputBusNames} {
+		for _, name := range names {
+			bus := c.Buses[name]
+			for i := 0; i < bus.Wires(); i++ {
+				g.aliases[logic.CollapsedWireName(bus, logic.WireIndex(i))] = logic.BusWire{
+					Bus:       bus,
+					WireIndex: logic.WireIndex(i),
+				}
+			}
+		}
+	}
+	g.addInstances(c.Collapse(c.Name()))
+	return g.write(w)
+}
+
+type node struct {
+	id    string
+	attrs string
+}
+
+type edgeKey struct {
+	from, to string
+	bus      *logic.Bus
+}
+
+type edge struct {
+	edgeKey
+	indices  []int
+	feedback bool
+}
+
+type graph struct {
+	c       *logic.Component
+	aliases map[string]logic.BusWire
+	drivers map[logic.BusWire][]string
+	nodes   []node
+	edges   []*edge
+	edgeMap map[edgeKey]*edge
+}
+
+func newGraph(c *logic.Component) *graph {
+	return &graph{
+		c:       c,
+		aliases: make(map[string]logic.BusWire),
+		drivers: make(map[logic.BusWire][]string),
+		edgeMap: make(map[edgeKey]*edge),
+	}
+}
+
+func (g *graph) resolve(wire logic.BusWire) logic.BusWire {
+	if alias, ok := g.aliases[wire.Bus.Name]; ok {
+		return alias
+	}
+	return wire
+}
+
+func (g *graph) addInstances(c *logic.Component) {
+	for _, name := range g.c.InputBusNames {
+		bus := g.c.Buses[name]
+		for i := 0; i < bus.Wires(); i++ {
+			wire := logic.BusWire{Bus: bus, WireIndex: logic.WireIndex(i)}
+			g.drivers[wire] = append(g.drivers[wire], "in:"+name)
+		}
+	}
+	for i, instance := range c.Instances {
+		id := "n" + strconv.Itoa(i)
+		for _, output := range instance.Outputs {
+			wire := g.resolve(output)
+			g.drivers[wire] = append(g.drivers[wire], id)
+		}
+		switch def := instance.Definition.(type) {
+		case *logic.Constants:
+			g.nodes = append(g.nodes, node{id, fmt.Sprintf("label=%s, shape=plaintext", quote(def.String()))})
+		case logic.NandGate, logic.TriGate, *logic.Component:
+			g.nodes = append(g.nodes, node{id, "label=" + quote(def.Name())})
+		default:
+			panic(fmt.Errorf("unexpected logic.Definition: %t", def))
+		}
+	}
+	for i, instance := range c.Instances {
+		id := "n" + strconv.Itoa(i)
+		for _, input := range instance.Inputs {
+			g.connect(g.resolve(input), id)
+		}
+	}
+	for _, name := range g.c.OutputBusNames {
+		bus := g.c.Buses[name]
+		for i := 0; i < bus.Wires(); i++ {
+			g.connect(logic.BusWire{Bus: bus, WireIndex: logic.WireIndex(i)}, "out:"+name)
+		}
+	}
+	g.markFeedback()
+}
+
+func (g *graph) connect(wire logic.BusWire, to string) {
+	for _, from := range g.drivers[wire] {
+		key := edgeKey{from, to, wire.Bus}
+		e, ok := g.edgeMap[key]
+		if !ok {
+			e = &edge{edgeKey: key}
+			g.edgeMap[key] = e
+			g.edges = append(g.edges, e)
+		}
+		e.indices = append(e.indices, int(wire.WireIndex))
+	}
+}
+
+func (g *graph) markFeedback() {
+	successors := make(map[string][]*edge)
+	for _, e := range g.edges {
+		successors[e.from] = append(successors[e.from], e)
+	}
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make(map[string]int)
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = active
+		for _, e := range successors[id] {
+			switch state[e.to] {
+			case unvisited:
+				visit(e.to)
+			case active:
+				e.feedback = true
+			}
+		}
+		state[id] = done
+	}
+	for _, name := range g.c.InputBusNames {
+		if state["in:"+name] == unvisited {
+			visit("in:" + name)
+		}
+	}
+	for _, n := range g.nodes {
+		if state[n.id] == unvisited {
+			visit(n.id)
+		}
+	}
+}
+
+func (g *graph) write(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("digraph ")
+	sb.WriteString(quote(g.c.Name()))
+	sb.WriteString(" {\n")
+	sb.WriteString("    rankdir=LR;\n")
+	sb.WriteString("    node [shape=box];\n")
+	for _, kind := range []struct {
+		rank   string
+		prefix string
+		shape  string
+		names  []string
+	}{
+		{"source", "in:", "invhouse", g.c.InputBusNames},
+		{"sink", "out:", "house", g.c.OutputBusNames},
+	} {
+		if len(kind.names) == 0 {
+			continue
+		}
+		sb.WriteString("    {\n")
+		fmt.Fprintf(&sb, "        rank=%s;\n", kind.rank)
+		for _, name := range kind.names {
+			label := name
+			if width := g.c.Buses[name].Wires(); width > 1 {
+				label += "[" + strconv.Itoa(width) + "]"
+			}
+			fmt.Fprintf(&sb, "        %s [label=%s, shape=%s];\n", quote(kind.prefix+name), quote(label), kind.shape)
+		}
+		sb.WriteString("    }\n")
+	}
+	for _, n := range g.nodes {
+		fmt.Fprintf(&sb, "    %s [%s];\n", quote(n.id), n.attrs)
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&sb, "    %s -> %s [label=%s", quote(e.from), quote(e.to), quote(edgeLabel(e)))
+		if e.feedback {
+			sb.WriteString(", color=red, penwidth=2, constraint=false")
+		}
+		sb.WriteString("];\n")
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func edgeLabel(e *edge) string {
+	if e.bus.Wires() == 1 {
+		return e.bus.Name
+	}
+	indices := append([]int(nil), e.indices...)
+	sort.Ints(indices)
+	var sb strings.Builder
+	sb.WriteString(e.bus.Name)
+	sb.WriteString("[")
+	for i := 0; i < len(indices); {
+		j := i
+		for j+1 < len(indices) && indices[j+1] <= indices[j]+1 {
+			j++
+		}
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(strconv.Itoa(indices[i]))
+		if indices[j] > indices[i] {
+			sb.WriteString(":")
+			sb.WriteString(strconv.Itoa(indices[j]))
+		}
+		i = j + 1
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,107 @@
+package dot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+const src = logictest.Not + `
+component Swap(a[4])(r[4], k) {
+    r[0]: Not(a[1])
+    r[1]: Not(a[0])
+    r[3]: Not(a[3])
+    r[2]: nand(a[2], a[3])
+    k: 1
+}
+
+component Memory1(s, r)(q, 'q) {
+    's: nand(s, s)
+    'r: nand(r, r)
+    q: nand('s, 'q)
+    'q: nand('r, q)
+}
+`
+
+func TestWrite(t *testing.T) {
+	system := logictest.Build(t, src)
+	var sb strings.Builder
+	if err := Write(&sb, system.Components["Swap"]); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	expected := `
+digraph "Swap" {
+    rankdir=LR;
+    node [shape=box];
+    {
+        rank=source;
+        "in:a" [label="a[4]", shape=invhouse];
+    }
+    {
+        rank=sink;
+        "out:r" [label="r[4]", shape=house];
+        "out:k" [label="k", shape=house];
+    }
+    "n0" [label="Not"];
+    "n1" [label="Not"];
+    "n2" [label="Not"];
+    "n3" [label="nand"];
+    "n4" [label="1", shape=plaintext];
+    "in:a" -> "n0" [label="a[1]"];
+    "in:a" -> "n1" [label="a[0]"];
+    "in:a" -> "n2" [label="a[3]"];
+    "in:a" -> "n3" [label="a[2:3]"];
+    "n0" -> "out:r" [label="r[0]"];
+    "n1" -> "out:r" [label="r[1]"];
+    "n3" -> "out:r" [label="r[2]"];
+    "n2" -> "out:r" [label="r[3]"];
+    "n4" -> "out:k" [label="k"];
+}
+`[1:]
+	if actual := sb.String(); actual != expected {
+		t.Errorf("Write() = %s; want %s", actual, expected)
+	}
+}
+
+func TestWriteFlat(t *testing.T) {
+	system := logictest.Build(t, src)
+	var sb strings.Builder
+	if err := WriteFlat(&sb, system.Components["Swap"]); err != nil {
+		t.Fatalf("WriteFlat() failed: %v", err)
+	}
+	actual := sb.String()
+	if strings.Contains(actual, `label="Not"`) {
+		t.Errorf("WriteFlat() did not collapse instances:\n%s", actual)
+	}
+	if n := strings.Count(actual, `[label="nand"]`); n != 4 {
+		t.Errorf("WriteFlat() produced %d NAND gates, want 4:\n%s", n, actual)
+	}
+	for _, line := range []string{
+		`"in:a" [label="a[4]", shape=invhouse];`,
+		`"in:a" -> "n2" [label="a[3]"];`,
+		`"n2" -> "out:r" [label="r[3]"];`,
+	} {
+		if !strings.Contains(actual, line) {
+			t.Errorf("WriteFlat() is missing %q:\n%s", line, actual)
+		}
+	}
+	if strings.Contains(actual, "color=red") {
+		t.Errorf("WriteFlat() highlighted feedback in combinational component:\n%s", actual)
+	}
+}
+
+func TestWriteFeedback(t *testing.T) {
+	system := logictest.Build(t, src)
+	var sb strings.Builder
+	if err := Write(&sb, system.Components["Memory1"]); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	actual := sb.String()
+	if n := strings.Count(actual, "color=red"); n != 1 {
+		t.Errorf("Write() highlighted %d feedback edges, want 1:\n%s", n, actual)
+	}
+	if !strings.Contains(actual, `"n3" -> "n2" [label="'q", color=red, penwidth=2, constraint=false];`) {
+		t.Errorf("Write() did not highlight feedback edge:\n%s", actual)
+	}
+}
@@ -0,0 +1,105 @@
+package equiv
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/sat"
+)
+
+var exhaustiveLimit = 16
+
+type Counterexample struct {
+	A, B     *logic.Component
+	Inputs   *logic.Constants
+	AOutputs *logic.Constants
+	BOutputs *logic.Constants
+}
+
+func (c *Counterexample) String() string {
+	return fmt.Sprintf("%s and %s differ for %s = %v: %s = %v vs. %s = %v",
+		c.A.Name(), c.B.Name(),
+		strings.Join(c.A.InputBusNames, ", "), c.Inputs,
+		strings.Join(c.A.OutputBusNames, ", "), c.AOutputs,
+		strings.Join(c.B.OutputBusNames, ", "), c.BOutputs)
+}
+
+func Check(a, b *logic.Component) (*Counterexample, error) {
+	if err := logic.ComparePorts(a, b); err != nil {
+		return nil, err
+	}
+	if a.InputWires() > exhaustiveLimit {
+		return checkWithSAT(a, b)
+	}
+	ea, err := newEvaluator(a)
+	if err != nil {
+		return nil, err
+	}
+	eb, err := newEvaluator(b)
+	if err != nil {
+		return nil, err
+	}
+	return checkExhaustively(ea, eb), nil
+}
+
+func checkExhaustively(ea, eb *evaluator) *Counterexample {
+	n := ea.component.InputWires()
+	inputs := make([]bool, n)
+	for x := 0; x < 1<<n; x++ {
+		for i := range inputs {
+			inputs[i] = (x>>i)%2 == 1
+		}
+		if c := compare(ea, eb, inputs); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+func checkWithSAT(a, b *logic.Component) (*Counterexample, error) {
+	f := sat.NewCNF()
+	ca, cb, err := sat.Miter(f, a, b)
+	if err != nil {
+		return nil, err
+	}
+	model, ok := sat.Solve(f)
+	if !ok {
+		return nil, nil
+	}
+	return &Counterexample{
+		A:        a,
+		B:        b,
+		Inputs:   ca.InputValues(model),
+		AOutputs: ca.OutputValues(model),
+		BOutputs: cb.OutputValues(model),
+	}, nil
+}
+
+func compare(ea, eb *evaluator, inputs []bool) *Counterexample {
+	aOutputs := ea.evaluate(inputs)
+	bOutputs := eb.evaluate(inputs)
+	for i := range aOutputs {
+		if aOutputs[i] != bOutputs[i] {
+			return &Counterexample{
+				A:        ea.component,
+				B:        eb.component,
+				Inputs:   group(inputs, ea.component, ea.component.InputBusNames),
+				AOutputs: group(aOutputs, ea.component, ea.component.OutputBusNames),
+				BOutputs: group(bOutputs, eb.component, eb.component.OutputBusNames),
+			}
+		}
+	}
+	return nil
+}
+
+func group(wires []bool, c *logic.Component, busNames []string) *logic.Constants {
+	vals := make([]logic.Value, len(busNames))
+	wireIndex := 0
+	for i, name := range busNames {
+		bus := c.Buses[name]
+		vals[i] = logic.Value(wires[wireIndex : wireIndex+bus.Wires()])
+		wireIndex += bus.Wires()
+	}
+	return logic.NewConstants(vals)
+}
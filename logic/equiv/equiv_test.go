@@ -0,0 +1,99 @@
+package equiv
+
+import (
+	"testing"
+
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+const src = logictest.Gates + logictest.Xor2 + `
+component Xnor(a, b)(r) {
+    i: Xor(a, b)
+    r: Not(i)
+}
+
+component Xor4(a[4], b[4])(r[4]) {
+    for i from 0 to 3 {
+        r[i]: Xor(a[i], b[i])
+    }
+}
+
+component Xor4Alt(a[4], b[4])(r[4]) {
+    r[0]: Xor2(a[0], b[0])
+    r[1]: Xor2(a[1], b[1])
+    r[2]: Xor(a[2], b[2])
+    r[3]: Xnor(a[3], b[3])
+}
+
+component Latch(s, r)(q) {
+    'q: nand(r, q)
+    q: nand(s, 'q)
+}
+`
+
+func checkModes(t *testing.T, f func(t *testing.T)) {
+	t.Run("exhaustive", f)
+	t.Run("sat", func(t *testing.T) {
+		defer func(limit int) { exhaustiveLimit = limit }(exhaustiveLimit)
+		exhaustiveLimit = 0
+		f(t)
+	})
+}
+
+func TestEquivalentComponents(t *testing.T) {
+	system := logictest.Build(t, src)
+	checkModes(t, func(t *testing.T) {
+		counterexample, err := Check(system.Components["Xor"], system.Components["Xor2"])
+		if err != nil {
+			t.Fatalf("Check() failed: %v", err)
+		}
+		if counterexample != nil {
+			t.Errorf("Check() = %v; want nil", counterexample)
+		}
+	})
+}
+
+func TestDifferentComponents(t *testing.T) {
+	system := logictest.Build(t, src)
+	checkModes(t, func(t *testing.T) {
+		counterexample, err := Check(system.Components["Xor4"], system.Components["Xor4Alt"])
+		if err != nil {
+			t.Fatalf("Check() failed: %v", err)
+		}
+		if counterexample == nil {
+			t.Fatalf("Check() = nil; want counterexample")
+		}
+		a := counterexample.Inputs.Values()[0]
+		b := counterexample.Inputs.Values()[1]
+		aOutput := counterexample.AOutputs.Values()[0]
+		bOutput := counterexample.BOutputs.Values()[0]
+		for i := 0; i < 3; i++ {
+			if aOutput[i] != bOutput[i] {
+				t.Errorf("counterexample outputs differ in wire %d; want only wire 3 to differ", i)
+			}
+		}
+		if aOutput[3] != (a[3] != b[3]) {
+			t.Errorf("counterexample output of Xor4 = %v; want xor of %v and %v", aOutput, a, b)
+		}
+		if aOutput[3] == bOutput[3] {
+			t.Errorf("counterexample outputs agree in wire 3: %v", counterexample)
+		}
+	})
+}
+
+func TestRejectsDifferentPorts(t *testing.T) {
+	system := logictest.Build(t, src)
+	if _, err := Check(system.Components["Xor"], system.Components["Not"]); err == nil {
+		t.Errorf("Check() succeeded for different input buses; want error")
+	}
+	if _, err := Check(system.Components["Xor"], system.Components["Xor4"]); err == nil {
+		t.Errorf("Check() succeeded for different bus widths; want error")
+	}
+}
+
+func TestRejectsSequentialComponents(t *testing.T) {
+	system := logictest.Build(t, src)
+	if _, err := Check(system.Components["Latch"], system.Components["And"]); err == nil {
+		t.Errorf("Check() succeeded for sequential component; want error")
+	}
+}
@@ -0,0 +1,62 @@
+package equiv
+
+import (
+	"fmt"
+
+	"github.com/arneph/mercury/logic"
+)
+
+type evaluator struct {
+	component *logic.Component
+	collapsed *logic.Component
+	order     []*logic.Instance
+}
+
+func newEvaluator(c *logic.Component) (*evaluator, error) {
+	if c.HasTriGates() {
+		return nil, fmt.Errorf("%s: tri-state buffers are not supported", c.Name())
+	}
+	collapsed := c.Collapse(c.Name())
+	levels, err := collapsed.Levelize()
+	if err != nil {
+		return nil, err
+	}
+	e := &evaluator{
+		component: c,
+		collapsed: collapsed,
+	}
+	for _, level := range levels {
+		e.order = append(e.order, level...)
+	}
+	return e, nil
+}
+
+func (e *evaluator) evaluate(inputs []bool) []bool {
+	values := make(map[*logic.Bus]bool, len(e.collapsed.Buses))
+	for i, name := range e.collapsed.InputBusNames {
+		values[e.collapsed.Buses[name]] = inputs[i]
+	}
+	for _, instance := range e.order {
+		switch def := instance.Definition.(type) {
+		case logic.NandGate:
+			a := values[instance.Inputs[0].Bus]
+			b := values[instance.Inputs[1].Bus]
+			values[instance.Outputs[0].Bus] = !(a && b)
+		case *logic.Constants:
+			var bits []bool
+			for _, value := range def.Values() {
+				bits = append(bits, value...)
+			}
+			for i, output := range instance.Outputs {
+				values[output.Bus] = bits[i]
+			}
+		default:
+			panic(fmt.Errorf("unexpected logic.Definition: %t", def))
+		}
+	}
+	outputs := make([]bool, len(e.collapsed.OutputBusNames))
+	for i, name := range e.collapsed.OutputBusNames {
+		outputs[i] = values[e.collapsed.Buses[name]]
+	}
+	return outputs
+}
@@ -0,0 +1,316 @@
+package gogen
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+func Write(w io.Writer, c *logic.Component, pkg string) error {
+	if !token.IsIdentifier(pkg) {
+		return fmt.Errorf("invalid package name: %q", pkg)
+	} else if c.HasTriGates() {
+		return fmt.Errorf("%s: tri-state buffers are not supported", c.Name())
+	}
+	g := &generator{
+		c:         c,
+		collapsed: c.Collapse(c.Name()),
+		names:     make(map[*logic.Bus]string),
+		used: map[string]bool{
+			"bool": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+			"true": true, "false": true, "error": true, "errors": true, "nil": true,
+			"Step": true, "settle": true, "stable": true, "v": true, "i": true, "err": true,
+		},
+	}
+	if err := g.declarePorts(); err != nil {
+		return err
+	}
+	fmt.Fprintf(&g.sb, "package %s\n\n", pkg)
+	if levels, err := g.collapsed.Levelize(); err == nil {
+		g.writeFunction(levels)
+	} else {
+		g.sb.WriteString("import \"errors\"\n\n")
+		g.writeStruct()
+	}
+	src, err := format.Source([]byte(g.sb.String()))
+	if err != nil {
+		return fmt.Errorf("could not format generated source: %v", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+type port struct {
+	name  string
+	bus   *logic.Bus
+	wires []*logic.Bus
+}
+
+type generator struct {
+	sb        strings.Builder
+	c         *logic.Component
+	collapsed *logic.Component
+	name      string
+	inputs    []port
+	outputs   []port
+	names     map[*logic.Bus]string
+	used      map[string]bool
+}
+
+func identifier(name string) string {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		switch {
+		case ch == '_' || ('A' <= ch && ch <= 'Z') || ('a' <= ch && ch <= 'z'):
+			sb.WriteByte(ch)
+		case '0' <= ch && ch <= '9':
+			if i == 0 {
+				sb.WriteByte('_')
+			}
+			sb.WriteByte(ch)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	if sb.Len() == 0 {
+		return "_x"
+	} else if token.IsKeyword(sb.String()) {
+		sb.WriteByte('_')
+	}
+	return sb.String()
+}
+
+func (g *generator) unique(name string) string {
+	unique := name
+	for i := 1; g.used[unique]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	g.used[unique] = true
+	return unique
+}
+
+func (g *generator) declarePorts() error {
+	name := identifier(g.c.Name())
+	g.name = g.unique(strings.ToUpper(name[:1]) + name[1:])
+	for _, kind := range []struct {
+		names []string
+		ports *[]port
+	}{
+		{g.c.InputBusNames, &g.inputs},
+		{g.c.OutputBusNames, &g.outputs},
+	} {
+		for _, busName := range kind.names {
+			bus := g.c.Buses[busName]
+			if bus.Wires() > 64 {
+				return fmt.Errorf("bus %s has %d wires; at most 64 are supported", busName, bus.Wires())
+			}
+			p := port{name: g.unique(identifier(busName)), bus: bus}
+			for i := 0; i < bus.Wires(); i++ {
+				wire := g.collapsed.Buses[logic.CollapsedWireName(bus, logic.WireIndex(i))]
+				if bus.Wires() == 1 {
+					g.names[wire] = p.name
+				}
+				p.wires = append(p.wires, wire)
+			}
+			*kind.ports = append(*kind.ports, p)
+		}
+	}
+	return nil
+}
+
+func (g *generator) wireName(bus *logic.Bus) string {
+	name, ok := g.names[bus]
+	if !ok {
+		name = g.unique(identifier(bus.Name))
+		g.names[bus] = name
+	}
+	return name
+}
+
+func goType(width int) string {
+	switch {
+	case width == 1:
+		return "bool"
+	case width <= 8:
+		return "uint8"
+	case width <= 16:
+		return "uint16"
+	case width <= 32:
+		return "uint32"
+	default:
+		return "uint64"
+	}
+}
+
+func (g *generator) signature(ports []port) string {
+	var sb strings.Builder
+	for i, p := range ports {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(p.name)
+		if i+1 == len(ports) || goType(p.bus.Wires()) != goType(ports[i+1].bus.Wires()) {
+			sb.WriteString(" ")
+			sb.WriteString(goType(p.bus.Wires()))
+		}
+	}
+	return sb.String()
+}
+
+func (g *generator) writeFunction(levels [][]*logic.Instance) {
+	fmt.Fprintf(&g.sb, "func %s(%s) (%s) {\n", g.name, g.signature(g.inputs), g.signature(g.outputs))
+	defined := make(map[*logic.Bus]bool)
+	for _, p := range g.inputs {
+		for i, wire := range p.wires {
+			defined[wire] = true
+			if len(p.wires) > 1 {
+				fmt.Fprintf(&g.sb, "%s := %s&(1<<%d) != 0\n", g.wireName(wire), p.name, i)
+			}
+		}
+	}
+	results := make(map[*logic.Bus]bool)
+	for _, p := range g.outputs {
+		if len(p.wires) == 1 {
+			results[p.wires[0]] = true
+		}
+	}
+	operand := func(wire logic.BusWire) string {
+		if !defined[wire.Bus] {
+			return "false"
+		}
+		return g.wireName(wire.Bus)
+	}
+	assign := func(wire logic.BusWire, expr string) {
+		op := ":="
+		if results[wire.Bus] {
+			op = "="
+		}
+		fmt.Fprintf(&g.sb, "%s %s %s\n", g.wireName(wire.Bus), op, expr)
+		defined[wire.Bus] = true
+	}
+	for _, level := range levels {
+		for _, instance := range level {
+			switch def := instance.Definition.(type) {
+			case logic.NandGate:
+				assign(instance.Outputs[0], nand(operand(instance.Inputs[0]), operand(instance.Inputs[1])))
+			case *logic.Constants:
+				for i, bit := range constantBits(def) {
+					assign(instance.Outputs[i], strconv.FormatBool(bit))
+				}
+			default:
+				panic(fmt.Errorf("unexpected logic.Definition: %t", def))
+			}
+		}
+	}
+	for _, p := range g.outputs {
+		if len(p.wires) == 1 {
+			continue
+		}
+		for i, wire := range p.wires {
+			if defined[wire] {
+				fmt.Fprintf(&g.sb, "if %s {\n%s |= 1 << %d\n}\n", g.wireName(wire), p.name, i)
+			}
+		}
+	}
+	g.sb.WriteString("return\n}\n")
+}
+
+func nand(a, b string) string {
+	if a == b {
+		return "!" + a
+	}
+	return fmt.Sprintf("!(%s && %s)", a, b)
+}
+
+func constantBits(def *logic.Constants) []bool {
+	var bits []bool
+	for _, value := range def.Values() {
+		bits = append(bits, value...)
+	}
+	return bits
+}
+
+func (g *generator) writeStruct() {
+	receiver := g.unique(strings.ToLower(g.name[:1]))
+	field := func(bus *logic.Bus) string {
+		return receiver + "." + g.wireName(bus)
+	}
+	var body strings.Builder
+	for _, instance := range g.collapsed.Instances {
+		switch def := instance.Definition.(type) {
+		case logic.NandGate:
+			fmt.Fprintf(&body, "if v := %s; v != %s {\n%s = v\nstable = false\n}\n",
+				nand(field(instance.Inputs[0].Bus), field(instance.Inputs[1].Bus)),
+				field(instance.Outputs[0].Bus), field(instance.Outputs[0].Bus))
+		case *logic.Constants:
+			for i, bit := range constantBits(def) {
+				fmt.Fprintf(&body, "if %s != %t {\n%s = %t\nstable = false\n}\n",
+					field(instance.Outputs[i].Bus), bit, field(instance.Outputs[i].Bus), bit)
+			}
+		default:
+			panic(fmt.Errorf("unexpected logic.Definition: %t", def))
+		}
+	}
+
+	fmt.Fprintf(&g.sb, "type %s struct {\n", g.name)
+	var fields []string
+	for _, p := range g.inputs {
+		for _, wire := range p.wires {
+			fields = append(fields, g.wireName(wire))
+		}
+	}
+	for _, p := range g.outputs {
+		for _, wire := range p.wires {
+			fields = append(fields, g.wireName(wire))
+		}
+	}
+	seen := make(map[string]bool)
+	for _, name := range fields {
+		seen[name] = true
+	}
+	for _, instance := range g.collapsed.Instances {
+		for _, wires := range [][]logic.BusWire{instance.Inputs, instance.Outputs} {
+			for _, wire := range wires {
+				if name := g.wireName(wire.Bus); !seen[name] {
+					seen[name] = true
+					fields = append(fields, name)
+				}
+			}
+		}
+	}
+	fmt.Fprintf(&g.sb, "%s bool\n}\n\n", strings.Join(fields, ", "))
+
+	fmt.Fprintf(&g.sb, "func New%s() (*%s, error) {\n%s := &%s{}\nif err := %s.settle(); err != nil {\nreturn nil, err\n}\nreturn %s, nil\n}\n\n",
+		g.name, g.name, receiver, g.name, receiver, receiver)
+
+	fmt.Fprintf(&g.sb, "func (%s *%s) Step(%s) (%s, err error) {\n", receiver, g.name, g.signature(g.inputs), g.signature(g.outputs))
+	for _, p := range g.inputs {
+		for i, wire := range p.wires {
+			if len(p.wires) == 1 {
+				fmt.Fprintf(&g.sb, "%s = %s\n", field(wire), p.name)
+			} else {
+				fmt.Fprintf(&g.sb, "%s = %s&(1<<%d) != 0\n", field(wire), p.name, i)
+			}
+		}
+	}
+	fmt.Fprintf(&g.sb, "if err = %s.settle(); err != nil {\nreturn\n}\n", receiver)
+	for _, p := range g.outputs {
+		for i, wire := range p.wires {
+			if len(p.wires) == 1 {
+				fmt.Fprintf(&g.sb, "%s = %s\n", p.name, field(wire))
+			} else {
+				fmt.Fprintf(&g.sb, "if %s {\n%s |= 1 << %d\n}\n", field(wire), p.name, i)
+			}
+		}
+	}
+	g.sb.WriteString("return\n}\n\n")
+
+	fmt.Fprintf(&g.sb, "func (%s *%s) settle() error {\nfor i := 0; i <= %d; i++ {\nstable := true\n%sif stable {\nreturn nil\n}\n}\nreturn errors.New(%q)\n}\n",
+		receiver, g.name, len(g.collapsed.Instances), body.String(), g.c.Name()+" did not settle")
+}
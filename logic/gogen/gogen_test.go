@@ -0,0 +1,191 @@
+package gogen
+
+import (
+	"go/parser"
+	positions "go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+const src = logictest.Not + logictest.And + logictest.Xor + logictest.Memory1 + `
+component Add1(a, b, ci)(r, co) {
+    i1: Xor(a, b)
+    r: Xor(i1, ci)
+    i2: And(a, b)
+    i3: And(i1, ci)
+    co: nand('i2, 'i3)
+    'i2: Not(i2)
+    'i3: Not(i3)
+}
+
+component Add8(a[8], b[8])(r[8], c) {
+    define k[8], z
+    z: 0
+    r[0], k[0]: Add1(a[0], b[0], z)
+    for i from 1 to 7 {
+        r[i], k[i]: Add1(a[i], b[i], k[i-1])
+    }
+    c: And(k[7], k[7])
+}
+
+component Ring(e)(r) {
+    r: nand(e, r)
+}
+
+component Wide(a[65])(r) {
+    r: Not(a[64])
+}
+`
+
+func generate(t *testing.T, system *logic.System, name string) string {
+	t.Helper()
+	var sb strings.Builder
+	if err := Write(&sb, system.Components[name], "logic"); err != nil {
+		t.Fatalf("Write(%s) failed: %v", name, err)
+	}
+	if _, err := parser.ParseFile(positions.NewFileSet(), name+".go", sb.String(), 0); err != nil {
+		t.Fatalf("generated source for %s does not parse: %v\n%s", name, err, sb.String())
+	}
+	return sb.String()
+}
+
+func TestCombinational(t *testing.T) {
+	system := logictest.Build(t, src)
+	expected := `package logic
+
+func Xor(a, b bool) (r bool) {
+	i1 := !(a && b)
+	i2 := !(a && i1)
+	i3 := !(b && i1)
+	r = !(i2 && i3)
+	return
+}
+`
+	if actual := generate(t, system, "Xor"); actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+	expected = `package logic
+
+func Not(a bool) (r bool) {
+	r = !a
+	return
+}
+`
+	if actual := generate(t, system, "Not"); actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+	add8 := generate(t, system, "Add8")
+	if !strings.Contains(add8, "func Add8(a, b uint8) (r uint8, c bool) {") {
+		t.Errorf("unexpected signature for Add8:\n%s", add8)
+	}
+}
+
+func TestSequential(t *testing.T) {
+	system := logictest.Build(t, src)
+	memory := generate(t, system, "Memory1")
+	for _, s := range []string{
+		"type Memory1 struct {",
+		"func NewMemory1() (*Memory1, error) {",
+		"func (m *Memory1) Step(s, r bool) (q, _q bool, err error) {",
+		"func (m *Memory1) settle() error {",
+		"if v := !m.s; v != m._s {",
+	} {
+		if !strings.Contains(memory, s) {
+			t.Errorf("expected generated source to contain %q:\n%s", s, memory)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	system := logictest.Build(t, src)
+	if err := Write(&strings.Builder{}, system.Components["Wide"], "logic"); err == nil {
+		t.Errorf("expected Write to fail for bus wider than 64 wires")
+	}
+	if err := Write(&strings.Builder{}, system.Components["Xor"], "no-package"); err == nil {
+		t.Errorf("expected Write to fail for invalid package name")
+	}
+}
+
+const mainSrc = `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	for a := 0; a < 256; a++ {
+		for b := 0; b < 256; b++ {
+			r, c := Add8(uint8(a), uint8(b))
+			if int(r) != (a+b)&0xff || c != (a+b > 0xff) {
+				fmt.Printf("Add8(%d, %d) = %d, %v\n", a, b, r, c)
+				os.Exit(1)
+			}
+		}
+	}
+	m, err := NewMemory1()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if q, _, err := m.Step(true, false); err != nil || !q {
+		fmt.Println("Memory1 did not set:", err)
+		os.Exit(1)
+	}
+	if q, _, err := m.Step(false, false); err != nil || !q {
+		fmt.Println("Memory1 did not hold:", err)
+		os.Exit(1)
+	}
+	if q, _, err := m.Step(false, true); err != nil || q {
+		fmt.Println("Memory1 did not reset:", err)
+		os.Exit(1)
+	}
+	ring, err := NewRing()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if _, err := ring.Step(true); err == nil || err.Error() != "Ring did not settle" {
+		fmt.Println("Ring did not oscillate:", err)
+		os.Exit(1)
+	}
+}
+`
+
+func TestRunGenerated(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	system := logictest.Build(t, src)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":     "module generated\n\ngo 1.21\n",
+		"main.go":    mainSrc,
+		"add8.go":    strings.Replace(generate(t, system, "Add8"), "package logic", "package main", 1),
+		"memory1.go": strings.Replace(generate(t, system, "Memory1"), "package logic", "package main", 1),
+		"ring.go":    strings.Replace(generate(t, system, "Ring"), "package logic", "package main", 1),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, command := range []string{"vet", "run"} {
+		cmd := exec.Command(gobin, command, ".")
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %s failed: %v\n%s", command, err, out)
+		}
+	}
+}
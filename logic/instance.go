@@ -2,6 +2,7 @@ package logic
 
 import (
 	"fmt"
+	positions "go/token"
 	"strconv"
 	"strings"
 )
@@ -10,6 +11,7 @@ type Instance struct {
 	Definition Definition
 	Inputs     []BusWire
 	Outputs    []BusWire
+	Pos        positions.Pos
 }
 
 func (inst *Instance) String() string {
@@ -29,7 +31,7 @@ func (inst *Instance) String() string {
 	switch def := inst.Definition.(type) {
 	case *Constants:
 		sb.WriteString(def.String())
-	case NandGate, *Component:
+	case NandGate, TriGate, *Component:
 		sb.WriteString(def.Name())
 		sb.WriteString("(")
 		for i, input := range inst.Inputs {
@@ -0,0 +1,93 @@
+package logictest
+
+import (
+	positions "go/token"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/text"
+)
+
+const (
+	Not = `
+component Not(a)(r) {
+    r: nand(a, a)
+}
+`
+	And = `
+component And(a, b)(r) {
+    i: nand(a, b)
+    r: Not(i)
+}
+`
+	Or = `
+component Or(a, b)(r) {
+    'a: Not(a)
+    'b: Not(b)
+    r: nand('a, 'b)
+}
+`
+	Xor = `
+component Xor(a, b)(r) {
+    i1: nand(a, b)
+    i2: nand(a, i1)
+    i3: nand(b, i1)
+    r: nand(i2, i3)
+}
+`
+	Xor2 = `
+component Xor2(a, b)(r) {
+    'a: Not(a)
+    'b: Not(b)
+    i1: And(a, 'b)
+    i2: And('a, b)
+    r: Or(i1, i2)
+}
+`
+	Add1 = `
+component Add1(a, b, ci)(r, co) {
+    i1: Xor(a, b)
+    r: Xor(i1, ci)
+    i2: And(a, b)
+    i3: And(i1, ci)
+    co: Or(i2, i3)
+}
+`
+	Add4 = `
+component Add4(a[4], b[4], ci)(r[4], co) {
+    define c[3]
+    r[0], c[0]: Add1(a[0], b[0], ci)
+    for i from 1 to 2 {
+        r[i], c[i]: Add1(a[i], b[i], c[i-1])
+    }
+    r[3], co: Add1(a[3], b[3], c[2])
+}
+`
+	Memory1 = `
+component Memory1(s, r)(q, 'q) {
+    's: Not(s)
+    'r: Not(r)
+    q: nand('s, 'q)
+    'q: nand('r, q)
+}
+`
+	Gates = Not + And + Or + Xor
+)
+
+func Build(tb testing.TB, src string) *logic.System {
+	tb.Helper()
+	system, _ := BuildFile(tb, "test.mercury", src)
+	return system
+}
+
+func BuildFile(tb testing.TB, name, src string) (*logic.System, *positions.File) {
+	tb.Helper()
+	fileSet := positions.NewFileSet()
+	file := fileSet.AddFile(name, fileSet.Base(), len(src))
+	file.SetLinesForContent([]byte(src))
+	system, errs := text.BuildFromFile(file, []byte(src))
+	if errs.Len() > 0 {
+		tb.Fatalf("BuildFromFile() failed: %v\n%s", errs, src)
+	}
+	return system, file
+}
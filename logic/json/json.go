@@ -0,0 +1,465 @@
+package json
+
+import (
+	stdjson "encoding/json"
+	"fmt"
+	positions "go/token"
+	"sort"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+const Version = 1
+
+type system struct {
+	Version    int          `json:"version"`
+	Components []*component `json:"components"`
+	Tests      []*test      `json:"tests"`
+}
+
+type component struct {
+	Name      string      `json:"name"`
+	Inputs    []*bus      `json:"inputs"`
+	Outputs   []*bus      `json:"outputs"`
+	Buses     []*bus      `json:"buses"`
+	Instances []*instance `json:"instances"`
+}
+
+type bus struct {
+	Name  string `json:"name"`
+	Width int    `json:"width"`
+}
+
+type instance struct {
+	Kind      string   `json:"kind"`
+	Component string   `json:"component,omitempty"`
+	Values    []string `json:"values,omitempty"`
+	Inputs    []*wire  `json:"inputs"`
+	Outputs   []*wire  `json:"outputs"`
+}
+
+type wire struct {
+	Bus  string `json:"bus"`
+	Wire int    `json:"wire"`
+}
+
+type test struct {
+	Name      string  `json:"name"`
+	Component string  `json:"component,omitempty"`
+	Steps     []*step `json:"steps"`
+}
+
+type step struct {
+	Kind   string   `json:"kind"`
+	Values []string `json:"values,omitempty"`
+	Path   string   `json:"path,omitempty"`
+	A      string   `json:"a,omitempty"`
+	B      string   `json:"b,omitempty"`
+}
+
+func Marshal(s *logic.System) ([]byte, error) {
+	m := &marshaler{
+		components: make(map[string]*logic.Component),
+	}
+	for _, c := range s.Components {
+		if err := m.addComponent(c); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range s.Tests {
+		if t.Component != nil {
+			if err := m.addComponent(t.Component); err != nil {
+				return nil, err
+			}
+		}
+		for _, st := range t.Steps {
+			if st, ok := st.(*logic.CheckEquivalence); ok {
+				for _, c := range []*logic.Component{st.A, st.B} {
+					if err := m.addComponent(c); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	out := &system{
+		Version:    Version,
+		Components: []*component{},
+		Tests:      []*test{},
+	}
+	names := make([]string, 0, len(m.components))
+	for name := range m.components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out.Components = append(out.Components, marshalComponent(m.components[name]))
+	}
+	names = names[:0]
+	for name := range s.Tests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out.Tests = append(out.Tests, marshalTest(s.Tests[name]))
+	}
+	return stdjson.MarshalIndent(out, "", "  ")
+}
+
+type marshaler struct {
+	components map[string]*logic.Component
+}
+
+func (m *marshaler) addComponent(c *logic.Component) error {
+	if other, ok := m.components[c.Name()]; ok {
+		if other != c {
+			return fmt.Errorf("different components share name: %s", c.Name())
+		}
+		return nil
+	}
+	m.components[c.Name()] = c
+	for _, inst := range c.Instances {
+		if child, ok := inst.Definition.(*logic.Component); ok {
+			if err := m.addComponent(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func marshalComponent(c *logic.Component) *component {
+	out := &component{
+		Name:      c.Name(),
+		Inputs:    marshalBuses(c, c.InputBusNames),
+		Outputs:   marshalBuses(c, c.OutputBusNames),
+		Instances: []*instance{},
+	}
+	ports := make(map[string]bool)
+	for _, names := range [][]string{c.InputBusNames, c.OutputBusNames} {
+		for _, name := range names {
+			ports[name] = true
+		}
+	}
+	var internal []string
+	for name := range c.Buses {
+		if !ports[name] {
+			internal = append(internal, name)
+		}
+	}
+	sort.Strings(internal)
+	out.Buses = marshalBuses(c, internal)
+	for _, inst := range c.Instances {
+		out.Instances = append(out.Instances, marshalInstance(inst))
+	}
+	return out
+}
+
+func marshalBuses(c *logic.Component, names []string) []*bus {
+	buses := []*bus{}
+	for _, name := range names {
+		buses = append(buses, &bus{Name: name, Width: c.Buses[name].Wires()})
+	}
+	return buses
+}
+
+func marshalInstance(inst *logic.Instance) *instance {
+	out := &instance{
+		Inputs:  marshalWires(inst.Inputs),
+		Outputs: marshalWires(inst.Outputs),
+	}
+	switch def := inst.Definition.(type) {
+	case *logic.Constants:
+		out.Kind = "constants"
+		out.Values = marshalValues(def)
+	case logic.NandGate, logic.TriGate:
+		out.Kind = def.Name()
+	case *logic.Component:
+		out.Kind = "component"
+		out.Component = def.Name()
+	default:
+		panic(fmt.Errorf("unexpected logic.Definition: %t", def))
+	}
+	return out
+}
+
+func marshalWires(wires []logic.BusWire) []*wire {
+	out := []*wire{}
+	for _, w := range wires {
+		out = append(out, &wire{Bus: w.Bus.Name, Wire: int(w.WireIndex)})
+	}
+	return out
+}
+
+func marshalValues(c *logic.Constants) []string {
+	values := []string{}
+	for _, value := range c.Values() {
+		var sb strings.Builder
+		for i := len(value) - 1; i >= 0; i-- {
+			if value[i] {
+				sb.WriteByte('1')
+			} else {
+				sb.WriteByte('0')
+			}
+		}
+		values = append(values, sb.String())
+	}
+	return values
+}
+
+func marshalTest(t *logic.Test) *test {
+	out := &test{
+		Name:  t.Name(),
+		Steps: []*step{},
+	}
+	if t.Component != nil {
+		out.Component = t.Component.Name()
+	}
+	for _, st := range t.Steps {
+		switch st := st.(type) {
+		case *logic.SetInputs:
+			out.Steps = append(out.Steps, &step{Kind: "set", Values: marshalValues(st.Inputs)})
+		case *logic.CheckOutputs:
+			out.Steps = append(out.Steps, &step{Kind: st.Kind.String(), Values: marshalValues(st.Outputs)})
+		case *logic.ApplyVectors:
+			out.Steps = append(out.Steps, &step{Kind: "vectors", Path: st.Path})
+		case *logic.CheckEquivalence:
+			out.Steps = append(out.Steps, &step{Kind: "equiv", A: st.A.Name(), B: st.B.Name()})
+		default:
+			panic(fmt.Errorf("unexpected logic.TestStep: %t", st))
+		}
+	}
+	return out
+}
+
+func Unmarshal(data []byte) (*logic.System, error) {
+	var in system
+	if err := stdjson.Unmarshal(data, &in); err != nil {
+		return nil, err
+	} else if in.Version != Version {
+		return nil, fmt.Errorf("unsupported version: %d", in.Version)
+	}
+	s := logic.NewSystem()
+	for _, c := range in.Components {
+		if _, ok := s.Components[c.Name]; ok {
+			return nil, fmt.Errorf("redefinition of component: %s", c.Name)
+		}
+		inputs, err := unmarshalBuses(c.Inputs)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %v", c.Name, err)
+		}
+		outputs, err := unmarshalBuses(c.Outputs)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %v", c.Name, err)
+		}
+		component, err := newComponent(c.Name, inputs, outputs)
+		if err != nil {
+			return nil, err
+		}
+		buses, err := unmarshalBuses(c.Buses)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %v", c.Name, err)
+		}
+		for _, b := range buses {
+			if _, ok := component.Buses[b.Name]; ok {
+				return nil, fmt.Errorf("component %s: redefinition of bus: %s", c.Name, b.Name)
+			}
+			component.Buses[b.Name] = b
+		}
+		s.AddComponent(component)
+	}
+	for _, c := range in.Components {
+		component := s.Components[c.Name]
+		for i, inst := range c.Instances {
+			instance, err := unmarshalInstance(s, component, inst)
+			if err != nil {
+				return nil, fmt.Errorf("component %s: instance %d: %v", c.Name, i, err)
+			}
+			component.Instances = append(component.Instances, instance)
+		}
+	}
+	for _, t := range in.Tests {
+		if _, ok := s.Tests[t.Name]; ok {
+			return nil, fmt.Errorf("redefinition of test: %s", t.Name)
+		}
+		test, err := unmarshalTest(s, t)
+		if err != nil {
+			return nil, fmt.Errorf("test %s: %v", t.Name, err)
+		}
+		s.AddTest(test)
+	}
+	return s, nil
+}
+
+func unmarshalBuses(in []*bus) ([]*logic.Bus, error) {
+	var buses []*logic.Bus
+	for _, b := range in {
+		if b.Name == "" {
+			return nil, fmt.Errorf("bus without name")
+		} else if b.Width <= 0 {
+			return nil, fmt.Errorf("invalid width of bus %s: %d", b.Name, b.Width)
+		}
+		buses = append(buses, logic.NewBus(b.Name, b.Width))
+	}
+	return buses, nil
+}
+
+func newComponent(name string, inputs, outputs []*logic.Bus) (c *logic.Component, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("component %s: %v", name, r)
+		}
+	}()
+	return logic.NewComponent(name, inputs, outputs), nil
+}
+
+func unmarshalInstance(s *logic.System, c *logic.Component, in *instance) (*logic.Instance, error) {
+	inputs, err := unmarshalWires(c, in.Inputs)
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := unmarshalWires(c, in.Outputs)
+	if err != nil {
+		return nil, err
+	}
+	var def logic.Definition
+	var inputWires, outputWires int
+	switch in.Kind {
+	case "constants":
+		constants, err := unmarshalValues(in.Values)
+		if err != nil {
+			return nil, err
+		}
+		def = constants
+		for _, value := range constants.Values() {
+			outputWires += len(value)
+		}
+	case "nand":
+		def = logic.Nand
+		inputWires, outputWires = 2, 1
+	case "tri":
+		def = logic.Tri
+		inputWires, outputWires = 2, 1
+	case "component":
+		child, ok := s.Components[in.Component]
+		if !ok {
+			return nil, fmt.Errorf("undefined component: %s", in.Component)
+		}
+		def = child
+		inputWires, outputWires = child.InputWires(), child.OutputWires()
+	default:
+		return nil, fmt.Errorf("unknown instance kind: %q", in.Kind)
+	}
+	if len(inputs) != inputWires {
+		return nil, fmt.Errorf("%s expects %d input wires, got %d", def.Name(), inputWires, len(inputs))
+	} else if len(outputs) != outputWires {
+		return nil, fmt.Errorf("%s expects %d output wires, got %d", def.Name(), outputWires, len(outputs))
+	}
+	return &logic.Instance{
+		Definition: def,
+		Inputs:     inputs,
+		Outputs:    outputs,
+	}, nil
+}
+
+func unmarshalWires(c *logic.Component, in []*wire) ([]logic.BusWire, error) {
+	var wires []logic.BusWire
+	for _, w := range in {
+		b, ok := c.Buses[w.Bus]
+		if !ok {
+			return nil, fmt.Errorf("undefined bus: %s", w.Bus)
+		} else if w.Wire < 0 || w.Wire >= b.Wires() {
+			return nil, fmt.Errorf("wire index %d out of range for bus %s with %d wires", w.Wire, w.Bus, b.Wires())
+		}
+		wires = append(wires, logic.BusWire{
+			Bus:       b,
+			WireIndex: logic.WireIndex(w.Wire),
+		})
+	}
+	return wires, nil
+}
+
+func unmarshalValues(in []string) (*logic.Constants, error) {
+	var values []logic.Value
+	for _, s := range in {
+		if s == "" {
+			return nil, fmt.Errorf("empty value")
+		}
+		value := make(logic.Value, len(s))
+		for i := range s {
+			switch s[len(s)-1-i] {
+			case '0':
+			case '1':
+				value[i] = true
+			default:
+				return nil, fmt.Errorf("invalid binary value: %q", s)
+			}
+		}
+		values = append(values, value)
+	}
+	return logic.NewConstants(values), nil
+}
+
+func unmarshalTest(s *logic.System, in *test) (*logic.Test, error) {
+	var c *logic.Component
+	if in.Component != "" {
+		var ok bool
+		c, ok = s.Components[in.Component]
+		if !ok {
+			return nil, fmt.Errorf("undefined component: %s", in.Component)
+		}
+	}
+	t := logic.NewTest(in.Name, positions.NoPos, c)
+	for i, st := range in.Steps {
+		var step logic.TestStep
+		switch st.Kind {
+		case "set", "assert", "expect":
+			if c == nil {
+				return nil, fmt.Errorf("step %d: %s without component", i, st.Kind)
+			}
+			values, err := unmarshalValues(st.Values)
+			if err != nil {
+				return nil, fmt.Errorf("step %d: %v", i, err)
+			}
+			names := c.InputBusNames
+			switch st.Kind {
+			case "set":
+				step = logic.NewSetInputsStep(positions.NoPos, values)
+			case "assert":
+				names = c.OutputBusNames
+				step = logic.NewCheckOutputsStep(positions.NoPos, logic.ASSERT, values)
+			case "expect":
+				names = c.OutputBusNames
+				step = logic.NewCheckOutputsStep(positions.NoPos, logic.EXPECT, values)
+			}
+			if len(values.Values()) != len(names) {
+				return nil, fmt.Errorf("step %d: expected %d values, got %d", i, len(names), len(values.Values()))
+			}
+			for j, value := range values.Values() {
+				if wires := c.Buses[names[j]].Wires(); len(value) != wires {
+					return nil, fmt.Errorf("step %d: expected %d bits for %s, got %d", i, wires, names[j], len(value))
+				}
+			}
+		case "vectors":
+			if c == nil {
+				return nil, fmt.Errorf("step %d: vectors without component", i)
+			}
+			step = logic.NewApplyVectorsStep(positions.NoPos, st.Path)
+		case "equiv":
+			a, ok := s.Components[st.A]
+			if !ok {
+				return nil, fmt.Errorf("step %d: undefined component: %s", i, st.A)
+			}
+			b, ok := s.Components[st.B]
+			if !ok {
+				return nil, fmt.Errorf("step %d: undefined component: %s", i, st.B)
+			}
+			step = logic.NewCheckEquivalenceStep(positions.NoPos, a, b)
+		default:
+			return nil, fmt.Errorf("step %d: unknown kind: %q", i, st.Kind)
+		}
+		t.Steps = append(t.Steps, step)
+	}
+	return t, nil
+}
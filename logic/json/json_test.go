@@ -0,0 +1,273 @@
+package json
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/equiv"
+	"github.com/arneph/mercury/logic/internal/logictest"
+	"github.com/arneph/mercury/logic/simulation"
+)
+
+const src = logictest.Not + logictest.And + logictest.Xor + logictest.Add4 + `
+component Xor2(a, b)(r) {
+    'a: Not(a)
+    'b: Not(b)
+    i1: nand(a, 'b)
+    i2: nand('a, b)
+    r: nand(i1, i2)
+}
+
+component Add1(a, b, ci)(r, co) {
+    i1: Xor(a, b)
+    r: Xor(i1, ci)
+    i2: And(a, b)
+    i3: And(i1, ci)
+    co: nand(i2', i3')
+    i2': Not(i2)
+    i3': Not(i3)
+}
+
+component Flags(a[2])(z, k[3]) {
+    i: nand(a[0], a[1])
+    z: Not(i)
+    k: 5
+}
+
+test Add4 {
+    component: Add4
+
+    set a, b, ci: 5, 9, 1
+    assert r, co is 15, 0
+
+    set a, b, ci: 15, 1, 0
+    expect r, co is 0, 1
+}
+
+test Xor {
+    equiv Xor, Xor2
+}
+`
+
+func TestMarshal(t *testing.T) {
+	system := logictest.Build(t, src)
+	data, err := Marshal(system)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	out := string(data)
+	for _, expected := range []string{
+		`"version": 1,`,
+		`{
+      "name": "Not",
+      "inputs": [
+        {
+          "name": "a",
+          "width": 1
+        }
+      ],
+      "outputs": [
+        {
+          "name": "r",
+          "width": 1
+        }
+      ],
+      "buses": [],
+      "instances": [
+        {
+          "kind": "nand",
+          "inputs": [
+            {
+              "bus": "a",
+              "wire": 0
+            },
+            {
+              "bus": "a",
+              "wire": 0
+            }
+          ],
+          "outputs": [
+            {
+              "bus": "r",
+              "wire": 0
+            }
+          ]
+        }
+      ]
+    }`,
+		`"kind": "component",
+          "component": "Add1",`,
+		`"kind": "constants",
+          "values": [
+            "101"
+          ],`,
+		`{
+          "kind": "equiv",
+          "a": "Xor",
+          "b": "Xor2"
+        }`,
+		`{
+          "kind": "set",
+          "values": [
+            "0101",
+            "1001",
+            "1"
+          ]
+        }`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Marshal() output does not contain:\n%s\ngot:\n%s", expected, out)
+		}
+	}
+	if strings.Index(out, `"name": "Add1"`) > strings.Index(out, `"name": "Add4"`) {
+		t.Errorf("Marshal() did not sort components by name")
+	}
+}
+
+func TestMarshalIsDeterministic(t *testing.T) {
+	expected, err := Marshal(logictest.Build(t, src))
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		actual, err := Marshal(logictest.Build(t, src))
+		if err != nil {
+			t.Fatalf("Marshal() failed: %v", err)
+		} else if string(actual) != string(expected) {
+			t.Fatalf("Marshal() output differs between runs:\n%s\n%s", expected, actual)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	system := logictest.Build(t, src)
+	data, err := Marshal(system)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	result, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if len(result.Components) != len(system.Components) || len(result.Tests) != len(system.Tests) {
+		t.Fatalf("Unmarshal() returned %d components and %d tests; want %d and %d",
+			len(result.Components), len(result.Tests), len(system.Components), len(system.Tests))
+	}
+	for name, c := range system.Components {
+		r := result.Components[name]
+		if len(r.Buses) != len(c.Buses) || len(r.Instances) != len(c.Instances) {
+			t.Errorf("%s has %d buses and %d instances; want %d and %d",
+				name, len(r.Buses), len(r.Instances), len(c.Buses), len(c.Instances))
+		}
+		if err := logic.ComparePorts(c, r); err != nil {
+			t.Errorf("ComparePorts() for %s failed: %v", name, err)
+			continue
+		}
+		counterexample, err := equiv.Check(c, r)
+		if err != nil {
+			t.Errorf("equiv.Check() for %s failed: %v", name, err)
+		} else if counterexample != nil {
+			t.Errorf("%s is not equivalent after round trip: %v", name, counterexample)
+		}
+	}
+	for name, test := range result.Tests {
+		if outcome, errs := simulation.RunTest(test, nil, simulation.RunOptions{}); outcome != simulation.PASS {
+			t.Errorf("test %s failed after round trip: %v: %v", name, outcome, errs)
+		}
+	}
+	again, err := Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	} else if string(again) != string(data) {
+		t.Errorf("Marshal() after round trip differs:\n%s\n%s", data, again)
+	}
+}
+
+func TestRoundTripTri(t *testing.T) {
+	sel := logic.NewBus("sel", 1)
+	a := logic.NewBus("a", 1)
+	b := logic.NewBus("b", 1)
+	r := logic.NewBus("r", 1)
+	c := logic.NewComponent("Mux", []*logic.Bus{sel, a, b}, []*logic.Bus{r})
+	c.Buses["'sel"] = logic.NewBus("'sel", 1)
+	c.Instances = []*logic.Instance{
+		{Definition: logic.Nand, Inputs: []logic.BusWire{{Bus: sel}, {Bus: sel}}, Outputs: []logic.BusWire{{Bus: c.Buses["'sel"]}}},
+		{Definition: logic.Tri, Inputs: []logic.BusWire{{Bus: c.Buses["'sel"]}, {Bus: a}}, Outputs: []logic.BusWire{{Bus: r}}},
+		{Definition: logic.Tri, Inputs: []logic.BusWire{{Bus: sel}, {Bus: b}}, Outputs: []logic.BusWire{{Bus: r}}},
+	}
+	system := logic.NewSystem()
+	system.Components["Mux"] = c
+	data, err := Marshal(system)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	} else if !strings.Contains(string(data), `"kind": "tri"`) {
+		t.Errorf("Marshal() did not emit tri instances:\n%s", data)
+	}
+	result, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	state, err := simulation.NewComponentState(result.Components["Mux"].Collapse("Mux"))
+	if err != nil {
+		t.Fatalf("NewComponentState() failed: %v", err)
+	}
+	for m := 0; m < 8; m++ {
+		s, x, y := m&1 == 1, m&2 == 2, m&4 == 4
+		if err := state.SetInputs(logic.NewConstants([]logic.Value{{s}, {x}, {y}})); err != nil {
+			t.Fatalf("SetInputs() failed: %v", err)
+		}
+		expected := x
+		if s {
+			expected = y
+		}
+		if actual := state.Outputs().Values()[0][0]; actual != expected {
+			t.Errorf("Mux(%v, %v, %v) = %v; want %v", s, x, y, actual, expected)
+		}
+	}
+}
+
+func TestMarshalIncludesReferencedComponents(t *testing.T) {
+	system := logictest.Build(t, src)
+	subset := logic.NewSystem()
+	subset.AddComponent(system.Components["Add4"])
+	data, err := Marshal(subset)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	result, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	for _, name := range []string{"Add4", "Add1", "Xor", "And", "Not"} {
+		if _, ok := result.Components[name]; !ok {
+			t.Errorf("Unmarshal() result does not contain %s", name)
+		}
+	}
+	if _, ok := result.Components["Xor2"]; ok {
+		t.Errorf("Unmarshal() result contains unreferenced Xor2")
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	for _, src := range []string{
+		`{`,
+		`{"version": 2}`,
+		`{"version": 1, "components": [{"name": "A", "inputs": [{"name": "a", "width": 0}]}]}`,
+		`{"version": 1, "components": [{"name": "A", "inputs": [{"name": "a", "width": 1}], "outputs": [{"name": "a", "width": 1}]}]}`,
+		`{"version": 1, "components": [{"name": "A"}, {"name": "A"}]}`,
+		`{"version": 1, "components": [{"name": "A", "inputs": [{"name": "a", "width": 1}], "instances": [{"kind": "nand", "inputs": [{"bus": "a", "wire": 0}], "outputs": [{"bus": "a", "wire": 0}]}]}]}`,
+		`{"version": 1, "components": [{"name": "A", "inputs": [{"name": "a", "width": 1}], "instances": [{"kind": "nand", "inputs": [{"bus": "a", "wire": 0}, {"bus": "a", "wire": 1}], "outputs": [{"bus": "a", "wire": 0}]}]}]}`,
+		`{"version": 1, "components": [{"name": "A", "instances": [{"kind": "component", "component": "B"}]}]}`,
+		`{"version": 1, "components": [{"name": "A", "outputs": [{"name": "r", "width": 1}], "instances": [{"kind": "constants", "values": ["2"], "outputs": [{"bus": "r", "wire": 0}]}]}]}`,
+		`{"version": 1, "components": [{"name": "A", "instances": [{"kind": "latch"}]}]}`,
+		`{"version": 1, "tests": [{"name": "T", "component": "A"}]}`,
+		`{"version": 1, "components": [{"name": "A", "inputs": [{"name": "a", "width": 2}]}], "tests": [{"name": "T", "component": "A", "steps": [{"kind": "set", "values": ["1"]}]}]}`,
+		`{"version": 1, "tests": [{"name": "T", "steps": [{"kind": "equiv", "a": "A", "b": "B"}]}]}`,
+		`{"version": 1, "tests": [{"name": "T", "steps": [{"kind": "set"}]}]}`,
+	} {
+		if _, err := Unmarshal([]byte(src)); err == nil {
+			t.Errorf("Unmarshal() for %s succeeded; want error", src)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package logic
+
+type Latch struct {
+	Q    *Instance
+	QBar *Instance
+}
+
+func (l *Latch) SetBar() BusWire {
+	return otherInput(l.Q, l.QBar.Outputs[0])
+}
+
+func (l *Latch) ResetBar() BusWire {
+	return otherInput(l.QBar, l.Q.Outputs[0])
+}
+
+func otherInput(gate *Instance, feedback BusWire) BusWire {
+	if gate.Inputs[0] == feedback {
+		return gate.Inputs[1]
+	}
+	return gate.Inputs[0]
+}
+
+func FindLatches(c *Component) []*Latch {
+	drivers := make(map[BusWire]*Instance)
+	for _, instance := range c.Instances {
+		if _, ok := instance.Definition.(NandGate); ok {
+			drivers[instance.Outputs[0]] = instance
+		}
+	}
+	used := make(map[*Instance]bool)
+	var latches []*Latch
+	for _, instance := range c.Instances {
+		if _, ok := instance.Definition.(NandGate); !ok || used[instance] {
+			continue
+		}
+		for _, input := range instance.Inputs {
+			other, ok := drivers[input]
+			if !ok || other == instance || used[other] {
+				continue
+			} else if !feedsOnce(instance, other) || !feedsOnce(other, instance) {
+				continue
+			}
+			latch := &Latch{Q: instance, QBar: other}
+			if latch.SetBar() == latch.ResetBar() {
+				continue
+			}
+			used[instance] = true
+			used[other] = true
+			latches = append(latches, latch)
+			break
+		}
+	}
+	return latches
+}
+
+func feedsOnce(from, to *Instance) bool {
+	count := 0
+	for _, input := range to.Inputs {
+		if input == from.Outputs[0] {
+			count++
+		}
+	}
+	return count == 1
+}
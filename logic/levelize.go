@@ -0,0 +1,58 @@
+package logic
+
+import "fmt"
+
+func (c *Component) Levelize() ([][]*Instance, error) {
+	drivers := make(map[BusWire]int)
+	for i, instance := range c.Instances {
+		for _, output := range instance.Outputs {
+			drivers[output] = i
+		}
+	}
+	levelOf := make([]int, len(c.Instances))
+	pending := make([]int, len(c.Instances))
+	dependents := make([][]int, len(c.Instances))
+	for i, instance := range c.Instances {
+		for _, input := range instance.Inputs {
+			driver, ok := drivers[input]
+			if !ok {
+				continue
+			}
+			pending[i]++
+			dependents[driver] = append(dependents[driver], i)
+		}
+	}
+	var queue []int
+	for i := range c.Instances {
+		if pending[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+	var levels [][]*Instance
+	visited := 0
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		visited++
+		for len(levels) <= levelOf[i] {
+			levels = append(levels, nil)
+		}
+		levels[levelOf[i]] = append(levels[levelOf[i]], c.Instances[i])
+		for _, j := range dependents[i] {
+			levelOf[j] = max(levelOf[j], levelOf[i]+1)
+			pending[j]--
+			if pending[j] == 0 {
+				queue = append(queue, j)
+			}
+		}
+	}
+	if visited < len(c.Instances) {
+		for i, instance := range c.Instances {
+			if pending[i] > 0 && len(instance.Outputs) > 0 {
+				return nil, fmt.Errorf("component %s contains a feedback loop through bus %s", c.Name(), instance.Outputs[0].Bus.Name)
+			}
+		}
+		return nil, fmt.Errorf("component %s contains a feedback loop", c.Name())
+	}
+	return levels, nil
+}
@@ -0,0 +1,457 @@
+package logisim
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+func Write(w io.Writer, system *logic.System, main string) error {
+	if c, ok := system.Components[main]; !ok {
+		return fmt.Errorf("undefined component: %s", main)
+	} else if c.HasTriGates() {
+		return fmt.Errorf("%s: tri-state buffers are not supported", c.Name())
+	}
+	var names []string
+	for name := range system.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"no\"?>\n")
+	sb.WriteString("<project source=\"3.8.0\" version=\"1.0\">\n")
+	sb.WriteString("  This file is intended to be loaded by Logisim-evolution (https://github.com/logisim-evolution/).\n\n")
+	sb.WriteString("  <lib desc=\"#Wiring\" name=\"0\"/>\n")
+	sb.WriteString("  <lib desc=\"#Gates\" name=\"1\"/>\n")
+	fmt.Fprintf(&sb, "  <main name=%s/>\n", quote(main))
+	appearances := make(map[*logic.Component]*appearance)
+	for _, name := range names {
+		c := system.Components[name]
+		appearances[c] = newAppearance(c)
+	}
+	for _, name := range names {
+		cw := &circuitWriter{
+			sb:          &sb,
+			c:           system.Components[name],
+			appearances: appearances,
+		}
+		if err := cw.writeCircuit(); err != nil {
+			return err
+		}
+	}
+	sb.WriteString("</project>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteString(`"`)
+	xml.EscapeText(&sb, []byte(s))
+	sb.WriteString(`"`)
+	return sb.String()
+}
+
+type point struct {
+	x, y int
+}
+
+func (p point) add(q point) point {
+	return point{p.x + q.x, p.y + q.y}
+}
+
+func (p point) String() string {
+	return "(" + strconv.Itoa(p.x) + "," + strconv.Itoa(p.y) + ")"
+}
+
+type appearance struct {
+	width   int
+	height  int
+	inputs  []point
+	outputs []point
+}
+
+func newAppearance(c *logic.Component) *appearance {
+	a := &appearance{width: 60}
+	if width := (len(c.Name())*8 + 29) / 10 * 10; width > a.width {
+		a.width = width
+	}
+	offset := 0
+	for _, name := range c.InputBusNames {
+		offset += 10*c.Buses[name].Wires() + 10
+		a.inputs = append(a.inputs, point{0, offset})
+	}
+	for _, name := range c.OutputBusNames {
+		offset += 10*c.Buses[name].Wires() + 10
+		a.outputs = append(a.outputs, point{a.width, offset})
+	}
+	a.height = offset + 20
+	return a
+}
+
+type attr struct {
+	name, val string
+}
+
+type comp struct {
+	lib   string
+	name  string
+	loc   point
+	attrs []attr
+}
+
+type terminal struct {
+	wire logic.BusWire
+	at   point
+}
+
+type item struct {
+	level   int
+	min     point
+	max     point
+	anchor  point
+	comps   []comp
+	wires   [][2]point
+	sources []terminal
+	sinks   []terminal
+}
+
+func newItem(min, max point) *item {
+	return &item{min: min, max: max}
+}
+
+func (it *item) include(p point) {
+	it.min = point{min(it.min.x, p.x-10), min(it.min.y, p.y-10)}
+	it.max = point{max(it.max.x, p.x+10), max(it.max.y, p.y+10)}
+}
+
+func (it *item) port(p point, wires []logic.BusWire, source bool) {
+	if len(wires) == 1 {
+		it.add(terminal{wires[0], p}, source)
+		return
+	}
+	combined := p
+	if !source {
+		combined = p.add(point{-40, 0})
+		it.wires = append(it.wires, [2]point{combined, p})
+	}
+	n := len(wires)
+	it.comps = append(it.comps, comp{
+		lib:  "0",
+		name: "Splitter",
+		loc:  combined,
+		attrs: []attr{
+			{"fanout", strconv.Itoa(n)},
+			{"incoming", strconv.Itoa(n)},
+		},
+	})
+	it.include(combined)
+	for i, wire := range wires {
+		it.add(terminal{wire, combined.add(point{20, 10 * (i - n)})}, source)
+	}
+}
+
+func (it *item) add(t terminal, source bool) {
+	if source {
+		it.sources = append(it.sources, t)
+	} else {
+		it.sinks = append(it.sinks, t)
+	}
+	it.include(t.at)
+}
+
+func busWires(bus *logic.Bus) []logic.BusWire {
+	wires := make([]logic.BusWire, bus.Wires())
+	for i := range wires {
+		wires[i] = logic.BusWire{Bus: bus, WireIndex: logic.WireIndex(i)}
+	}
+	return wires
+}
+
+func pinAttrs(bus *logic.Bus) []attr {
+	attrs := []attr{{"label", bus.Name}}
+	if bus.Wires() > 1 {
+		attrs = append(attrs, attr{"width", strconv.Itoa(bus.Wires())})
+	}
+	return attrs
+}
+
+type circuitWriter struct {
+	sb          *strings.Builder
+	c           *logic.Component
+	appearances map[*logic.Component]*appearance
+	items       []*item
+}
+
+func (cw *circuitWriter) writeCircuit() error {
+	var inputs, instances, outputs []*item
+	for _, name := range cw.c.InputBusNames {
+		bus := cw.c.Buses[name]
+		it := newItem(point{-30, -10}, point{0, 10})
+		it.comps = append(it.comps, comp{lib: "0", name: "Pin", attrs: pinAttrs(bus)})
+		it.port(point{}, busWires(bus), true)
+		inputs = append(inputs, it)
+	}
+	for _, instance := range cw.c.Instances {
+		var it *item
+		switch def := instance.Definition.(type) {
+		case *logic.Constants:
+			it = newItem(point{-20, -10}, point{0, 10})
+			var bits []bool
+			for _, value := range def.Values() {
+				bits = append(bits, value...)
+			}
+			for i, output := range instance.Outputs {
+				value := "0x0"
+				if bits[i] {
+					value = "0x1"
+				}
+				loc := point{0, 20 * i}
+				it.comps = append(it.comps, comp{lib: "0", name: "Constant", loc: loc, attrs: []attr{{"value", value}}})
+				it.add(terminal{output, loc}, true)
+			}
+		case logic.NandGate:
+			it = newItem(point{-60, -30}, point{0, 30})
+			it.comps = append(it.comps, comp{lib: "1", name: "NAND Gate", attrs: []attr{{"size", "50"}, {"inputs", "2"}}})
+			it.add(terminal{instance.Inputs[0], point{-60, -20}}, false)
+			it.add(terminal{instance.Inputs[1], point{-60, 20}}, false)
+			it.add(terminal{instance.Outputs[0], point{}}, true)
+		case *logic.Component:
+			a, ok := cw.appearances[def]
+			if !ok {
+				return fmt.Errorf("component %s instantiates %s, which is not part of the system", cw.c.Name(), def.Name())
+			}
+			it = newItem(point{}, point{a.width, a.height})
+			it.comps = append(it.comps, comp{name: def.Name()})
+			for _, kind := range []struct {
+				names  []string
+				points []point
+				wires  []logic.BusWire
+				source bool
+			}{
+				{def.InputBusNames, a.inputs, instance.Inputs, false},
+				{def.OutputBusNames, a.outputs, instance.Outputs, true},
+			} {
+				offset := 0
+				for i, name := range kind.names {
+					width := def.Buses[name].Wires()
+					it.port(kind.points[i], kind.wires[offset:offset+width], kind.source)
+					offset += width
+				}
+			}
+		default:
+			panic(fmt.Errorf("unexpected logic.Definition: %t", def))
+		}
+		instances = append(instances, it)
+	}
+	for _, name := range cw.c.OutputBusNames {
+		bus := cw.c.Buses[name]
+		attrs := append([]attr{{"facing", "west"}, {"output", "true"}}, pinAttrs(bus)...)
+		it := newItem(point{0, -10}, point{30, 10})
+		it.comps = append(it.comps, comp{lib: "0", name: "Pin", attrs: attrs})
+		it.port(point{}, busWires(bus), false)
+		outputs = append(outputs, it)
+	}
+	cw.levelize(instances)
+	last := 0
+	for _, it := range instances {
+		last = max(last, it.level)
+	}
+	for _, it := range outputs {
+		it.level = last + 1
+	}
+	cw.items = append(append(inputs, instances...), outputs...)
+	sort.SliceStable(cw.items, func(i, j int) bool {
+		return cw.items[i].level < cw.items[j].level
+	})
+	tracks := cw.place()
+
+	fmt.Fprintf(cw.sb, "  <circuit name=%s>\n", quote(cw.c.Name()))
+	fmt.Fprintf(cw.sb, "    <a name=\"circuit\" val=%s/>\n", quote(cw.c.Name()))
+	cw.writeAppearance()
+	for _, it := range cw.items {
+		for _, c := range it.comps {
+			cw.writeComp(c, it.anchor)
+		}
+	}
+	for _, it := range cw.items {
+		for _, wire := range it.wires {
+			cw.writeWire(wire[0].add(it.anchor), wire[1].add(it.anchor))
+		}
+	}
+	cw.writeNets(tracks)
+	cw.sb.WriteString("  </circuit>\n")
+	return nil
+}
+
+func (cw *circuitWriter) levelize(instances []*item) {
+	drivers := make(map[logic.BusWire]*item)
+	for _, it := range instances {
+		for _, t := range it.sources {
+			drivers[t.wire] = it
+		}
+	}
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make(map[*item]int)
+	var visit func(it *item)
+	visit = func(it *item) {
+		state[it] = active
+		level := 1
+		for _, t := range it.sinks {
+			driver, ok := drivers[t.wire]
+			if !ok {
+				continue
+			}
+			switch state[driver] {
+			case unvisited:
+				visit(driver)
+			case active:
+				continue
+			}
+			level = max(level, driver.level+1)
+		}
+		it.level = level
+		state[it] = done
+	}
+	for _, it := range instances {
+		if state[it] == unvisited {
+			visit(it)
+		}
+	}
+}
+
+func (cw *circuitWriter) place() map[logic.BusWire]int {
+	tracks := make(map[logic.BusWire]int)
+	x, y := 40, 40
+	for i := 0; i < len(cw.items); {
+		level := cw.items[i].level
+		j := i
+		width := 0
+		for j < len(cw.items) && cw.items[j].level == level {
+			width = max(width, cw.items[j].max.x-cw.items[j].min.x)
+			j++
+		}
+		track := x + width + 20
+		for _, it := range cw.items[i:j] {
+			it.anchor = point{x - it.min.x, y - it.min.y}
+			y += it.max.y - it.min.y + 10
+			for _, t := range it.sources {
+				tracks[t.wire] = track
+				track += 10
+			}
+		}
+		x = track + 20
+		i = j
+	}
+	return tracks
+}
+
+func (cw *circuitWriter) writeAppearance() {
+	a := cw.appearances[cw.c]
+	origin := point{50, 50}
+	cw.sb.WriteString("    <appear>\n")
+	fmt.Fprintf(cw.sb, "      <rect fill=\"none\" height=\"%d\" stroke=\"#000000\" stroke-width=\"2\" width=\"%d\" x=\"%d\" y=\"%d\"/>\n",
+		a.height, a.width, origin.x, origin.y)
+	fmt.Fprintf(cw.sb, "      <text font-family=\"SansSerif\" font-size=\"12\" text-anchor=\"middle\" x=\"%d\" y=\"%d\">",
+		origin.x+a.width/2, origin.y+15)
+	xml.EscapeText(cw.sb, []byte(cw.c.Name()))
+	cw.sb.WriteString("</text>\n")
+	pins := cw.pinLocations()
+	for i, p := range a.inputs {
+		p = p.add(origin)
+		fmt.Fprintf(cw.sb, "      <circ-port height=\"8\" pin=\"%d,%d\" width=\"8\" x=\"%d\" y=\"%d\"/>\n",
+			pins[i].x, pins[i].y, p.x-4, p.y-4)
+	}
+	for i, p := range a.outputs {
+		p = p.add(origin)
+		pin := pins[len(a.inputs)+i]
+		fmt.Fprintf(cw.sb, "      <circ-port height=\"10\" pin=\"%d,%d\" width=\"10\" x=\"%d\" y=\"%d\"/>\n",
+			pin.x, pin.y, p.x-5, p.y-5)
+	}
+	fmt.Fprintf(cw.sb, "      <circ-anchor facing=\"east\" height=\"6\" width=\"6\" x=\"%d\" y=\"%d\"/>\n", origin.x-3, origin.y-3)
+	cw.sb.WriteString("    </appear>\n")
+}
+
+func (cw *circuitWriter) pinLocations() []point {
+	var pins []point
+	for _, it := range cw.items {
+		for _, c := range it.comps {
+			if c.name == "Pin" {
+				pins = append(pins, c.loc.add(it.anchor))
+			}
+		}
+	}
+	return pins
+}
+
+func (cw *circuitWriter) writeComp(c comp, anchor point) {
+	cw.sb.WriteString("    <comp")
+	if c.lib != "" {
+		fmt.Fprintf(cw.sb, " lib=%s", quote(c.lib))
+	}
+	fmt.Fprintf(cw.sb, " loc=\"%s\" name=%s", c.loc.add(anchor), quote(c.name))
+	if len(c.attrs) == 0 {
+		cw.sb.WriteString("/>\n")
+		return
+	}
+	cw.sb.WriteString(">\n")
+	for _, a := range c.attrs {
+		fmt.Fprintf(cw.sb, "      <a name=%s val=%s/>\n", quote(a.name), quote(a.val))
+	}
+	cw.sb.WriteString("    </comp>\n")
+}
+
+func (cw *circuitWriter) writeWire(from, to point) {
+	if from == to {
+		return
+	}
+	fmt.Fprintf(cw.sb, "    <wire from=\"%s\" to=\"%s\"/>\n", from, to)
+}
+
+func (cw *circuitWriter) writeNets(tracks map[logic.BusWire]int) {
+	type net struct {
+		source point
+		sinks  []point
+	}
+	nets := make(map[logic.BusWire]*net)
+	var order []logic.BusWire
+	for _, it := range cw.items {
+		for _, t := range it.sources {
+			nets[t.wire] = &net{source: t.at.add(it.anchor)}
+			order = append(order, t.wire)
+		}
+	}
+	for _, it := range cw.items {
+		for _, t := range it.sinks {
+			if n, ok := nets[t.wire]; ok {
+				n.sinks = append(n.sinks, t.at.add(it.anchor))
+			}
+		}
+	}
+	for _, wire := range order {
+		n := nets[wire]
+		if len(n.sinks) == 0 {
+			continue
+		}
+		x := tracks[wire]
+		ys := []int{n.source.y}
+		cw.writeWire(n.source, point{x, n.source.y})
+		for _, sink := range n.sinks {
+			cw.writeWire(point{x, sink.y}, sink)
+			ys = append(ys, sink.y)
+		}
+		sort.Ints(ys)
+		for i := 1; i < len(ys); i++ {
+			cw.writeWire(point{x, ys[i-1]}, point{x, ys[i]})
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package logisim
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/equiv"
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+const src = logictest.Gates + logictest.Add1 + logictest.Add4 + logictest.Memory1 + `
+component Swap(a[2], b)(r[2], k[3]) {
+    r[0], r[1]: Add1(a[1], b, a[0])
+    k: 5
+}
+`
+
+type xmlProject struct {
+	Libs []struct {
+		Desc string `xml:"desc,attr"`
+		Name string `xml:"name,attr"`
+	} `xml:"lib"`
+	Main struct {
+		Name string `xml:"name,attr"`
+	} `xml:"main"`
+	Circuits []*xmlCircuit `xml:"circuit"`
+}
+
+type xmlCircuit struct {
+	Name   string `xml:"name,attr"`
+	Appear struct {
+		Ports []struct {
+			Pin   string `xml:"pin,attr"`
+			X     int    `xml:"x,attr"`
+			Y     int    `xml:"y,attr"`
+			Width int    `xml:"width,attr"`
+		} `xml:"circ-port"`
+		Anchor struct {
+			X int `xml:"x,attr"`
+			Y int `xml:"y,attr"`
+		} `xml:"circ-anchor"`
+	} `xml:"appear"`
+	Comps []*xmlComp `xml:"comp"`
+	Wires []struct {
+		From string `xml:"from,attr"`
+		To   string `xml:"to,attr"`
+	} `xml:"wire"`
+}
+
+type xmlComp struct {
+	Lib   string `xml:"lib,attr"`
+	Loc   string `xml:"loc,attr"`
+	Name  string `xml:"name,attr"`
+	Attrs []struct {
+		Name string `xml:"name,attr"`
+		Val  string `xml:"val,attr"`
+	} `xml:"a"`
+}
+
+func (c *xmlComp) attr(name, def string) string {
+	for _, a := range c.Attrs {
+		if a.Name == name {
+			return a.Val
+		}
+	}
+	return def
+}
+
+func parsePoint(t *testing.T, s string) point {
+	t.Helper()
+	var p point
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "("), ")")
+	if _, err := fmt.Sscanf(s, "%d,%d", &p.x, &p.y); err != nil {
+		t.Fatalf("invalid point %q: %v", s, err)
+	}
+	return p
+}
+
+type bit struct {
+	group int
+	index int
+}
+
+type extractor struct {
+	t          *testing.T
+	circuits   map[string]*xmlCircuit
+	components map[string]*logic.Component
+}
+
+func (e *extractor) ports(c *xmlCircuit) (inputs, outputs []point) {
+	anchor := point{c.Appear.Anchor.X + 3, c.Appear.Anchor.Y + 3}
+	for _, port := range c.Appear.Ports {
+		center := point{port.X + port.Width/2, port.Y + port.Width/2}
+		offset := point{center.x - anchor.x, center.y - anchor.y}
+		if port.Width == 8 {
+			inputs = append(inputs, offset)
+		} else {
+			outputs = append(outputs, offset)
+		}
+	}
+	return inputs, outputs
+}
+
+func (e *extractor) extract(name string) *logic.Component {
+	t := e.t
+	if c, ok := e.components[name]; ok {
+		return c
+	}
+	circuit, ok := e.circuits[name]
+	if !ok {
+		t.Fatalf("missing circuit %s", name)
+	}
+	parent := make(map[point]point)
+	var find func(p point) point
+	find = func(p point) point {
+		if q, ok := parent[p]; ok && q != p {
+			root := find(q)
+			parent[p] = root
+			return root
+		}
+		parent[p] = p
+		return p
+	}
+	type segment struct{ from, to point }
+	var segments []segment
+	for _, w := range circuit.Wires {
+		from, to := parsePoint(t, w.From), parsePoint(t, w.To)
+		if from.x != to.x && from.y != to.y {
+			t.Errorf("circuit %s: diagonal wire %v-%v", name, from, to)
+		}
+		parent[find(from)] = find(to)
+		segments = append(segments, segment{from, to})
+	}
+	var connections []point
+	type port struct {
+		at    point
+		width int
+	}
+	portsOf := make(map[*xmlComp][]port)
+	for _, comp := range circuit.Comps {
+		loc := parsePoint(t, comp.Loc)
+		var ports []port
+		switch comp.Name {
+		case "Pin", "Constant":
+			width, _ := strconv.Atoi(comp.attr("width", "1"))
+			ports = []port{{loc, width}}
+		case "NAND Gate":
+			ports = []port{{loc.add(point{-60, -20}), 1}, {loc.add(point{-60, 20}), 1}, {loc, 1}}
+		case "Splitter":
+			fanout, _ := strconv.Atoi(comp.attr("fanout", "2"))
+			ports = []port{{loc, fanout}}
+			for i := 0; i < fanout; i++ {
+				ports = append(ports, port{loc.add(point{20, 10 * (i - fanout)}), 1})
+			}
+		default:
+			child := e.extract(comp.Name)
+			inputs, outputs := e.ports(e.circuits[comp.Name])
+			for i, offset := range append(inputs, outputs...) {
+				var busName string
+				if i < len(inputs) {
+					busName = child.InputBusNames[i]
+				} else {
+					busName = child.OutputBusNames[i-len(inputs)]
+				}
+				ports = append(ports, port{loc.add(offset), child.Buses[busName].Wires()})
+			}
+		}
+		for _, p := range ports {
+			find(p.at)
+			connections = append(connections, p.at)
+		}
+		portsOf[comp] = ports
+	}
+	for _, s := range segments {
+		for p := range parent {
+			inside := (p.x == s.from.x && p.x == s.to.x && min(s.from.y, s.to.y) < p.y && p.y < max(s.from.y, s.to.y)) ||
+				(p.y == s.from.y && p.y == s.to.y && min(s.from.x, s.to.x) < p.x && p.x < max(s.from.x, s.to.x))
+			if inside {
+				t.Errorf("circuit %s: point %v touches the interior of wire %v-%v", name, p, s.from, s.to)
+			}
+		}
+	}
+
+	groups := make(map[point]int)
+	group := func(p point) int {
+		root := find(p)
+		if g, ok := groups[root]; ok {
+			return g
+		}
+		groups[root] = len(groups)
+		return groups[root]
+	}
+	bitParent := make(map[bit]bit)
+	var findBit func(b bit) bit
+	findBit = func(b bit) bit {
+		if q, ok := bitParent[b]; ok && q != b {
+			root := findBit(q)
+			bitParent[b] = root
+			return root
+		}
+		bitParent[b] = b
+		return b
+	}
+	for _, comp := range circuit.Comps {
+		if comp.Name != "Splitter" {
+			continue
+		}
+		ports := portsOf[comp]
+		for i, end := range ports[1:] {
+			bitParent[findBit(bit{group(ports[0].at), i})] = findBit(bit{group(end.at), 0})
+		}
+	}
+
+	var inputs, outputs []*logic.Bus
+	wires := make(map[bit]logic.BusWire)
+	for _, comp := range circuit.Comps {
+		if comp.Name != "Pin" {
+			continue
+		}
+		ports := portsOf[comp]
+		bus := logic.NewBus(comp.attr("label", ""), ports[0].width)
+		if comp.attr("output", "false") == "true" {
+			outputs = append(outputs, bus)
+		} else {
+			inputs = append(inputs, bus)
+		}
+		for i := 0; i < bus.Wires(); i++ {
+			root := findBit(bit{group(ports[0].at), i})
+			if _, ok := wires[root]; ok {
+				t.Errorf("circuit %s: pins %s is connected to another pin", name, bus.Name)
+			}
+			wires[root] = logic.BusWire{Bus: bus, WireIndex: logic.WireIndex(i)}
+		}
+	}
+	c := logic.NewComponent(name, inputs, outputs)
+	wire := func(p point, i int) logic.BusWire {
+		root := findBit(bit{group(p), i})
+		if w, ok := wires[root]; ok {
+			return w
+		}
+		bus := logic.NewBus("w"+strconv.Itoa(len(c.Buses)), 1)
+		c.Buses[bus.Name] = bus
+		wires[root] = logic.BusWire{Bus: bus}
+		return wires[root]
+	}
+	for _, comp := range circuit.Comps {
+		ports := portsOf[comp]
+		switch comp.Name {
+		case "Pin", "Splitter":
+			continue
+		case "Constant":
+			value, _ := strconv.ParseUint(comp.attr("value", "0x1"), 0, 64)
+			c.Instances = append(c.Instances, &logic.Instance{
+				Definition: logic.NewConstants([]logic.Value{{value == 1}}),
+				Outputs:    []logic.BusWire{wire(ports[0].at, 0)},
+			})
+		case "NAND Gate":
+			c.Instances = append(c.Instances, &logic.Instance{
+				Definition: logic.Nand,
+				Inputs:     []logic.BusWire{wire(ports[0].at, 0), wire(ports[1].at, 0)},
+				Outputs:    []logic.BusWire{wire(ports[2].at, 0)},
+			})
+		default:
+			child := e.extract(comp.Name)
+			instance := &logic.Instance{Definition: child}
+			for i, p := range ports {
+				for j := 0; j < p.width; j++ {
+					if i < len(child.InputBusNames) {
+						instance.Inputs = append(instance.Inputs, wire(p.at, j))
+					} else {
+						instance.Outputs = append(instance.Outputs, wire(p.at, j))
+					}
+				}
+			}
+			c.Instances = append(c.Instances, instance)
+		}
+	}
+	e.components[name] = c
+	return c
+}
+
+func writeAndExtract(t *testing.T, system *logic.System, main string) *extractor {
+	t.Helper()
+	var sb strings.Builder
+	if err := Write(&sb, system, main); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	var project xmlProject
+	if err := xml.Unmarshal([]byte(sb.String()), &project); err != nil {
+		t.Fatalf("Write() produced invalid XML: %v", err)
+	}
+	if project.Main.Name != main {
+		t.Errorf("main circuit is %q; want %q", project.Main.Name, main)
+	}
+	if len(project.Libs) != 2 || project.Libs[0].Desc != "#Wiring" || project.Libs[1].Desc != "#Gates" {
+		t.Errorf("unexpected libraries: %v", project.Libs)
+	}
+	e := &extractor{
+		t:          t,
+		circuits:   make(map[string]*xmlCircuit),
+		components: make(map[string]*logic.Component),
+	}
+	for _, circuit := range project.Circuits {
+		e.circuits[circuit.Name] = circuit
+	}
+	if len(e.circuits) != len(system.Components) {
+		t.Errorf("Write() produced %d circuits; want %d", len(e.circuits), len(system.Components))
+	}
+	return e
+}
+
+func TestWriteStructure(t *testing.T) {
+	system := logictest.Build(t, src)
+	e := writeAndExtract(t, system, "Add4")
+	counts := make(map[string]int)
+	for _, comp := range e.circuits["Add4"].Comps {
+		counts[comp.Name]++
+	}
+	expected := map[string]int{"Pin": 5, "Splitter": 3, "Add1": 4}
+	for name, count := range expected {
+		if counts[name] != count {
+			t.Errorf("Add4 contains %d %s components; want %d", counts[name], name, count)
+		}
+	}
+	if len(counts) != len(expected) {
+		t.Errorf("Add4 contains unexpected components: %v", counts)
+	}
+	if ports := len(e.circuits["Add1"].Appear.Ports); ports != 5 {
+		t.Errorf("Add1 appearance has %d ports; want 5", ports)
+	}
+}
+
+func TestWriteConnectivity(t *testing.T) {
+	system := logictest.Build(t, src)
+	e := writeAndExtract(t, system, "Add4")
+	for _, name := range []string{"Xor", "Add1", "Add4", "Swap"} {
+		c := system.Components[name]
+		extracted := e.extract(name)
+		counterexample, err := equiv.Check(c, extracted)
+		if err != nil {
+			t.Errorf("equiv.Check() failed for %s: %v", name, err)
+		} else if counterexample != nil {
+			t.Errorf("extracted circuit %s differs: %v", name, counterexample)
+		}
+	}
+}
+
+func TestWriteFeedback(t *testing.T) {
+	system := logictest.Build(t, src)
+	e := writeAndExtract(t, system, "Memory1")
+	extracted := e.extract("Memory1")
+	collapsed := extracted.Collapse("Memory1")
+	if len(collapsed.Instances) != 4 {
+		t.Errorf("extracted Memory1 has %d gates; want 4", len(collapsed.Instances))
+	}
+	if len(logic.FindLatches(collapsed)) != 1 {
+		t.Errorf("extracted Memory1 does not contain a latch:\n%s", extracted)
+	}
+}
+
+func TestWriteUndefinedMain(t *testing.T) {
+	system := logictest.Build(t, src)
+	if err := Write(&strings.Builder{}, system, "Missing"); err == nil {
+		t.Errorf("Write() succeeded for undefined main circuit")
+	}
+}
@@ -0,0 +1,258 @@
+package print
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/synth"
+)
+
+const indent = "    "
+
+func Write(w io.Writer, c *logic.Component) error {
+	p := &printer{
+		componentNames: make(map[*logic.Component]string),
+		usedNames:      map[string]bool{"nand": true, "tri": true},
+	}
+	p.nameComponents(c)
+	p.writeHierarchy(c, make(map[*logic.Component]bool))
+	_, err := io.WriteString(w, p.sb.String())
+	return err
+}
+
+func String(c *logic.Component) string {
+	var sb strings.Builder
+	Write(&sb, c)
+	return sb.String()
+}
+
+type printer struct {
+	sb             strings.Builder
+	componentNames map[*logic.Component]string
+	usedNames      map[string]bool
+}
+
+func (p *printer) nameComponents(c *logic.Component) {
+	if _, ok := p.componentNames[c]; ok {
+		return
+	}
+	p.componentNames[c] = uniqueName(synth.Identifier(c.Name()), p.usedNames)
+	for _, instance := range c.Instances {
+		if child, ok := instance.Definition.(*logic.Component); ok {
+			p.nameComponents(child)
+		}
+	}
+}
+
+func uniqueName(name string, used map[string]bool) string {
+	unique := name
+	for i := 1; used[unique]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
+
+func (p *printer) writeHierarchy(c *logic.Component, written map[*logic.Component]bool) {
+	if written[c] {
+		return
+	}
+	written[c] = true
+	for _, instance := range c.Instances {
+		if child, ok := instance.Definition.(*logic.Component); ok {
+			p.writeHierarchy(child, written)
+		}
+	}
+	if p.sb.Len() > 0 {
+		p.sb.WriteString("\n")
+	}
+	p.writeComponent(c)
+}
+
+func (p *printer) writeComponent(c *logic.Component) {
+	cp := &componentPrinter{
+		printer:   p,
+		busNames:  make(map[*logic.Bus]string),
+		usedNames: make(map[string]bool),
+	}
+	for _, names := range [][]string{c.InputBusNames, c.OutputBusNames} {
+		for _, name := range names {
+			cp.nameBus(c.Buses[name])
+		}
+	}
+	ports := make(map[string]bool)
+	for _, names := range [][]string{c.InputBusNames, c.OutputBusNames} {
+		for _, name := range names {
+			ports[name] = true
+		}
+	}
+	var internal []string
+	for name := range c.Buses {
+		if !ports[name] {
+			internal = append(internal, name)
+		}
+	}
+	sort.Strings(internal)
+	for _, name := range internal {
+		cp.nameBus(c.Buses[name])
+	}
+
+	p.sb.WriteString("component ")
+	p.sb.WriteString(p.componentNames[c])
+	p.sb.WriteString("(")
+	cp.writeDeclarations(c, c.InputBusNames)
+	p.sb.WriteString(")(")
+	cp.writeDeclarations(c, c.OutputBusNames)
+	p.sb.WriteString(") {\n")
+	referenced := make(map[*logic.Bus]bool)
+	for _, instance := range c.Instances {
+		for _, wires := range [][]logic.BusWire{instance.Inputs, instance.Outputs} {
+			for _, wire := range wires {
+				referenced[wire.Bus] = true
+			}
+		}
+	}
+	for _, name := range internal {
+		bus := c.Buses[name]
+		if bus.Wires() == 1 && referenced[bus] {
+			continue
+		}
+		p.sb.WriteString(indent)
+		p.sb.WriteString("define ")
+		p.sb.WriteString(cp.declaration(bus))
+		p.sb.WriteString("\n")
+	}
+	for _, instance := range c.Instances {
+		switch def := instance.Definition.(type) {
+		case *logic.Constants:
+			cp.writeConstants(def, instance)
+		case logic.NandGate, logic.TriGate:
+			cp.writeInstance(def.Name(), instance)
+		case *logic.Component:
+			cp.writeInstance(p.componentNames[def], instance)
+		default:
+			panic(fmt.Errorf("unexpected logic.Definition: %t", def))
+		}
+	}
+	p.sb.WriteString("}\n")
+}
+
+type componentPrinter struct {
+	*printer
+	busNames  map[*logic.Bus]string
+	usedNames map[string]bool
+}
+
+func (cp *componentPrinter) nameBus(bus *logic.Bus) {
+	cp.busNames[bus] = uniqueName(synth.Identifier(bus.Name), cp.usedNames)
+}
+
+func (cp *componentPrinter) declaration(bus *logic.Bus) string {
+	if bus.Wires() == 1 {
+		return cp.busNames[bus]
+	}
+	return cp.busNames[bus] + "[" + strconv.Itoa(bus.Wires()) + "]"
+}
+
+func (cp *componentPrinter) writeDeclarations(c *logic.Component, names []string) {
+	for i, name := range names {
+		if i > 0 {
+			cp.sb.WriteString(", ")
+		}
+		cp.sb.WriteString(cp.declaration(c.Buses[name]))
+	}
+}
+
+func (cp *componentPrinter) references(wires []logic.BusWire, widths []int) []string {
+	var refs []string
+	for _, width := range widths {
+		port := wires[:width]
+		wires = wires[width:]
+		if bus := port[0].Bus; bus.Wires() == width && width > 1 {
+			full := true
+			for i, wire := range port {
+				if wire.Bus != bus || int(wire.WireIndex) != i {
+					full = false
+					break
+				}
+			}
+			if full {
+				refs = append(refs, cp.busNames[bus])
+				continue
+			}
+		}
+		for _, wire := range port {
+			refs = append(refs, cp.reference(wire))
+		}
+	}
+	return refs
+}
+
+func (cp *componentPrinter) reference(wire logic.BusWire) string {
+	if wire.Bus.Wires() == 1 {
+		return cp.busNames[wire.Bus]
+	}
+	return cp.busNames[wire.Bus] + "[" + strconv.Itoa(int(wire.WireIndex)) + "]"
+}
+
+func portWidths(def logic.Definition) ([]int, []int) {
+	switch def := def.(type) {
+	case logic.NandGate, logic.TriGate:
+		return []int{1, 1}, []int{1}
+	case *logic.Component:
+		var widths [2][]int
+		for i, names := range [][]string{def.InputBusNames, def.OutputBusNames} {
+			for _, name := range names {
+				widths[i] = append(widths[i], def.Buses[name].Wires())
+			}
+		}
+		return widths[0], widths[1]
+	default:
+		panic(fmt.Errorf("unexpected logic.Definition: %t", def))
+	}
+}
+
+func (cp *componentPrinter) writeInstance(name string, instance *logic.Instance) {
+	inputs, outputs := portWidths(instance.Definition)
+	cp.sb.WriteString(indent)
+	cp.sb.WriteString(strings.Join(cp.references(instance.Outputs, outputs), ", "))
+	cp.sb.WriteString(": ")
+	cp.sb.WriteString(name)
+	cp.sb.WriteString("(")
+	cp.sb.WriteString(strings.Join(cp.references(instance.Inputs, inputs), ", "))
+	cp.sb.WriteString(")\n")
+}
+
+func (cp *componentPrinter) writeConstants(def *logic.Constants, instance *logic.Instance) {
+	var refs, values []string
+	offset := 0
+	for _, value := range def.Values() {
+		wires := instance.Outputs[offset : offset+len(value)]
+		offset += len(value)
+		if whole := cp.references(wires, []int{len(wires)}); len(whole) == 1 && len(value) < 64 {
+			refs = append(refs, whole[0])
+			values = append(values, value.String())
+			continue
+		}
+		for i, wire := range wires {
+			refs = append(refs, cp.reference(wire))
+			if value[i] {
+				values = append(values, "1")
+			} else {
+				values = append(values, "0")
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return
+	}
+	cp.sb.WriteString(indent)
+	cp.sb.WriteString(strings.Join(refs, ", "))
+	cp.sb.WriteString(": ")
+	cp.sb.WriteString(strings.Join(values, ", "))
+	cp.sb.WriteString("\n")
+}
@@ -0,0 +1,170 @@
+package print
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+const src = logictest.Not + logictest.And + logictest.Xor + logictest.Add4 + `
+component Add1(a, b, ci)(r, co) {
+    i1: Xor(a, b)
+    r: Xor(i1, ci)
+    i2: And(a, b)
+    i3: And(i1, ci)
+    'co: nand('i2, 'i3)
+    'i2: Not(i2)
+    'i3: Not(i3)
+    co: Not('co)
+}
+
+component Mux(sel, a, b)(r) {
+    'sel: Not(sel)
+    r: tri('sel, a)
+    r: tri(sel, b)
+}
+
+component Flags(a[2])(z, k[3], m[2]) {
+    define unused, wide[5]
+    i: nand(a[0], a[1])
+    z: Not(i)
+    k, m[1], m[0]: 5, 1, 0
+}
+`
+
+func compareComponents(a, b *logic.Component) error {
+	if a.Name() != b.Name() {
+		return fmt.Errorf("names differ: %s, %s", a.Name(), b.Name())
+	} else if strings.Join(a.InputBusNames, ",") != strings.Join(b.InputBusNames, ",") {
+		return fmt.Errorf("%s: inputs differ: %v, %v", a.Name(), a.InputBusNames, b.InputBusNames)
+	} else if strings.Join(a.OutputBusNames, ",") != strings.Join(b.OutputBusNames, ",") {
+		return fmt.Errorf("%s: outputs differ: %v, %v", a.Name(), a.OutputBusNames, b.OutputBusNames)
+	} else if len(a.Buses) != len(b.Buses) {
+		return fmt.Errorf("%s: bus counts differ: %d, %d", a.Name(), len(a.Buses), len(b.Buses))
+	}
+	for name, bus := range a.Buses {
+		if other, ok := b.Buses[name]; !ok || other.Wires() != bus.Wires() {
+			return fmt.Errorf("%s: bus %s differs", a.Name(), name)
+		}
+	}
+	if len(a.Instances) != len(b.Instances) {
+		return fmt.Errorf("%s: instance counts differ: %d, %d", a.Name(), len(a.Instances), len(b.Instances))
+	}
+	for i := range a.Instances {
+		x, y := a.Instances[i], b.Instances[i]
+		if x.Definition.Name() != y.Definition.Name() {
+			return fmt.Errorf("%s: instance %d definitions differ: %s, %s", a.Name(), i, x.Definition.Name(), y.Definition.Name())
+		} else if wires(x.Inputs) != wires(y.Inputs) || wires(x.Outputs) != wires(y.Outputs) {
+			return fmt.Errorf("%s: instance %d connections differ: %s, %s", a.Name(), i, x, y)
+		}
+		switch def := x.Definition.(type) {
+		case *logic.Constants:
+			if bits(def) != bits(y.Definition.(*logic.Constants)) {
+				return fmt.Errorf("%s: instance %d values differ: %s, %s", a.Name(), i, x, y)
+			}
+		case *logic.Component:
+			if err := compareComponents(def, y.Definition.(*logic.Component)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func wires(ws []logic.BusWire) string {
+	var sb strings.Builder
+	for _, w := range ws {
+		fmt.Fprintf(&sb, "%s[%d] ", w.Bus.Name, w.WireIndex)
+	}
+	return sb.String()
+}
+
+func bits(c *logic.Constants) string {
+	var sb strings.Builder
+	for _, value := range c.Values() {
+		for _, bit := range value {
+			if bit {
+				sb.WriteByte('1')
+			} else {
+				sb.WriteByte('0')
+			}
+		}
+	}
+	return sb.String()
+}
+
+func roundTrip(t *testing.T, c *logic.Component) {
+	t.Helper()
+	printed := String(c)
+	if again := String(c); again != printed {
+		t.Fatalf("String() is not deterministic:\n%s\n%s", printed, again)
+	}
+	system := logictest.Build(t, printed)
+	parsed, ok := system.Components[c.Name()]
+	if !ok {
+		t.Fatalf("printed source does not define %s:\n%s", c.Name(), printed)
+	}
+	if err := compareComponents(c, parsed); err != nil {
+		t.Errorf("parse(print(%s)) differs: %v\n%s", c.Name(), err, printed)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	system := logictest.Build(t, src)
+	for _, name := range []string{"Not", "Add1", "Add4", "Mux", "Flags"} {
+		roundTrip(t, system.Components[name])
+	}
+}
+
+func TestRoundTripCollapsed(t *testing.T) {
+	system := logictest.Build(t, src)
+	for _, name := range []string{"Add4", "Mux", "Flags"} {
+		roundTrip(t, system.Components[name].Collapse(name))
+	}
+}
+
+func TestWrite(t *testing.T) {
+	system := logictest.Build(t, src)
+	expected := `component Not(a)(r) {
+    r: nand(a, a)
+}
+
+component Flags(a[2])(z, k[3], m[2]) {
+    define unused
+    define wide[5]
+    i: nand(a[0], a[1])
+    z: Not(i)
+    k, m[1], m[0]: 5, 1, 0
+}
+`
+	if actual := String(system.Components["Flags"]); actual != expected {
+		t.Errorf("String() = \n%s\nwant:\n%s", actual, expected)
+	}
+}
+
+func TestWriteRenamesInvalidIdentifiers(t *testing.T) {
+	a := logic.NewBus("a-b", 1)
+	r := logic.NewBus("for", 2)
+	c := logic.NewComponent("nand", []*logic.Bus{a}, []*logic.Bus{r})
+	c.Instances = append(c.Instances, &logic.Instance{
+		Definition: logic.Nand,
+		Inputs:     []logic.BusWire{{Bus: a}, {Bus: a}},
+		Outputs:    []logic.BusWire{{Bus: r, WireIndex: 1}},
+	}, &logic.Instance{
+		Definition: logic.NewConstants([]logic.Value{{true}}),
+		Outputs:    []logic.BusWire{{Bus: r, WireIndex: 0}},
+	})
+	expected := `component nand_1(a_b)(for_[2]) {
+    for_[1]: nand(a_b, a_b)
+    for_[0]: 1
+}
+`
+	printed := String(c)
+	if printed != expected {
+		t.Errorf("String() = \n%s\nwant:\n%s", printed, expected)
+	}
+	logictest.Build(t, printed)
+}
@@ -0,0 +1,44 @@
+package sat
+
+type Var int
+
+func (v Var) Pos() Lit {
+	return Lit(2 * v)
+}
+
+func (v Var) Neg() Lit {
+	return Lit(2*v + 1)
+}
+
+type Lit int
+
+func (l Lit) Var() Var {
+	return Var(l / 2)
+}
+
+func (l Lit) IsNeg() bool {
+	return l%2 == 1
+}
+
+func (l Lit) Not() Lit {
+	return l ^ 1
+}
+
+type CNF struct {
+	Vars    int
+	Clauses [][]Lit
+}
+
+func NewCNF() *CNF {
+	return &CNF{}
+}
+
+func (f *CNF) NewVar() Var {
+	v := Var(f.Vars)
+	f.Vars++
+	return v
+}
+
+func (f *CNF) AddClause(lits ...Lit) {
+	f.Clauses = append(f.Clauses, lits)
+}
@@ -0,0 +1,178 @@
+package sat
+
+import (
+	"fmt"
+
+	"github.com/arneph/mercury/logic"
+)
+
+type Circuit struct {
+	Component *logic.Component
+	collapsed *logic.Component
+	vars      map[*logic.Bus]Var
+	inputs    []Var
+	outputs   []Var
+}
+
+func Encode(f *CNF, c *logic.Component) (*Circuit, error) {
+	return encode(f, c, nil)
+}
+
+func EncodeWithInputs(f *CNF, c *logic.Component, inputs []Var) (*Circuit, error) {
+	if len(inputs) != c.InputWires() {
+		return nil, fmt.Errorf("wrong number of input variables for %s: expected %d, got %d", c.Name(), c.InputWires(), len(inputs))
+	}
+	return encode(f, c, inputs)
+}
+
+func encode(f *CNF, c *logic.Component, inputs []Var) (*Circuit, error) {
+	if c.HasTriGates() {
+		return nil, fmt.Errorf("%s: tri-state buffers are not supported", c.Name())
+	}
+	collapsed := c.Collapse(c.Name())
+	if _, err := collapsed.Levelize(); err != nil {
+		return nil, err
+	}
+	circuit := &Circuit{
+		Component: c,
+		collapsed: collapsed,
+		vars:      make(map[*logic.Bus]Var),
+	}
+	isInput := make(map[*logic.Bus]bool)
+	for i, name := range collapsed.InputBusNames {
+		bus := collapsed.Buses[name]
+		if inputs != nil {
+			circuit.vars[bus] = inputs[i]
+		} else {
+			circuit.vars[bus] = f.NewVar()
+		}
+		isInput[bus] = true
+		circuit.inputs = append(circuit.inputs, circuit.vars[bus])
+	}
+	var order []*logic.Bus
+	varFor := func(bus *logic.Bus) Var {
+		v, ok := circuit.vars[bus]
+		if !ok {
+			v = f.NewVar()
+			circuit.vars[bus] = v
+			order = append(order, bus)
+		}
+		return v
+	}
+	driven := make(map[*logic.Bus]bool)
+	for _, instance := range collapsed.Instances {
+		for _, output := range instance.Outputs {
+			driven[output.Bus] = true
+		}
+		switch def := instance.Definition.(type) {
+		case logic.NandGate:
+			r := varFor(instance.Outputs[0].Bus).Pos()
+			a := varFor(instance.Inputs[0].Bus).Pos()
+			b := varFor(instance.Inputs[1].Bus).Pos()
+			f.AddClause(r, a)
+			f.AddClause(r, b)
+			f.AddClause(r.Not(), a.Not(), b.Not())
+		case *logic.Constants:
+			var bits []bool
+			for _, value := range def.Values() {
+				bits = append(bits, value...)
+			}
+			for i, output := range instance.Outputs {
+				l := varFor(output.Bus).Pos()
+				if bits[i] {
+					f.AddClause(l)
+				} else {
+					f.AddClause(l.Not())
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unexpected logic.Definition: %t", def)
+		}
+	}
+	for _, name := range collapsed.OutputBusNames {
+		circuit.outputs = append(circuit.outputs, varFor(collapsed.Buses[name]))
+	}
+	for _, bus := range order {
+		if !driven[bus] && !isInput[bus] {
+			f.AddClause(circuit.vars[bus].Neg())
+		}
+	}
+	return circuit, nil
+}
+
+func (c *Circuit) Inputs() []Var {
+	return c.inputs
+}
+
+func (c *Circuit) Outputs() []Var {
+	return c.outputs
+}
+
+func Miter(f *CNF, a, b *logic.Component) (*Circuit, *Circuit, error) {
+	if a.InputWires() != b.InputWires() {
+		return nil, nil, fmt.Errorf("different number of input wires: %s has %d, %s has %d", a.Name(), a.InputWires(), b.Name(), b.InputWires())
+	} else if a.OutputWires() != b.OutputWires() {
+		return nil, nil, fmt.Errorf("different number of output wires: %s has %d, %s has %d", a.Name(), a.OutputWires(), b.Name(), b.OutputWires())
+	}
+	ca, err := Encode(f, a)
+	if err != nil {
+		return nil, nil, err
+	}
+	cb, err := EncodeWithInputs(f, b, ca.Inputs())
+	if err != nil {
+		return nil, nil, err
+	}
+	diffs := make([]Lit, len(ca.Outputs()))
+	for i := range ca.Outputs() {
+		x := ca.Outputs()[i].Pos()
+		y := cb.Outputs()[i].Pos()
+		d := f.NewVar().Pos()
+		f.AddClause(d.Not(), x, y)
+		f.AddClause(d.Not(), x.Not(), y.Not())
+		f.AddClause(d, x.Not(), y)
+		f.AddClause(d, x, y.Not())
+		diffs[i] = d
+	}
+	f.AddClause(diffs...)
+	return ca, cb, nil
+}
+
+func (c *Circuit) InputValues(model []bool) *logic.Constants {
+	return c.groupValues(model, c.inputs, c.Component.InputBusNames)
+}
+
+func (c *Circuit) OutputValues(model []bool) *logic.Constants {
+	return c.groupValues(model, c.outputs, c.Component.OutputBusNames)
+}
+
+func (c *Circuit) groupValues(model []bool, vars []Var, busNames []string) *logic.Constants {
+	vals := make([]logic.Value, len(busNames))
+	varIndex := 0
+	for i, name := range busNames {
+		bus := c.Component.Buses[name]
+		val := make(logic.Value, bus.Wires())
+		for j := range val {
+			val[j] = model[vars[varIndex]]
+			varIndex++
+		}
+		vals[i] = val
+	}
+	return logic.NewConstants(vals)
+}
+
+func (c *Circuit) BusValue(model []bool, busName string) (logic.Value, error) {
+	bus, ok := c.Component.Buses[busName]
+	if !ok {
+		return nil, fmt.Errorf("undefined bus in %s: %s", c.Component.Name(), busName)
+	}
+	val := make(logic.Value, bus.Wires())
+	for i := range val {
+		name := logic.CollapsedWireName(bus, logic.WireIndex(i))
+		v, ok := c.vars[c.collapsed.Buses[name]]
+		if !ok {
+			return nil, fmt.Errorf("bus is not connected in %s: %s", c.Component.Name(), busName)
+		}
+		val[i] = model[v]
+	}
+	return val, nil
+}
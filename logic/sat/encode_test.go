@@ -0,0 +1,109 @@
+package sat
+
+import (
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+const src = logictest.Gates + logictest.Xor2 + logictest.Add1 + `
+component Add1Alt(a, b, ci)(r, co) {
+    i1: Xor2(a, b)
+    r: Xor2(i1, ci)
+    i2: nand(a, b)
+    i3: nand(i1, ci)
+    co: nand(i2, i3)
+}
+
+component Add32(a[32], b[32])(r[32], c) {
+    define d[33]
+    'a: Not(a[0])
+    d[0]: And(a[0], 'a)
+    for i from 0 to 31 {
+        r[i], d[i + 1]: Add1(a[i], b[i], d[i])
+    }
+    c: Or(d[32], d[0])
+}
+
+component Add32Alt(a[32], b[32])(r[32], c) {
+    define d[33]
+    i: nand(a[0], a[0])
+    'i: nand(a[0], i)
+    d[0]: Not('i)
+    for j from 0 to 31 {
+        r[j], d[j + 1]: Add1Alt(a[j], b[j], d[j])
+    }
+    c: Or(d[32], d[0])
+}
+`
+
+func TestExtractsModelAsBusValues(t *testing.T) {
+	system := logictest.Build(t, src)
+	f := NewCNF()
+	c, err := Encode(f, system.Components["Add1"])
+	if err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	r, co := c.Outputs()[0], c.Outputs()[1]
+	a := c.Inputs()[0]
+	f.AddClause(r.Neg())
+	f.AddClause(co.Pos())
+	f.AddClause(a.Neg())
+	model, ok := Solve(f)
+	if !ok {
+		t.Fatalf("Solve() = unsatisfiable; want satisfiable")
+	}
+	inputs := c.InputValues(model).Values()
+	expectedInputs := []logic.Value{{false}, {true}, {true}}
+	for i, input := range inputs {
+		if input[0] != expectedInputs[i][0] {
+			t.Errorf("input %d = %v; want %v", i, input, expectedInputs[i])
+		}
+	}
+	outputs := c.OutputValues(model).Values()
+	if outputs[0][0] || !outputs[1][0] {
+		t.Errorf("outputs = %v; want r = 0, co = 1", outputs)
+	}
+	i1, err := c.BusValue(model, "i1")
+	if err != nil {
+		t.Fatalf("BusValue() failed: %v", err)
+	}
+	if !i1[0] {
+		t.Errorf("i1 = %v; want 1", i1)
+	}
+	if _, err := c.BusValue(model, "missing"); err == nil {
+		t.Errorf("BusValue() for undefined bus succeeded; want error")
+	}
+}
+
+func TestMiterOfEquivalentAddersIsUnsatisfiable(t *testing.T) {
+	system := logictest.Build(t, src)
+	for _, pair := range [][2]string{{"Add1", "Add1Alt"}, {"Add32", "Add32Alt"}} {
+		f := NewCNF()
+		if _, _, err := Miter(f, system.Components[pair[0]], system.Components[pair[1]]); err != nil {
+			t.Fatalf("Miter() failed: %v", err)
+		}
+		if model, ok := Solve(f); ok {
+			t.Errorf("Solve() for %s and %s = %v; want unsatisfiable", pair[0], pair[1], model)
+		}
+	}
+}
+
+func TestMiterOfDifferentComponentsIsSatisfiable(t *testing.T) {
+	system := logictest.Build(t, src)
+	f := NewCNF()
+	ca, cb, err := Miter(f, system.Components["Xor"], system.Components["And"])
+	if err != nil {
+		t.Fatalf("Miter() failed: %v", err)
+	}
+	model, ok := Solve(f)
+	if !ok {
+		t.Fatalf("Solve() = unsatisfiable; want satisfiable")
+	}
+	a := ca.OutputValues(model).Values()[0][0]
+	b := cb.OutputValues(model).Values()[0][0]
+	if a == b {
+		t.Errorf("Xor and And agree on counterexample %v", ca.InputValues(model))
+	}
+}
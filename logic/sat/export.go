@@ -0,0 +1,177 @@
+package sat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+type namedVar struct {
+	v    Var
+	name string
+}
+
+func (c *Circuit) namedVars() []namedVar {
+	labels := make(map[string]string)
+	for _, names := range [][]string{c.Component.InputBusNames, c.Component.OutputBusNames} {
+		for _, name := range names {
+			bus := c.Component.Buses[name]
+			for i := 0; i < bus.Wires(); i++ {
+				label := name
+				if bus.Wires() > 1 {
+					label = fmt.Sprintf("%s[%d]", name, i)
+				}
+				labels[logic.CollapsedWireName(bus, logic.WireIndex(i))] = label
+			}
+		}
+	}
+	var vars []namedVar
+	for bus, v := range c.vars {
+		name := bus.Name
+		if label, ok := labels[name]; ok {
+			name = label
+		}
+		vars = append(vars, namedVar{v, c.Component.Name() + "." + name})
+	}
+	sort.Slice(vars, func(i, j int) bool {
+		if vars[i].v != vars[j].v {
+			return vars[i].v < vars[j].v
+		}
+		return vars[i].name < vars[j].name
+	})
+	return vars
+}
+
+func describe(circuits []*Circuit) string {
+	switch len(circuits) {
+	case 1:
+		return circuits[0].Component.Name()
+	case 2:
+		return fmt.Sprintf("miter of %s and %s: unsatisfiable if and only if they are equivalent",
+			circuits[0].Component.Name(), circuits[1].Component.Name())
+	default:
+		var names []string
+		for _, c := range circuits {
+			names = append(names, c.Component.Name())
+		}
+		return strings.Join(names, ", ")
+	}
+}
+
+func WriteDIMACS(w io.Writer, f *CNF, circuits ...*Circuit) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "c %s\n", describe(circuits))
+	for _, c := range circuits {
+		for _, nv := range c.namedVars() {
+			fmt.Fprintf(bw, "c var %d %s\n", nv.v+1, nv.name)
+		}
+	}
+	fmt.Fprintf(bw, "p cnf %d %d\n", f.Vars, len(f.Clauses))
+	for _, lits := range f.Clauses {
+		for _, l := range lits {
+			fmt.Fprintf(bw, "%d ", dimacsLit(l))
+		}
+		bw.WriteString("0\n")
+	}
+	return bw.Flush()
+}
+
+func dimacsLit(l Lit) int {
+	if l.IsNeg() {
+		return -int(l.Var() + 1)
+	}
+	return int(l.Var() + 1)
+}
+
+func WriteSMTLIB(w io.Writer, f *CNF, circuits ...*Circuit) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "; %s\n", describe(circuits))
+	bw.WriteString("(set-logic QF_UF)\n")
+	names := make(map[Var][]string)
+	for _, c := range circuits {
+		for _, nv := range c.namedVars() {
+			names[nv.v] = append(names[nv.v], nv.name)
+		}
+	}
+	for v := Var(0); v < Var(f.Vars); v++ {
+		fmt.Fprintf(bw, "(declare-const v%d Bool)", v+1)
+		if len(names[v]) > 0 {
+			fmt.Fprintf(bw, " ; %s", strings.Join(names[v], ", "))
+		}
+		bw.WriteString("\n")
+	}
+	for _, lits := range f.Clauses {
+		bw.WriteString("(assert ")
+		if len(lits) != 1 {
+			bw.WriteString("(or")
+		}
+		for i, l := range lits {
+			if len(lits) != 1 || i > 0 {
+				bw.WriteString(" ")
+			}
+			if l.IsNeg() {
+				fmt.Fprintf(bw, "(not v%d)", l.Var()+1)
+			} else {
+				fmt.Fprintf(bw, "v%d", l.Var()+1)
+			}
+		}
+		if len(lits) != 1 {
+			bw.WriteString(")")
+		}
+		bw.WriteString(")\n")
+	}
+	bw.WriteString("(check-sat)\n")
+	bw.WriteString("(get-model)\n")
+	return bw.Flush()
+}
+
+func ReadModel(r io.Reader, vars int) (model []bool, ok bool, err error) {
+	model = make([]bool, vars)
+	scanner := bufio.NewScanner(r)
+	satisfiable := false
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "s":
+			switch strings.Join(fields[1:], " ") {
+			case "SATISFIABLE":
+				satisfiable = true
+			case "UNSATISFIABLE":
+				return nil, false, nil
+			default:
+				return nil, false, fmt.Errorf("unknown solver status: %s", strings.Join(fields[1:], " "))
+			}
+		case "v":
+			for _, field := range fields[1:] {
+				lit, err := strconv.Atoi(field)
+				if err != nil {
+					return nil, false, fmt.Errorf("invalid literal in model: %s", field)
+				} else if lit == 0 {
+					continue
+				}
+				v := lit
+				if v < 0 {
+					v = -v
+				}
+				if v > vars {
+					return nil, false, fmt.Errorf("literal %d exceeds number of variables %d", lit, vars)
+				}
+				model[v-1] = lit > 0
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, false, err
+	} else if !satisfiable {
+		return nil, false, fmt.Errorf("missing solver status line")
+	}
+	return model, true, nil
+}
@@ -0,0 +1,173 @@
+package sat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+func parseDIMACS(t *testing.T, src string) *CNF {
+	t.Helper()
+	f := NewCNF()
+	header := false
+	for _, line := range strings.Split(src, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] == "c" {
+			continue
+		} else if fields[0] == "p" {
+			if len(fields) != 4 || fields[1] != "cnf" {
+				t.Fatalf("invalid problem line: %q", line)
+			}
+			vars, _ := strconv.Atoi(fields[2])
+			for i := 0; i < vars; i++ {
+				f.NewVar()
+			}
+			header = true
+			continue
+		} else if !header {
+			t.Fatalf("clause before problem line: %q", line)
+		}
+		var lits []Lit
+		for _, field := range fields {
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				t.Fatalf("invalid literal %q in line %q", field, line)
+			} else if n > 0 {
+				lits = append(lits, Var(n-1).Pos())
+			} else if n < 0 {
+				lits = append(lits, Var(-n-1).Neg())
+			}
+		}
+		f.AddClause(lits...)
+	}
+	return f
+}
+
+func TestWriteDIMACSMiterOfEquivalentComponents(t *testing.T) {
+	system := logictest.Build(t, src)
+	f := NewCNF()
+	ca, cb, err := Miter(f, system.Components["Xor"], system.Components["Xor2"])
+	if err != nil {
+		t.Fatalf("Miter() failed: %v", err)
+	}
+	var sb strings.Builder
+	if err := WriteDIMACS(&sb, f, ca, cb); err != nil {
+		t.Fatalf("WriteDIMACS() failed: %v", err)
+	}
+	out := sb.String()
+	for _, expected := range []string{
+		"c miter of Xor and Xor2",
+		fmt.Sprintf("c var %d Xor.a\n", ca.Inputs()[0]+1),
+		fmt.Sprintf("c var %d Xor2.a\n", cb.Inputs()[0]+1),
+		fmt.Sprintf("c var %d Xor.r\n", ca.Outputs()[0]+1),
+		fmt.Sprintf("p cnf %d %d\n", f.Vars, len(f.Clauses)),
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("WriteDIMACS() output does not contain %q:\n%s", expected, out)
+		}
+	}
+	parsed := parseDIMACS(t, out)
+	if parsed.Vars != f.Vars || len(parsed.Clauses) != len(f.Clauses) {
+		t.Fatalf("parsed %d vars and %d clauses; want %d and %d",
+			parsed.Vars, len(parsed.Clauses), f.Vars, len(f.Clauses))
+	}
+	if model, ok := Solve(parsed); ok {
+		t.Errorf("Solve() = %v; want unsatisfiable", model)
+	}
+}
+
+func TestReadModelOfSatisfiableMiter(t *testing.T) {
+	system := logictest.Build(t, src)
+	f := NewCNF()
+	ca, cb, err := Miter(f, system.Components["Xor"], system.Components["Or"])
+	if err != nil {
+		t.Fatalf("Miter() failed: %v", err)
+	}
+	var sb strings.Builder
+	if err := WriteDIMACS(&sb, f, ca, cb); err != nil {
+		t.Fatalf("WriteDIMACS() failed: %v", err)
+	}
+	solution, ok := Solve(parseDIMACS(t, sb.String()))
+	if !ok {
+		t.Fatalf("Solve() = unsatisfiable; want satisfiable")
+	}
+	var out strings.Builder
+	out.WriteString("c external solver output\ns SATISFIABLE\nv")
+	for i, value := range solution {
+		if value {
+			fmt.Fprintf(&out, " %d", i+1)
+		} else {
+			fmt.Fprintf(&out, " %d", -(i + 1))
+		}
+	}
+	out.WriteString(" 0\n")
+	model, ok, err := ReadModel(strings.NewReader(out.String()), f.Vars)
+	if err != nil {
+		t.Fatalf("ReadModel() failed: %v", err)
+	} else if !ok {
+		t.Fatalf("ReadModel() = unsatisfiable; want satisfiable")
+	}
+	inputs := ca.InputValues(model).Values()
+	if !inputs[0][0] || !inputs[1][0] {
+		t.Errorf("counterexample = %v; want a = 1, b = 1", ca.InputValues(model))
+	}
+	if a, b := ca.OutputValues(model).Values()[0][0], cb.OutputValues(model).Values()[0][0]; a == b {
+		t.Errorf("Xor and Or agree on counterexample %v", ca.InputValues(model))
+	}
+}
+
+func TestReadModel(t *testing.T) {
+	if model, ok, err := ReadModel(strings.NewReader("s UNSATISFIABLE\n"), 3); err != nil || ok || model != nil {
+		t.Errorf("ReadModel() = %v, %v, %v; want unsatisfiable", model, ok, err)
+	}
+	model, ok, err := ReadModel(strings.NewReader("s SATISFIABLE\nv 1 -2\nv 3 0\n"), 3)
+	if err != nil || !ok {
+		t.Fatalf("ReadModel() = %v, %v, %v; want satisfiable", model, ok, err)
+	} else if !model[0] || model[1] || !model[2] {
+		t.Errorf("ReadModel() = %v; want [true false true]", model)
+	}
+	for _, src := range []string{
+		"v 1 0\n",
+		"s UNKNOWN\n",
+		"s SATISFIABLE\nv 1 x 0\n",
+		"s SATISFIABLE\nv 4 0\n",
+	} {
+		if _, _, err := ReadModel(strings.NewReader(src), 3); err == nil {
+			t.Errorf("ReadModel() for %q succeeded; want error", src)
+		}
+	}
+}
+
+func TestWriteSMTLIB(t *testing.T) {
+	system := logictest.Build(t, src)
+	f := NewCNF()
+	c, err := Encode(f, system.Components["Add1"])
+	if err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	f.AddClause(c.Outputs()[1].Pos())
+	var sb strings.Builder
+	if err := WriteSMTLIB(&sb, f, c); err != nil {
+		t.Fatalf("WriteSMTLIB() failed: %v", err)
+	}
+	out := sb.String()
+	if n := strings.Count(out, "(declare-const "); n != f.Vars {
+		t.Errorf("WriteSMTLIB() declared %d constants; want %d", n, f.Vars)
+	}
+	if n := strings.Count(out, "(assert "); n != len(f.Clauses) {
+		t.Errorf("WriteSMTLIB() made %d assertions; want %d", n, len(f.Clauses))
+	}
+	for _, expected := range []string{
+		"(set-logic QF_UF)\n",
+		fmt.Sprintf("(declare-const v%d Bool) ; Add1.co\n", c.Outputs()[1]+1),
+		fmt.Sprintf("(assert v%d)\n", c.Outputs()[1]+1),
+		"(check-sat)\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("WriteSMTLIB() output does not contain %q:\n%s", expected, out)
+		}
+	}
+}
@@ -0,0 +1,430 @@
+package sat
+
+import "sort"
+
+type lbool int8
+
+const (
+	lFalse lbool = -1
+	lUndef lbool = 0
+	lTrue  lbool = 1
+)
+
+const undefLit Lit = -1
+
+type clause struct {
+	lits     []Lit
+	learnt   bool
+	deleted  bool
+	activity float64
+}
+
+type Solver struct {
+	ok       bool
+	clauses  []*clause
+	learnts  []*clause
+	watches  [][]*clause
+	assigns  []lbool
+	level    []int
+	reason   []*clause
+	polarity []bool
+	seen     []bool
+	trail    []Lit
+	trailLim []int
+	qhead    int
+
+	activity    []float64
+	varInc      float64
+	varDecay    float64
+	clauseInc   float64
+	clauseDecay float64
+	order       varHeap
+
+	maxLearnts float64
+	model      []bool
+
+	Conflicts    int
+	Decisions    int
+	Propagations int
+	Restarts     int
+}
+
+func NewSolver() *Solver {
+	s := &Solver{
+		ok:          true,
+		varInc:      1,
+		varDecay:    0.95,
+		clauseInc:   1,
+		clauseDecay: 0.999,
+	}
+	s.order.activity = &s.activity
+	return s
+}
+
+func Solve(f *CNF) (model []bool, ok bool) {
+	s := NewSolver()
+	for i := 0; i < f.Vars; i++ {
+		s.NewVar()
+	}
+	for _, lits := range f.Clauses {
+		s.AddClause(lits...)
+	}
+	if !s.Solve() {
+		return nil, false
+	}
+	return s.Model(), true
+}
+
+func (s *Solver) NumVars() int {
+	return len(s.assigns)
+}
+
+func (s *Solver) NewVar() Var {
+	v := Var(len(s.assigns))
+	s.watches = append(s.watches, nil, nil)
+	s.assigns = append(s.assigns, lUndef)
+	s.level = append(s.level, 0)
+	s.reason = append(s.reason, nil)
+	s.polarity = append(s.polarity, false)
+	s.seen = append(s.seen, false)
+	s.activity = append(s.activity, 0)
+	s.order.insert(v)
+	return v
+}
+
+func (s *Solver) value(l Lit) lbool {
+	a := s.assigns[l.Var()]
+	if l.IsNeg() {
+		return -a
+	}
+	return a
+}
+
+func (s *Solver) decisionLevel() int {
+	return len(s.trailLim)
+}
+
+func (s *Solver) AddClause(lits ...Lit) bool {
+	if !s.ok {
+		return false
+	}
+	ls := append([]Lit(nil), lits...)
+	sort.Slice(ls, func(i, j int) bool { return ls[i] < ls[j] })
+	j := 0
+	prev := undefLit
+	for _, l := range ls {
+		if s.value(l) == lTrue || l == prev.Not() {
+			return true
+		} else if s.value(l) != lFalse && l != prev {
+			ls[j] = l
+			j++
+			prev = l
+		}
+	}
+	ls = ls[:j]
+	switch len(ls) {
+	case 0:
+		s.ok = false
+		return false
+	case 1:
+		s.enqueue(ls[0], nil)
+		if s.propagate() != nil {
+			s.ok = false
+		}
+		return s.ok
+	default:
+		c := &clause{lits: ls}
+		s.clauses = append(s.clauses, c)
+		s.attach(c)
+		return true
+	}
+}
+
+func (s *Solver) attach(c *clause) {
+	s.watches[c.lits[0]] = append(s.watches[c.lits[0]], c)
+	s.watches[c.lits[1]] = append(s.watches[c.lits[1]], c)
+}
+
+func (s *Solver) enqueue(l Lit, from *clause) {
+	v := l.Var()
+	if l.IsNeg() {
+		s.assigns[v] = lFalse
+	} else {
+		s.assigns[v] = lTrue
+	}
+	s.level[v] = s.decisionLevel()
+	s.reason[v] = from
+	s.trail = append(s.trail, l)
+}
+
+func (s *Solver) propagate() *clause {
+	for s.qhead < len(s.trail) {
+		falseLit := s.trail[s.qhead].Not()
+		s.qhead++
+		s.Propagations++
+		ws := s.watches[falseLit]
+		i, j := 0, 0
+		for i < len(ws) {
+			c := ws[i]
+			i++
+			if c.deleted {
+				continue
+			}
+			if c.lits[0] == falseLit {
+				c.lits[0], c.lits[1] = c.lits[1], c.lits[0]
+			}
+			if s.value(c.lits[0]) == lTrue {
+				ws[j] = c
+				j++
+				continue
+			}
+			found := false
+			for k := 2; k < len(c.lits); k++ {
+				if s.value(c.lits[k]) != lFalse {
+					c.lits[1], c.lits[k] = c.lits[k], c.lits[1]
+					s.watches[c.lits[1]] = append(s.watches[c.lits[1]], c)
+					found = true
+					break
+				}
+			}
+			if found {
+				continue
+			}
+			ws[j] = c
+			j++
+			if s.value(c.lits[0]) == lFalse {
+				j += copy(ws[j:], ws[i:])
+				s.watches[falseLit] = ws[:j]
+				s.qhead = len(s.trail)
+				return c
+			}
+			s.enqueue(c.lits[0], c)
+		}
+		s.watches[falseLit] = ws[:j]
+	}
+	return nil
+}
+
+func (s *Solver) analyze(confl *clause) ([]Lit, int) {
+	learnt := []Lit{undefLit}
+	pathCount := 0
+	p := undefLit
+	index := len(s.trail) - 1
+	for {
+		if confl.learnt {
+			s.bumpClause(confl)
+		}
+		for k, q := range confl.lits {
+			if p != undefLit && k == 0 {
+				continue
+			}
+			v := q.Var()
+			if s.seen[v] || s.level[v] == 0 {
+				continue
+			}
+			s.bumpVar(v)
+			s.seen[v] = true
+			if s.level[v] >= s.decisionLevel() {
+				pathCount++
+			} else {
+				learnt = append(learnt, q)
+			}
+		}
+		for !s.seen[s.trail[index].Var()] {
+			index--
+		}
+		p = s.trail[index]
+		index--
+		confl = s.reason[p.Var()]
+		s.seen[p.Var()] = false
+		pathCount--
+		if pathCount == 0 {
+			break
+		}
+	}
+	learnt[0] = p.Not()
+	backtrackLevel := 0
+	for i := 1; i < len(learnt); i++ {
+		if s.level[learnt[i].Var()] > backtrackLevel {
+			backtrackLevel = s.level[learnt[i].Var()]
+			learnt[1], learnt[i] = learnt[i], learnt[1]
+		}
+	}
+	for _, l := range learnt {
+		s.seen[l.Var()] = false
+	}
+	return learnt, backtrackLevel
+}
+
+func (s *Solver) cancelUntil(level int) {
+	if s.decisionLevel() <= level {
+		return
+	}
+	for i := len(s.trail) - 1; i >= s.trailLim[level]; i-- {
+		v := s.trail[i].Var()
+		s.assigns[v] = lUndef
+		s.reason[v] = nil
+		s.polarity[v] = !s.trail[i].IsNeg()
+		s.order.insert(v)
+	}
+	s.trail = s.trail[:s.trailLim[level]]
+	s.trailLim = s.trailLim[:level]
+	s.qhead = len(s.trail)
+}
+
+func (s *Solver) bumpVar(v Var) {
+	s.activity[v] += s.varInc
+	if s.activity[v] > 1e100 {
+		for i := range s.activity {
+			s.activity[i] *= 1e-100
+		}
+		s.varInc *= 1e-100
+	}
+	s.order.update(v)
+}
+
+func (s *Solver) bumpClause(c *clause) {
+	c.activity += s.clauseInc
+	if c.activity > 1e20 {
+		for _, l := range s.learnts {
+			l.activity *= 1e-20
+		}
+		s.clauseInc *= 1e-20
+	}
+}
+
+func (s *Solver) decayActivities() {
+	s.varInc /= s.varDecay
+	s.clauseInc /= s.clauseDecay
+}
+
+func (s *Solver) pickBranchLit() Lit {
+	for !s.order.empty() {
+		v := s.order.removeMax()
+		if s.assigns[v] == lUndef {
+			if s.polarity[v] {
+				return v.Pos()
+			}
+			return v.Neg()
+		}
+	}
+	return undefLit
+}
+
+func (s *Solver) locked(c *clause) bool {
+	v := c.lits[0].Var()
+	return s.reason[v] == c && s.value(c.lits[0]) == lTrue
+}
+
+func (s *Solver) reduceLearnts() {
+	sort.Slice(s.learnts, func(i, j int) bool {
+		return s.learnts[i].activity < s.learnts[j].activity
+	})
+	limit := s.clauseInc / float64(len(s.learnts))
+	j := 0
+	for i, c := range s.learnts {
+		if len(c.lits) > 2 && !s.locked(c) && (i < len(s.learnts)/2 || c.activity < limit) {
+			c.deleted = true
+		} else {
+			s.learnts[j] = c
+			j++
+		}
+	}
+	s.learnts = s.learnts[:j]
+}
+
+func (s *Solver) search(conflictBudget int) lbool {
+	conflicts := 0
+	for {
+		confl := s.propagate()
+		if confl != nil {
+			conflicts++
+			s.Conflicts++
+			if s.decisionLevel() == 0 {
+				return lFalse
+			}
+			learnt, backtrackLevel := s.analyze(confl)
+			s.cancelUntil(backtrackLevel)
+			if len(learnt) == 1 {
+				s.enqueue(learnt[0], nil)
+			} else {
+				c := &clause{lits: learnt, learnt: true}
+				s.learnts = append(s.learnts, c)
+				s.attach(c)
+				s.bumpClause(c)
+				s.enqueue(learnt[0], c)
+			}
+			s.decayActivities()
+			continue
+		}
+		if conflicts >= conflictBudget {
+			s.cancelUntil(0)
+			return lUndef
+		}
+		if float64(len(s.learnts)-len(s.trail)) >= s.maxLearnts {
+			s.reduceLearnts()
+		}
+		next := s.pickBranchLit()
+		if next == undefLit {
+			return lTrue
+		}
+		s.Decisions++
+		s.trailLim = append(s.trailLim, len(s.trail))
+		s.enqueue(next, nil)
+	}
+}
+
+func (s *Solver) Solve() bool {
+	s.model = nil
+	if !s.ok {
+		return false
+	}
+	if s.propagate() != nil {
+		s.ok = false
+		return false
+	}
+	s.maxLearnts = max(float64(len(s.clauses))/3, 100)
+	for restarts := 0; ; restarts++ {
+		status := s.search(int(luby(2, restarts) * 100))
+		switch status {
+		case lTrue:
+			s.model = make([]bool, len(s.assigns))
+			for v, a := range s.assigns {
+				s.model[v] = a == lTrue
+			}
+			s.cancelUntil(0)
+			return true
+		case lFalse:
+			s.ok = false
+			return false
+		}
+		s.Restarts++
+		s.maxLearnts *= 1.1
+	}
+}
+
+func (s *Solver) Model() []bool {
+	return s.model
+}
+
+func (s *Solver) Value(v Var) bool {
+	return s.model[v]
+}
+
+func luby(y float64, x int) float64 {
+	size, seq := 1, 0
+	for size < x+1 {
+		seq++
+		size = 2*size + 1
+	}
+	for size-1 != x {
+		size = (size - 1) >> 1
+		seq--
+		x = x % size
+	}
+	result := 1.0
+	for i := 0; i < seq; i++ {
+		result *= y
+	}
+	return result
+}
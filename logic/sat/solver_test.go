@@ -0,0 +1,133 @@
+package sat
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func satisfies(model []bool, clauses [][]Lit) bool {
+	for _, clause := range clauses {
+		satisfied := false
+		for _, l := range clause {
+			if model[l.Var()] != l.IsNeg() {
+				satisfied = true
+				break
+			}
+		}
+		if !satisfied {
+			return false
+		}
+	}
+	return true
+}
+
+func bruteForce(f *CNF) bool {
+	model := make([]bool, f.Vars)
+	for x := 0; x < 1<<f.Vars; x++ {
+		for v := range model {
+			model[v] = (x>>v)%2 == 1
+		}
+		if satisfies(model, f.Clauses) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSolvesSimpleFormulas(t *testing.T) {
+	f := NewCNF()
+	a, b, c := f.NewVar(), f.NewVar(), f.NewVar()
+	f.AddClause(a.Pos(), b.Pos())
+	f.AddClause(a.Neg(), c.Pos())
+	f.AddClause(b.Neg(), c.Neg())
+	f.AddClause(a.Pos())
+	model, ok := Solve(f)
+	if !ok {
+		t.Fatalf("Solve() = unsatisfiable; want satisfiable")
+	}
+	if !satisfies(model, f.Clauses) {
+		t.Errorf("Solve() = %v; does not satisfy formula", model)
+	}
+
+	f.AddClause(b.Pos(), c.Neg())
+	if _, ok := Solve(f); ok {
+		t.Errorf("Solve() = satisfiable; want unsatisfiable")
+	}
+}
+
+func TestEmptyClauseIsUnsatisfiable(t *testing.T) {
+	f := NewCNF()
+	f.NewVar()
+	f.AddClause()
+	if _, ok := Solve(f); ok {
+		t.Errorf("Solve() = satisfiable; want unsatisfiable")
+	}
+}
+
+func TestPigeonholeIsUnsatisfiable(t *testing.T) {
+	const holes = 6
+	f := NewCNF()
+	var vars [holes + 1][holes]Var
+	for p := range vars {
+		clause := make([]Lit, holes)
+		for h := range vars[p] {
+			vars[p][h] = f.NewVar()
+			clause[h] = vars[p][h].Pos()
+		}
+		f.AddClause(clause...)
+	}
+	for h := 0; h < holes; h++ {
+		for p := range vars {
+			for q := p + 1; q < len(vars); q++ {
+				f.AddClause(vars[p][h].Neg(), vars[q][h].Neg())
+			}
+		}
+	}
+	if _, ok := Solve(f); ok {
+		t.Errorf("Solve() = satisfiable; want unsatisfiable")
+	}
+}
+
+func TestAgreesWithBruteForceOnRandomFormulas(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 200; i++ {
+		f := NewCNF()
+		for v := 0; v < 12; v++ {
+			f.NewVar()
+		}
+		for c := 0; c < 50; c++ {
+			clause := make([]Lit, 3)
+			for j := range clause {
+				clause[j] = Lit(r.Intn(2 * f.Vars))
+			}
+			f.AddClause(clause...)
+		}
+		model, ok := Solve(f)
+		if expected := bruteForce(f); ok != expected {
+			t.Fatalf("Solve() = %v for formula %d; want %v", ok, i, expected)
+		}
+		if ok && !satisfies(model, f.Clauses) {
+			t.Fatalf("Solve() = %v for formula %d; does not satisfy formula", model, i)
+		}
+	}
+}
+
+func TestSolverIsIncremental(t *testing.T) {
+	s := NewSolver()
+	a, b := s.NewVar(), s.NewVar()
+	s.AddClause(a.Pos(), b.Pos())
+	if !s.Solve() {
+		t.Fatalf("Solve() = false; want true")
+	}
+	s.AddClause(a.Neg())
+	if !s.Solve() {
+		t.Fatalf("Solve() = false; want true")
+	}
+	if s.Value(a) || !s.Value(b) {
+		t.Errorf("model = %v; want a = false, b = true", s.Model())
+	}
+	s.AddClause(b.Neg())
+	if s.Solve() {
+		t.Errorf("Solve() = true; want false")
+	}
+}
@@ -0,0 +1,139 @@
+package simulation
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+
+	"github.com/arneph/mercury/logic"
+)
+
+const lanes = 64
+
+func SimulateBatch(c *logic.Component, inputs []*logic.Constants) ([]*logic.Constants, error) {
+	return SimulateBatchWithOptions(c, inputs, Options{})
+}
+
+func SimulateBatchWithOptions(c *logic.Component, inputs []*logic.Constants, opts Options) ([]*logic.Constants, error) {
+	if opts.FourValued {
+		return nil, fmt.Errorf("batch simulation does not support four-valued mode")
+	}
+	n := Compile(c.Collapse(c.Name()))
+	limit := opts.settleLimit()
+	initial := make([]uint64, n.Wires)
+	initial[OneWire] = ^uint64(0)
+	if wires := sweepLanes(n, initial, limit); wires != nil {
+		return nil, n.oscillationError(wires, limit)
+	}
+	outputs := make([]*logic.Constants, 0, len(inputs))
+	state := make([]uint64, n.Wires)
+	for start := 0; start < len(inputs); start += lanes {
+		batch := inputs[start:min(start+lanes, len(inputs))]
+		copy(state, initial)
+		for lane, input := range batch {
+			w := 0
+			for _, value := range input.Values() {
+				for _, v := range value {
+					if v {
+						state[n.Inputs[w]] |= 1 << lane
+					} else {
+						state[n.Inputs[w]] &^= 1 << lane
+					}
+					w++
+				}
+			}
+		}
+		if wires := sweepLanes(n, state, limit); wires != nil {
+			return nil, n.oscillationError(wires, limit)
+		} else if err := conflictLanes(n, state, uint64(1)<<len(batch)-1); err != nil {
+			return nil, err
+		}
+		for lane := range batch {
+			w := 0
+			values := make([]logic.Value, len(c.OutputBusNames))
+			for i, name := range c.OutputBusNames {
+				values[i] = make(logic.Value, c.Buses[name].Wires())
+				for j := range values[i] {
+					values[i][j] = state[n.Outputs[w]]&(1<<lane) != 0
+					w++
+				}
+			}
+			outputs = append(outputs, logic.NewConstants(values))
+		}
+	}
+	return outputs, nil
+}
+
+func sweepLanes(n *Netlist, state []uint64, limit int) []int32 {
+	for sweeps := 0; sweeps < limit; sweeps++ {
+		if !sweepLanesOnce(n, state, nil) {
+			return nil
+		}
+	}
+	changed := make(map[int32]bool)
+	for i := 0; i < 2; i++ {
+		sweepLanesOnce(n, state, changed)
+	}
+	wires := make([]int32, 0, len(changed))
+	for w := range changed {
+		wires = append(wires, w)
+	}
+	slices.Sort(wires)
+	return wires
+}
+
+func sweepLanesOnce(n *Netlist, state []uint64, changed map[int32]bool) bool {
+	stable := true
+	for _, g := range n.Gates {
+		r := ^(state[g.A] & state[g.B])
+		if g.Shared {
+			r, _ = resolveLanes(n, state, g.R)
+		}
+		if state[g.R] != r {
+			state[g.R] = r
+			stable = false
+			if changed != nil {
+				changed[g.R] = true
+			}
+		}
+	}
+	return !stable
+}
+
+func driveLanes(g Gate, state []uint64) (enabled, value uint64) {
+	if g.Tri {
+		return state[g.A], state[g.B]
+	}
+	return ^uint64(0), ^(state[g.A] & state[g.B])
+}
+
+func resolveLanes(n *Netlist, state []uint64, w int32) (value, conflict uint64) {
+	var ones, zeros uint64
+	for _, i := range n.Drivers(w) {
+		enabled, value := driveLanes(n.Gates[i], state)
+		ones |= enabled & value
+		zeros |= enabled &^ value
+	}
+	return ones &^ zeros, ones & zeros
+}
+
+func conflictLanes(n *Netlist, state []uint64, mask uint64) *ConflictError {
+	for _, w := range n.shared {
+		_, conflict := resolveLanes(n, state, w)
+		if conflict&mask == 0 {
+			continue
+		}
+		lane := bits.TrailingZeros64(conflict & mask)
+		var drivers []int32
+		var levels []Level
+		for _, i := range n.Drivers(w) {
+			enabled, value := driveLanes(n.Gates[i], state)
+			if enabled&(1<<lane) != 0 {
+				drivers = append(drivers, i)
+				levels = append(levels, levelOf(value&(1<<lane) != 0))
+			}
+		}
+		return n.conflictError(w, drivers, levels)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+func TestSimulateBatchMatchesComponentState(t *testing.T) {
+	system := logictest.Build(t, adderSource(16))
+	rng := rand.New(rand.NewSource(4))
+	for _, name := range []string{"Add1", "Memory1", "Add16", "Memory16"} {
+		c := system.Components[name]
+		inputs := make([]*logic.Constants, 150)
+		for i := range inputs {
+			inputs[i] = randomInputs(rng, c)
+		}
+		outputs, err := SimulateBatch(c, inputs)
+		if err != nil {
+			t.Fatalf("SimulateBatch() failed: %v", err)
+		} else if len(outputs) != len(inputs) {
+			t.Fatalf("SimulateBatch() returned %d outputs; want %d", len(outputs), len(inputs))
+		}
+		for i, input := range inputs {
+			s := newState(t, c.Collapse(c.Name()))
+			setInputs(t, s, collapseInput(input))
+			expected := groupOutput(s.Outputs(), c)
+			if fmt.Sprint(outputs[i]) != fmt.Sprint(expected) {
+				t.Errorf("%s for %v = %v; want %v", name, input, outputs[i], expected)
+			}
+		}
+	}
+}
+
+func TestSimulateBatchExhaustive(t *testing.T) {
+	c := logictest.Build(t, adderSource(8)).Components["Add8"]
+	var inputs []*logic.Constants
+	for m := 0; m < 1<<17; m++ {
+		inputs = append(inputs, logic.NewConstants([]logic.Value{
+			uintValue(uint64(m), 8),
+			uintValue(uint64(m>>8), 8),
+			uintValue(uint64(m>>16), 1),
+		}))
+	}
+	outputs, err := SimulateBatch(c, inputs)
+	if err != nil {
+		t.Fatalf("SimulateBatch() failed: %v", err)
+	}
+	for m, output := range outputs {
+		sum := m&0xff + (m>>8)&0xff + m>>16
+		r, co := output.Values()[0], output.Values()[1]
+		if r.String() != fmt.Sprint(sum&0xff) || co[0] != (sum > 0xff) {
+			t.Fatalf("Add8 for %v = %v; want %d", inputs[m], output, sum)
+		}
+	}
+}
+
+func uintValue(x uint64, width int) logic.Value {
+	value := make(logic.Value, width)
+	for i := range value {
+		value[i] = x>>i&1 == 1
+	}
+	return value
+}
+
+func TestSimulateBatchEmpty(t *testing.T) {
+	c := logictest.Build(t, src).Components["Add1"]
+	if outputs, err := SimulateBatch(c, nil); err != nil || len(outputs) != 0 {
+		t.Errorf("SimulateBatch() = %v, %v; want no outputs", outputs, err)
+	}
+	if _, err := SimulateBatchWithOptions(c, nil, Options{FourValued: true}); err == nil {
+		t.Errorf("SimulateBatchWithOptions() in four-valued mode succeeded")
+	}
+}
+
+func TestSimulateBatchOscillation(t *testing.T) {
+	c := logictest.Build(t, src).Components["Blinker"]
+	inputs := []*logic.Constants{
+		logic.NewConstants([]logic.Value{{false}}),
+		logic.NewConstants([]logic.Value{{true}}),
+	}
+	_, err := SimulateBatch(c, inputs)
+	if oscillation, ok := err.(*OscillationError); !ok {
+		t.Errorf("SimulateBatch() = %v; want *OscillationError", err)
+	} else if len(oscillation.Wires) != 4 {
+		t.Errorf("oscillating wires = %v; want 4 wires", oscillation.Wires)
+	}
+}
+
+func TestSimulateBatchTriState(t *testing.T) {
+	system := logictest.Build(t, src)
+	var inputs []*logic.Constants
+	for m := 0; m < 8; m++ {
+		inputs = append(inputs, logic.NewConstants([]logic.Value{{m&1 == 1}, {m&2 == 2}, {m&4 == 4}}))
+	}
+	outputs, err := SimulateBatch(system.Components["Mux"], inputs)
+	if err != nil {
+		t.Fatalf("SimulateBatch() failed: %v", err)
+	}
+	for m, output := range outputs {
+		expected := m&2 == 2
+		if m&1 == 1 {
+			expected = m&4 == 4
+		}
+		if r := output.Values()[0][0]; r != expected {
+			t.Errorf("Mux for %v = %v; want %v", inputs[m], r, expected)
+		}
+	}
+	inputs = []*logic.Constants{
+		logic.NewConstants([]logic.Value{{true}, {false}, {true}, {false}}),
+		logic.NewConstants([]logic.Value{{true}, {true}, {true}, {false}}),
+	}
+	_, err = SimulateBatch(system.Components["Clash"], inputs)
+	if conflict, ok := err.(*ConflictError); !ok {
+		t.Errorf("SimulateBatch() = %v; want *ConflictError", err)
+	} else if conflict.Wire != "r" || len(conflict.Drivers) != 2 {
+		t.Errorf("conflict on %s with %d drivers; want r with 2", conflict.Wire, len(conflict.Drivers))
+	}
+}
+
+func benchmarkInputs(c *logic.Component) []*logic.Constants {
+	rng := rand.New(rand.NewSource(5))
+	inputs := make([]*logic.Constants, 1024)
+	for i := range inputs {
+		inputs[i] = randomInputs(rng, c)
+	}
+	return inputs
+}
+
+func BenchmarkSimulateBatchAdd64(b *testing.B) {
+	c := logictest.Build(b, adderSource(64)).Components["Add64"]
+	inputs := benchmarkInputs(c)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SimulateBatch(c, inputs)
+	}
+}
+
+func BenchmarkSequentialAdd64(b *testing.B) {
+	c := logictest.Build(b, adderSource(64)).Components["Add64"]
+	inputs := benchmarkInputs(c)
+	n := Compile(c.Collapse(c.Name()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, input := range inputs {
+			s := newStateForNetlist(b, n)
+			setInputs(b, s, input)
+			groupOutput(s.Outputs(), c)
+		}
+	}
+}
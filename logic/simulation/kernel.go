@@ -0,0 +1,264 @@
+package simulation
+
+import (
+	"math/bits"
+	"slices"
+)
+
+type kernel struct {
+	netlist *Netlist
+	state   []uint64
+	unknown []uint64
+	simple  bool
+	current gateQueue
+	next    []int32
+	pending []bool
+	trace   map[int32]bool
+}
+
+func newKernel(n *Netlist) *kernel {
+	k := &kernel{
+		netlist: n,
+		state:   make([]uint64, (n.Wires+63)/64),
+		current: newGateQueue(len(n.Gates)),
+		pending: make([]bool, len(n.Gates)),
+		simple:  len(n.shared) == 0,
+	}
+	k.put(OneWire, true)
+	for i := range n.Gates {
+		k.schedule(int32(i))
+	}
+	return k
+}
+
+func newFourValuedKernel(n *Netlist) *kernel {
+	k := newKernel(n)
+	k.unknown = make([]uint64, len(k.state))
+	k.simple = false
+	driven := make([]bool, n.Wires)
+	for _, g := range n.Gates {
+		driven[g.R] = true
+	}
+	for _, w := range n.Inputs {
+		driven[w] = true
+	}
+	for w := firstWire; w < int32(n.Wires); w++ {
+		if driven[w] {
+			k.putLevel(w, X)
+		} else {
+			k.putLevel(w, Z)
+		}
+	}
+	return k
+}
+
+func (k *kernel) get(w int32) bool {
+	return k.state[w>>6]&(1<<(w&63)) != 0
+}
+
+func (k *kernel) put(w int32, v bool) {
+	if v {
+		k.state[w>>6] |= 1 << (w & 63)
+	} else {
+		k.state[w>>6] &^= 1 << (w & 63)
+	}
+}
+
+func (k *kernel) level(w int32) Level {
+	v := k.get(w)
+	if k.unknown != nil && k.unknown[w>>6]&(1<<(w&63)) != 0 {
+		if v {
+			return Z
+		}
+		return X
+	}
+	return levelOf(v)
+}
+
+func (k *kernel) putLevel(w int32, l Level) {
+	k.put(w, l == ONE || l == Z)
+	if l.Known() {
+		k.unknown[w>>6] &^= 1 << (w & 63)
+	} else {
+		k.unknown[w>>6] |= 1 << (w & 63)
+	}
+}
+
+func (k *kernel) set(w int32, v bool) {
+	if k.unknown != nil {
+		if k.level(w) != levelOf(v) {
+			k.putLevel(w, levelOf(v))
+			k.changed(w, -1)
+		}
+	} else if k.get(w) != v {
+		k.put(w, v)
+		k.changed(w, -1)
+	}
+}
+
+func (k *kernel) schedule(i int32) {
+	if !k.pending[i] {
+		k.pending[i] = true
+		k.next = append(k.next, i)
+	}
+}
+
+func (k *kernel) changed(w, evaluating int32) {
+	for _, i := range k.netlist.Fanout(w) {
+		if i > evaluating {
+			k.current.insert(i)
+		} else {
+			k.schedule(i)
+		}
+	}
+}
+
+func (k *kernel) settle(limit int) []int32 {
+	for passes := 0; len(k.next) > 0 || !k.current.empty(); passes++ {
+		if passes == limit {
+			return k.oscillating()
+		}
+		k.pass()
+	}
+	return nil
+}
+
+func (k *kernel) oscillating() []int32 {
+	k.trace = make(map[int32]bool)
+	for i := 0; i < 2 && (len(k.next) > 0 || !k.current.empty()); i++ {
+		k.pass()
+	}
+	wires := make([]int32, 0, len(k.trace))
+	for w := range k.trace {
+		wires = append(wires, w)
+	}
+	slices.Sort(wires)
+	k.trace = nil
+	return wires
+}
+
+func (k *kernel) pass() {
+	for _, i := range k.next {
+		k.pending[i] = false
+		k.current.insert(i)
+	}
+	k.next = k.next[:0]
+	k.current.rewind()
+	for !k.current.empty() {
+		k.evaluate(k.current.removeMin())
+	}
+}
+
+func (k *kernel) evaluate(i int32) {
+	g := k.netlist.Gates[i]
+	if !k.simple {
+		var r Level
+		if g.Shared {
+			r = k.resolve(g.R)
+		} else {
+			r = nandLevel(k.level(g.A), k.level(g.B))
+		}
+		if k.unknown == nil {
+			if k.get(g.R) == (r == ONE) {
+				return
+			}
+			k.put(g.R, r == ONE)
+		} else if k.level(g.R) == r {
+			return
+		} else {
+			k.putLevel(g.R, r)
+		}
+	} else {
+		r := !(k.get(g.A) && k.get(g.B))
+		if k.get(g.R) == r {
+			return
+		}
+		k.put(g.R, r)
+	}
+	k.changed(g.R, i)
+	if k.trace != nil {
+		k.trace[g.R] = true
+	}
+}
+
+func (k *kernel) drive(g Gate) Level {
+	a, b := k.level(g.A), k.level(g.B)
+	if !g.Tri {
+		return nandLevel(a, b)
+	} else if a == ZERO {
+		return Z
+	} else if a == ONE && b.Known() {
+		return b
+	}
+	return X
+}
+
+func (k *kernel) resolve(w int32) Level {
+	r := Z
+	for _, i := range k.netlist.Drivers(w) {
+		switch l := k.drive(k.netlist.Gates[i]); {
+		case l == Z:
+		case r == Z:
+			r = l
+		case r != l:
+			r = X
+		}
+	}
+	return r
+}
+
+func (k *kernel) conflict() *ConflictError {
+	for _, w := range k.netlist.shared {
+		var drivers []int32
+		var levels []Level
+		zero, one := false, false
+		for _, i := range k.netlist.Drivers(w) {
+			l := k.drive(k.netlist.Gates[i])
+			if l.Known() {
+				drivers = append(drivers, i)
+				levels = append(levels, l)
+				zero = zero || l == ZERO
+				one = one || l == ONE
+			}
+		}
+		if zero && one {
+			return k.netlist.conflictError(w, drivers, levels)
+		}
+	}
+	return nil
+}
+
+type gateQueue struct {
+	words  []uint64
+	cursor int
+	count  int
+}
+
+func newGateQueue(gates int) gateQueue {
+	return gateQueue{words: make([]uint64, (gates+63)/64)}
+}
+
+func (q *gateQueue) empty() bool {
+	return q.count == 0
+}
+
+func (q *gateQueue) insert(i int32) {
+	if bit := uint64(1) << (i % 64); q.words[i/64]&bit == 0 {
+		q.words[i/64] |= bit
+		q.count++
+	}
+}
+
+func (q *gateQueue) rewind() {
+	q.cursor = 0
+}
+
+func (q *gateQueue) removeMin() int32 {
+	for q.words[q.cursor] == 0 {
+		q.cursor++
+	}
+	b := bits.TrailingZeros64(q.words[q.cursor])
+	q.words[q.cursor] &^= 1 << b
+	q.count--
+	return int32(q.cursor*64 + b)
+}
@@ -0,0 +1,82 @@
+package simulation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+type Level uint8
+
+const (
+	ZERO Level = iota
+	ONE
+	X
+	Z
+)
+
+func levelOf(v bool) Level {
+	if v {
+		return ONE
+	}
+	return ZERO
+}
+
+func (l Level) Known() bool {
+	return l == ZERO || l == ONE
+}
+
+func (l Level) String() string {
+	switch l {
+	case ZERO:
+		return "0"
+	case ONE:
+		return "1"
+	case X:
+		return "X"
+	case Z:
+		return "Z"
+	default:
+		panic(fmt.Errorf("unexpected simulation.Level: %d", l))
+	}
+}
+
+type Levels []Level
+
+func (ls Levels) Known() bool {
+	for _, l := range ls {
+		if !l.Known() {
+			return false
+		}
+	}
+	return true
+}
+
+func (ls Levels) Value() logic.Value {
+	value := make(logic.Value, len(ls))
+	for i, l := range ls {
+		value[i] = l == ONE
+	}
+	return value
+}
+
+func (ls Levels) String() string {
+	if ls.Known() {
+		return ls.Value().String()
+	}
+	var sb strings.Builder
+	for i := len(ls) - 1; i >= 0; i-- {
+		sb.WriteString(ls[i].String())
+	}
+	return sb.String()
+}
+
+func nandLevel(a, b Level) Level {
+	if a == ZERO || b == ZERO {
+		return ONE
+	} else if a == ONE && b == ONE {
+		return ZERO
+	}
+	return X
+}
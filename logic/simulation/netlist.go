@@ -0,0 +1,210 @@
+package simulation
+
+import (
+	"fmt"
+	positions "go/token"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/arneph/mercury/logic"
+)
+
+const (
+	ZeroWire int32 = iota
+	OneWire
+	firstWire
+)
+
+type Gate struct {
+	A, B, R int32
+	Tri     bool
+	Shared  bool
+}
+
+type Netlist struct {
+	Component   *logic.Component
+	Wires       int
+	Gates       []Gate
+	Inputs      []int32
+	Outputs     []int32
+	offsets     map[*logic.Bus]int32
+	fanout      []int32
+	fanoutStart []int32
+	drivers     []int32
+	driverStart []int32
+	shared      []int32
+	gatePos     []positions.Pos
+	names       []string
+	namesOnce   sync.Once
+}
+
+func Compile(c *logic.Component) *Netlist {
+	n := &Netlist{
+		Component: c,
+		Gates:     make([]Gate, 0, len(c.Instances)),
+		offsets:   make(map[*logic.Bus]int32, len(c.Buses)),
+	}
+	wires := firstWire
+	addBus := func(bus *logic.Bus) {
+		if _, ok := n.offsets[bus]; !ok {
+			n.offsets[bus] = wires
+			wires += int32(bus.Wires())
+		}
+	}
+	for _, names := range [][]string{c.InputBusNames, c.OutputBusNames} {
+		for _, name := range names {
+			addBus(c.Buses[name])
+		}
+	}
+	names := make([]string, 0, len(c.Buses))
+	for name := range c.Buses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		addBus(c.Buses[name])
+	}
+	n.Wires = int(wires)
+	for _, name := range c.InputBusNames {
+		n.Inputs = append(n.Inputs, n.busWires(c.Buses[name])...)
+	}
+	for _, name := range c.OutputBusNames {
+		n.Outputs = append(n.Outputs, n.busWires(c.Buses[name])...)
+	}
+	for _, instance := range c.Instances {
+		switch def := instance.Definition.(type) {
+		case logic.NandGate, logic.TriGate:
+			n.Gates = append(n.Gates, Gate{
+				A:   n.Wire(instance.Inputs[0]),
+				B:   n.Wire(instance.Inputs[1]),
+				R:   n.Wire(instance.Outputs[0]),
+				Tri: def == logic.Tri,
+			})
+			n.gatePos = append(n.gatePos, instance.Pos)
+		case *logic.Constants:
+			i := 0
+			for _, value := range def.Values() {
+				for _, v := range value {
+					constant := Gate{A: OneWire, B: OneWire, R: n.Wire(instance.Outputs[i])}
+					if v {
+						constant.A, constant.B = ZeroWire, ZeroWire
+					}
+					n.Gates = append(n.Gates, constant)
+					n.gatePos = append(n.gatePos, instance.Pos)
+					i++
+				}
+			}
+		default:
+			panic(fmt.Errorf("unexpected logic.Definition: %t", def))
+		}
+	}
+	n.buildFanout()
+	n.buildDrivers()
+	return n
+}
+
+func (n *Netlist) busWires(bus *logic.Bus) []int32 {
+	wires := make([]int32, bus.Wires())
+	for i := range wires {
+		wires[i] = n.offsets[bus] + int32(i)
+	}
+	return wires
+}
+
+func (n *Netlist) Wire(w logic.BusWire) int32 {
+	return n.offsets[w.Bus] + int32(w.WireIndex)
+}
+
+func (n *Netlist) buildFanout() {
+	n.fanoutStart = make([]int32, n.Wires+1)
+	for _, g := range n.Gates {
+		n.fanoutStart[g.A+1]++
+		if g.B != g.A {
+			n.fanoutStart[g.B+1]++
+		}
+	}
+	for w := 0; w < n.Wires; w++ {
+		n.fanoutStart[w+1] += n.fanoutStart[w]
+	}
+	n.fanout = make([]int32, n.fanoutStart[n.Wires])
+	next := append([]int32(nil), n.fanoutStart[:n.Wires]...)
+	for i, g := range n.Gates {
+		n.fanout[next[g.A]] = int32(i)
+		next[g.A]++
+		if g.B != g.A {
+			n.fanout[next[g.B]] = int32(i)
+			next[g.B]++
+		}
+	}
+}
+
+func (n *Netlist) Fanout(w int32) []int32 {
+	return n.fanout[n.fanoutStart[w]:n.fanoutStart[w+1]]
+}
+
+func (n *Netlist) buildDrivers() {
+	n.driverStart = make([]int32, n.Wires+1)
+	tri := make([]bool, n.Wires)
+	for _, g := range n.Gates {
+		n.driverStart[g.R+1]++
+		tri[g.R] = tri[g.R] || g.Tri
+	}
+	for w := 0; w < n.Wires; w++ {
+		if tri[w] || n.driverStart[w+1] > 1 {
+			n.shared = append(n.shared, int32(w))
+		}
+		n.driverStart[w+1] += n.driverStart[w]
+	}
+	n.drivers = make([]int32, n.driverStart[n.Wires])
+	next := append([]int32(nil), n.driverStart[:n.Wires]...)
+	for i, g := range n.Gates {
+		n.drivers[next[g.R]] = int32(i)
+		next[g.R]++
+	}
+	for _, w := range n.shared {
+		for _, i := range n.Drivers(w) {
+			n.Gates[i].Shared = true
+		}
+	}
+}
+
+func (n *Netlist) Drivers(w int32) []int32 {
+	return n.drivers[n.driverStart[w]:n.driverStart[w+1]]
+}
+
+func (n *Netlist) GatePos(i int32) positions.Pos {
+	return n.gatePos[i]
+}
+
+func (n *Netlist) WireName(w int32) string {
+	n.namesOnce.Do(func() {
+		n.names = make([]string, n.Wires)
+		for bus, offset := range n.offsets {
+			name := n.Component.HierarchicalName(bus.Name)
+			for i := 0; i < bus.Wires(); i++ {
+				n.names[offset+int32(i)] = name
+				if bus.Wires() > 1 {
+					n.names[offset+int32(i)] += "[" + strconv.Itoa(i) + "]"
+				}
+			}
+		}
+	})
+	return n.names[w]
+}
+
+func (n *Netlist) oscillationError(wires []int32, limit int) *OscillationError {
+	err := &OscillationError{Iterations: limit}
+	for _, w := range wires {
+		err.Wires = append(err.Wires, n.WireName(w))
+	}
+	return err
+}
+
+func (n *Netlist) conflictError(w int32, drivers []int32, levels []Level) *ConflictError {
+	err := &ConflictError{Wire: n.WireName(w)}
+	for i, driver := range drivers {
+		err.Drivers = append(err.Drivers, Driver{Pos: n.gatePos[driver], Level: levels[i]})
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package simulation
+
+import (
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+)
+
+func TestCompile(t *testing.T) {
+	a := logic.NewBus("a", 2)
+	r := logic.NewBus("r", 1)
+	k := logic.NewBus("k", 2)
+	i := logic.NewBus("i", 1)
+	c := logic.NewComponent("C", []*logic.Bus{a}, []*logic.Bus{r, k})
+	c.Buses["i"] = i
+	c.Instances = []*logic.Instance{
+		{
+			Definition: logic.Nand,
+			Inputs:     []logic.BusWire{{Bus: a, WireIndex: 0}, {Bus: a, WireIndex: 1}},
+			Outputs:    []logic.BusWire{{Bus: i}},
+		},
+		{
+			Definition: logic.Nand,
+			Inputs:     []logic.BusWire{{Bus: i}, {Bus: i}},
+			Outputs:    []logic.BusWire{{Bus: r}},
+		},
+		{
+			Definition: logic.NewConstants([]logic.Value{{true, false}}),
+			Outputs:    []logic.BusWire{{Bus: k, WireIndex: 0}, {Bus: k, WireIndex: 1}},
+		},
+	}
+	n := Compile(c)
+	if n.Wires != 8 {
+		t.Errorf("Wires = %d; want 8", n.Wires)
+	}
+	if len(n.Inputs) != 2 || n.Inputs[0] != 2 || n.Inputs[1] != 3 {
+		t.Errorf("Inputs = %v; want [2 3]", n.Inputs)
+	}
+	if len(n.Outputs) != 3 || n.Outputs[0] != 4 || n.Outputs[1] != 5 || n.Outputs[2] != 6 {
+		t.Errorf("Outputs = %v; want [4 5 6]", n.Outputs)
+	}
+	expected := []Gate{
+		{A: 2, B: 3, R: 7},
+		{A: 7, B: 7, R: 4},
+		{A: ZeroWire, B: ZeroWire, R: 5},
+		{A: OneWire, B: OneWire, R: 6},
+	}
+	if len(n.Gates) != len(expected) {
+		t.Fatalf("Gates = %v; want %v", n.Gates, expected)
+	}
+	for j := range expected {
+		if n.Gates[j] != expected[j] {
+			t.Errorf("Gates[%d] = %v; want %v", j, n.Gates[j], expected[j])
+		}
+	}
+	if fanout := n.Fanout(7); len(fanout) != 1 || fanout[0] != 1 {
+		t.Errorf("Fanout(i) = %v; want [1]", fanout)
+	}
+	if fanout := n.Fanout(OneWire); len(fanout) != 1 || fanout[0] != 3 {
+		t.Errorf("Fanout(OneWire) = %v; want [3]", fanout)
+	}
+	s := newStateForNetlist(t, n)
+	setInputs(t, s, logic.NewConstants([]logic.Value{{true, true}}))
+	outputs := s.Outputs().Values()
+	if !outputs[0][0] || !outputs[1][0] || outputs[1][1] {
+		t.Errorf("Outputs() = %v; want 1, 1", s.Outputs())
+	}
+}
+
+func TestNetlistIsShared(t *testing.T) {
+	c := buildComponent(t, src, "Add1")
+	r := NewRunner(RunOptions{})
+	if r.netlist(c) != r.netlist(c) {
+		t.Errorf("Runner compiled the same component twice")
+	}
+	if NewRunner(RunOptions{}).netlist(c) == r.netlist(c) {
+		t.Errorf("Runners share compiled netlists")
+	}
+}
+
+func TestCompileSharedWires(t *testing.T) {
+	n := Compile(buildComponent(t, src, "Mux"))
+	r := n.Outputs[0]
+	drivers := n.Drivers(r)
+	if len(drivers) != 2 {
+		t.Fatalf("Drivers(r) = %v; want 2 drivers", drivers)
+	}
+	for _, i := range drivers {
+		if g := n.Gates[i]; !g.Tri || !g.Shared || g.R != r {
+			t.Errorf("Gates[%d] = %+v; want shared tri gate driving r", i, g)
+		}
+	}
+	for i, g := range n.Gates {
+		if !g.Tri && g.Shared {
+			t.Errorf("Gates[%d] = %+v; want unshared nand gate", i, g)
+		}
+	}
+}
@@ -1,70 +1,181 @@
 package simulation
 
-import "github.com/arneph/mercury/logic"
+import (
+	"fmt"
+	positions "go/token"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+const DefaultSettleLimit = 10000
+
+type Options struct {
+	SettleLimit int
+	FourValued  bool
+}
+
+func (o Options) settleLimit() int {
+	if o.SettleLimit > 0 {
+		return o.SettleLimit
+	}
+	return DefaultSettleLimit
+}
+
+type OscillationError struct {
+	Iterations int
+	Wires      []string
+}
+
+func (e *OscillationError) Error() string {
+	const shown = 8
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "did not settle after %d iterations; oscillating wires: ", e.Iterations)
+	for i, wire := range e.Wires {
+		if i == shown {
+			fmt.Fprintf(&sb, ", and %d more", len(e.Wires)-shown)
+			break
+		} else if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(wire)
+	}
+	return sb.String()
+}
+
+type Driver struct {
+	Pos   positions.Pos
+	Level Level
+}
+
+type ConflictError struct {
+	Wire    string
+	Drivers []Driver
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflicting drivers on wire %s", e.Wire)
+}
 
 type ComponentState struct {
 	Component *logic.Component
-	BusStates map[*logic.Bus]logic.Value
+	netlist   *Netlist
+	kernel    *kernel
+	limit     int
+	trace     *VCDWriter
+}
+
+func NewComponentState(c *logic.Component) (*ComponentState, error) {
+	return NewComponentStateForNetlist(Compile(c))
+}
+
+func NewComponentStateForNetlist(n *Netlist) (*ComponentState, error) {
+	return NewComponentStateWithOptions(n, Options{})
 }
 
-func NewComponentState(c *logic.Component) *ComponentState {
-	busStates := make(map[*logic.Bus]logic.Value, len(c.Buses))
-	for _, bus := range c.Buses {
-		busStates[bus] = make(logic.Value, bus.Wires())
+func NewComponentStateWithOptions(n *Netlist, opts Options) (*ComponentState, error) {
+	k := newKernel
+	if opts.FourValued {
+		k = newFourValuedKernel
 	}
 	s := &ComponentState{
-		Component: c,
-		BusStates: busStates,
+		Component: n.Component,
+		netlist:   n,
+		kernel:    k(n),
+		limit:     opts.settleLimit(),
 	}
-	s.simulateUntilStable()
-	return s
+	if err := s.simulateUntilStable(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *ComponentState) FourValued() bool {
+	return s.kernel.unknown != nil
+}
+
+func (s *ComponentState) Trace(v *VCDWriter) {
+	s.trace = v
+	v.next(s.kernel.level)
+}
+
+func (s *ComponentState) BusStates() map[*logic.Bus]logic.Value {
+	states := make(map[*logic.Bus]logic.Value, len(s.Component.Buses))
+	for _, bus := range s.Component.Buses {
+		states[bus] = s.BusState(bus)
+	}
+	return states
+}
+
+func (s *ComponentState) BusState(bus *logic.Bus) logic.Value {
+	return s.BusLevels(bus).Value()
 }
 
-func (s *ComponentState) Inputs() logic.Constants {
-	vals := make([]logic.Value, len(s.Component.InputBusNames))
-	for i, name := range s.Component.InputBusNames {
-		bus := s.Component.Buses[name]
-		vals[i] = s.BusStates[bus]
+func (s *ComponentState) BusLevels(bus *logic.Bus) Levels {
+	levels := make(Levels, bus.Wires())
+	for i := range levels {
+		levels[i] = s.kernel.level(s.netlist.Wire(logic.BusWire{Bus: bus, WireIndex: logic.WireIndex(i)}))
 	}
-	return *logic.NewConstants(vals)
+	return levels
+}
+
+func (s *ComponentState) Inputs() *logic.Constants {
+	return s.group(s.Component.InputBusNames, s.netlist.Inputs)
 }
 
-func (s *ComponentState) SetInputs(c *logic.Constants) {
-	for i, name := range s.Component.InputBusNames {
-		bus := s.Component.Buses[name]
-		s.BusStates[bus] = c.Values()[i]
+func (s *ComponentState) SetInputs(c *logic.Constants) error {
+	w := 0
+	for _, value := range c.Values() {
+		for _, v := range value {
+			s.kernel.set(s.netlist.Inputs[w], v)
+			w++
+		}
+	}
+	err := s.simulateUntilStable()
+	if s.trace != nil {
+		s.trace.next(s.kernel.level)
+	}
+	if err != nil {
+		return err
+	} else if err := s.kernel.conflict(); err != nil {
+		return err
 	}
-	s.simulateUntilStable()
+	return nil
 }
 
 func (s *ComponentState) Outputs() *logic.Constants {
-	vals := make([]logic.Value, len(s.Component.OutputBusNames))
-	for i, name := range s.Component.OutputBusNames {
-		bus := s.Component.Buses[name]
-		vals[i] = s.BusStates[bus]
+	return s.group(s.Component.OutputBusNames, s.netlist.Outputs)
+}
+
+func (s *ComponentState) OutputLevels() []Levels {
+	return s.groupLevels(s.Component.OutputBusNames, s.netlist.Outputs)
+}
+
+func (s *ComponentState) group(busNames []string, wires []int32) *logic.Constants {
+	levels := s.groupLevels(busNames, wires)
+	vals := make([]logic.Value, len(levels))
+	for i, l := range levels {
+		vals[i] = l.Value()
 	}
 	return logic.NewConstants(vals)
 }
 
-func (s *ComponentState) simulateUntilStable() {
-	for {
-		stable := true
-		for _, instance := range s.Component.Instances {
-			_ = instance.Definition.(logic.NandGate)
-			aWire := instance.Inputs[0]
-			aState := s.BusStates[aWire.Bus][aWire.WireIndex]
-			bWire := instance.Inputs[1]
-			bState := s.BusStates[bWire.Bus][bWire.WireIndex]
-			rWire := instance.Outputs[0]
-			oldRState := s.BusStates[rWire.Bus][rWire.WireIndex]
-			newRState := !(aState && bState)
-			s.BusStates[rWire.Bus][rWire.WireIndex] = newRState
-			if oldRState != newRState {
-				stable = false
-			}
-		}
-		if stable {
-			break
+func (s *ComponentState) groupLevels(busNames []string, wires []int32) []Levels {
+	levels := make([]Levels, len(busNames))
+	for i, name := range busNames {
+		levels[i] = make(Levels, s.Component.Buses[name].Wires())
+		for j := range levels[i] {
+			levels[i][j] = s.kernel.level(wires[0])
+			wires = wires[1:]
 		}
 	}
+	return levels
+}
+
+func (s *ComponentState) simulateUntilStable() error {
+	wires := s.kernel.settle(s.limit)
+	if wires == nil {
+		return nil
+	}
+	return s.netlist.oscillationError(wires, s.limit)
 }
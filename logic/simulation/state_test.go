@@ -0,0 +1,575 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+const src = logictest.Gates + logictest.Add1 + logictest.Memory1 + `
+component Ring(en)(r) {
+    n1: nand(en, n3)
+    n2: Not(n1)
+    n3: Not(n2)
+    r: Not(n3)
+}
+
+component Blinker(en)(r) {
+    r: Ring(en)
+}
+
+component Float(a)(r) {
+    define f
+    r: nand(a, f)
+}
+
+component Mux(sel, a, b)(r) {
+    'sel: Not(sel)
+    r: tri('sel, a)
+    r: tri(sel, b)
+}
+
+component Clash(ea, eb, a, b)(r) {
+    r: tri(ea, a)
+    r: tri(eb, b)
+}
+`
+
+func adderSource(bits int) string {
+	return src + fmt.Sprintf(`
+component Add%[1]d(a[%[1]d], b[%[1]d], ci)(r[%[1]d], co) {
+    define c[%[1]d]
+    r[0], c[0]: Add1(a[0], b[0], ci)
+    for i from 1 to %[2]d {
+        r[i], c[i]: Add1(a[i], b[i], c[i-1])
+    }
+    co: Or(c[%[2]d], c[%[2]d])
+}
+
+component Memory%[1]d(s[%[1]d], r)(q[%[1]d]) {
+    define 'q[%[1]d]
+    for i from 0 to %[2]d {
+        q[i], 'q[i]: Memory1(s[i], r)
+    }
+}
+`, bits, bits-1)
+}
+
+func buildComponent(tb testing.TB, src, name string) *logic.Component {
+	c := logictest.Build(tb, src).Components[name]
+	return c.Collapse(c.Name())
+}
+
+type sweepState struct {
+	c      *logic.Component
+	states map[*logic.Bus]logic.Value
+}
+
+func newSweepState(c *logic.Component) *sweepState {
+	s := &sweepState{c: c, states: make(map[*logic.Bus]logic.Value)}
+	for _, bus := range c.Buses {
+		s.states[bus] = make(logic.Value, bus.Wires())
+	}
+	s.sweep()
+	return s
+}
+
+func (s *sweepState) setInputs(inputs *logic.Constants) {
+	for i, name := range s.c.InputBusNames {
+		copy(s.states[s.c.Buses[name]], inputs.Values()[i])
+	}
+	s.sweep()
+}
+
+func (s *sweepState) sweep() {
+	for {
+		stable := true
+		for _, instance := range s.c.Instances {
+			var values []bool
+			switch def := instance.Definition.(type) {
+			case logic.NandGate:
+				a, b := instance.Inputs[0], instance.Inputs[1]
+				values = []bool{!(s.states[a.Bus][a.WireIndex] && s.states[b.Bus][b.WireIndex])}
+			case *logic.Constants:
+				for _, value := range def.Values() {
+					values = append(values, value...)
+				}
+			}
+			for i, r := range instance.Outputs {
+				if s.states[r.Bus][r.WireIndex] != values[i] {
+					s.states[r.Bus][r.WireIndex] = values[i]
+					stable = false
+				}
+			}
+		}
+		if stable {
+			return
+		}
+	}
+}
+
+func randomInputs(rng *rand.Rand, c *logic.Component) *logic.Constants {
+	values := make([]logic.Value, len(c.InputBusNames))
+	for i, name := range c.InputBusNames {
+		values[i] = make(logic.Value, c.Buses[name].Wires())
+		for j := range values[i] {
+			values[i][j] = rng.Intn(2) == 1
+		}
+	}
+	return logic.NewConstants(values)
+}
+
+func TestMatchesSweepSimulation(t *testing.T) {
+	src := adderSource(16)
+	rng := rand.New(rand.NewSource(1))
+	for _, name := range []string{"Add1", "Memory1", "Add16", "Memory16"} {
+		c := buildComponent(t, src, name)
+		expected := newSweepState(c)
+		actual := newState(t, c)
+		for step := 0; step < 200; step++ {
+			states := actual.BusStates()
+			for _, bus := range c.Buses {
+				if fmt.Sprint(expected.states[bus]) != fmt.Sprint(states[bus]) {
+					t.Fatalf("%s after %d steps: bus %s = %v; want %v", name, step, bus.Name, states[bus], expected.states[bus])
+				}
+			}
+			inputs := randomInputs(rng, c)
+			expected.setInputs(inputs)
+			setInputs(t, actual, inputs)
+		}
+	}
+}
+
+func TestAdderOutputs(t *testing.T) {
+	c := buildComponent(t, adderSource(64), "Add64")
+	s := newState(t, c)
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 100; i++ {
+		a, b := rng.Uint64(), rng.Uint64()
+		setInputs(t, s, logic.NewConstants(append(append(bitValues(a), bitValues(b)...), logic.Value{false})))
+		var r uint64
+		for j, value := range s.Outputs().Values()[:64] {
+			if value[0] {
+				r |= 1 << j
+			}
+		}
+		co := s.Outputs().Values()[64][0]
+		if r != a+b || co != (a+b < a) {
+			t.Fatalf("%d + %d = %d, %v; want %d, %v", a, b, r, co, a+b, a+b < a)
+		}
+	}
+}
+
+func bitValues(x uint64) []logic.Value {
+	values := make([]logic.Value, 64)
+	for i := range values {
+		values[i] = logic.Value{x>>i&1 == 1}
+	}
+	return values
+}
+
+func TestInputsAreCopied(t *testing.T) {
+	c := buildComponent(t, src, "Memory1")
+	s := newState(t, c)
+	inputs := logic.NewConstants([]logic.Value{{true}, {false}})
+	setInputs(t, s, inputs)
+	inputs.Values()[0][0] = false
+	if !s.Inputs().Values()[0][0] {
+		t.Errorf("SetInputs() kept a reference to the caller's values")
+	}
+	if q := s.Outputs().Values()[0][0]; !q {
+		t.Errorf("q = %v; want 1", q)
+	}
+}
+
+func TestFourValuedMemory(t *testing.T) {
+	c := buildComponent(t, src, "Memory1")
+	s, err := NewComponentStateWithOptions(Compile(c), Options{FourValued: true})
+	if err != nil {
+		t.Fatalf("NewComponentStateWithOptions() failed: %v", err)
+	}
+	if !s.FourValued() {
+		t.Errorf("FourValued() = false; want true")
+	}
+	for i, step := range []struct {
+		s, r bool
+		q    string
+	}{
+		{false, false, "X, X"},
+		{true, false, "1, 0"},
+		{false, false, "1, 0"},
+		{false, true, "0, 1"},
+	} {
+		setInputs(t, s, logic.NewConstants([]logic.Value{{step.s}, {step.r}}))
+		if actual := busLevels(s.OutputLevels()).String(); actual != step.q {
+			t.Errorf("step %d: outputs = %s; want %s", i, actual, step.q)
+		}
+	}
+}
+
+func TestFourValuedFloatingWire(t *testing.T) {
+	c := buildComponent(t, src, "Float")
+	s, err := NewComponentStateWithOptions(Compile(c), Options{FourValued: true})
+	if err != nil {
+		t.Fatalf("NewComponentStateWithOptions() failed: %v", err)
+	}
+	if f := s.BusLevels(c.Buses["f"]); f.String() != "Z" {
+		t.Errorf("f = %s; want Z", f)
+	}
+	for _, step := range []struct {
+		a bool
+		r string
+	}{{false, "1"}, {true, "X"}} {
+		setInputs(t, s, logic.NewConstants([]logic.Value{{step.a}}))
+		if r := s.BusLevels(c.Buses["r"]); r.String() != step.r {
+			t.Errorf("a = %v: r = %s; want %s", step.a, r, step.r)
+		}
+		if r := s.BusState(c.Buses["r"]); r[0] != (step.r == "1") {
+			t.Errorf("a = %v: BusState(r) = %v", step.a, r)
+		}
+	}
+}
+
+func TestFourValuedMatchesTwoValued(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	c := buildComponent(t, adderSource(16), "Add16")
+	two := newState(t, c)
+	four, err := NewComponentStateWithOptions(Compile(c), Options{FourValued: true})
+	if err != nil {
+		t.Fatalf("NewComponentStateWithOptions() failed: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		inputs := randomInputs(rng, c)
+		setInputs(t, two, inputs)
+		setInputs(t, four, inputs)
+		if e, a := two.Outputs().String(), busLevels(four.OutputLevels()).String(); e != a {
+			t.Fatalf("outputs for %v = %s; want %s", inputs, a, e)
+		}
+	}
+}
+
+func TestTriStateMux(t *testing.T) {
+	c := buildComponent(t, src, "Mux")
+	four, err := NewComponentStateWithOptions(Compile(c), Options{FourValued: true})
+	if err != nil {
+		t.Fatalf("NewComponentStateWithOptions() failed: %v", err)
+	}
+	for _, s := range []*ComponentState{newState(t, c), four} {
+		for m := 0; m < 8; m++ {
+			sel, a, b := m&1 == 1, m&2 == 2, m&4 == 4
+			setInputs(t, s, logic.NewConstants([]logic.Value{{sel}, {a}, {b}}))
+			expected := levelOf(a)
+			if sel {
+				expected = levelOf(b)
+			}
+			if r := s.OutputLevels()[0][0]; r != expected {
+				t.Errorf("four-valued %v: Mux(%v, %v, %v) = %v; want %v", s.FourValued(), sel, a, b, r, expected)
+			}
+		}
+	}
+}
+
+func TestTriStateFloating(t *testing.T) {
+	c := buildComponent(t, src, "Clash")
+	s, err := NewComponentStateWithOptions(Compile(c), Options{FourValued: true})
+	if err != nil {
+		t.Fatalf("NewComponentStateWithOptions() failed: %v", err)
+	}
+	for _, step := range []struct {
+		inputs []logic.Value
+		r      Level
+	}{
+		{[]logic.Value{{false}, {false}, {true}, {false}}, Z},
+		{[]logic.Value{{true}, {false}, {true}, {false}}, ONE},
+		{[]logic.Value{{false}, {true}, {true}, {false}}, ZERO},
+		{[]logic.Value{{true}, {true}, {true}, {true}}, ONE},
+	} {
+		setInputs(t, s, logic.NewConstants(step.inputs))
+		if r := s.OutputLevels()[0][0]; r != step.r {
+			t.Errorf("Clash(%v) = %v; want %v", step.inputs, r, step.r)
+		}
+	}
+}
+
+func TestTriStateConflict(t *testing.T) {
+	c := buildComponent(t, src, "Clash")
+	four, err := NewComponentStateWithOptions(Compile(c), Options{FourValued: true})
+	if err != nil {
+		t.Fatalf("NewComponentStateWithOptions() failed: %v", err)
+	}
+	for _, s := range []*ComponentState{newState(t, c), four} {
+		err := s.SetInputs(logic.NewConstants([]logic.Value{{true}, {true}, {true}, {false}}))
+		conflict, ok := err.(*ConflictError)
+		if !ok {
+			t.Fatalf("SetInputs() = %v; want *ConflictError", err)
+		}
+		if conflict.Wire != "r" || len(conflict.Drivers) != 2 {
+			t.Fatalf("conflict on %s with %d drivers; want r with 2", conflict.Wire, len(conflict.Drivers))
+		}
+		for i, level := range []Level{ONE, ZERO} {
+			if d := conflict.Drivers[i]; d.Level != level || !d.Pos.IsValid() {
+				t.Errorf("driver %d = %v at %v; want %v at a valid position", i, d.Level, d.Pos, level)
+			}
+		}
+	}
+}
+
+func TestOscillation(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Ring":    "r, n1, n2, n3",
+		"Blinker": "r, Ring_i1/n1, Ring_i1/n2, Ring_i1/n3",
+	} {
+		c := buildComponent(t, src, name)
+		s := newState(t, c)
+		err := s.SetInputs(logic.NewConstants([]logic.Value{{true}}))
+		oscillation, ok := err.(*OscillationError)
+		if !ok {
+			t.Fatalf("%s: SetInputs() = %v; want *OscillationError", name, err)
+		}
+		if actual := strings.Join(oscillation.Wires, ", "); actual != expected {
+			t.Errorf("%s: oscillating wires = %s; want %s", name, actual, expected)
+		}
+		if oscillation.Iterations != DefaultSettleLimit {
+			t.Errorf("%s: iterations = %d; want %d", name, oscillation.Iterations, DefaultSettleLimit)
+		}
+	}
+}
+
+func TestSettleLimit(t *testing.T) {
+	s, err := NewComponentStateWithOptions(Compile(buildComponent(t, src, "Ring")), Options{SettleLimit: 50})
+	if err != nil {
+		t.Fatalf("NewComponentStateWithOptions() failed: %v", err)
+	}
+	err = s.SetInputs(logic.NewConstants([]logic.Value{{true}}))
+	if oscillation, ok := err.(*OscillationError); !ok || oscillation.Iterations != 50 {
+		t.Errorf("SetInputs() = %v; want oscillation after 50 iterations", err)
+	}
+}
+
+func TestRunTestReportsOscillation(t *testing.T) {
+	testSrc := src + `
+test Blinker {
+    component: Blinker
+
+    set en: 0
+    expect r is 0
+    set en: 1
+    expect r is 0
+}
+`
+	system, file := logictest.BuildFile(t, "oscillation.mercury", testSrc)
+	outcome, errs := RunTest(system.Tests["Blinker"], file, RunOptions{})
+	if outcome != OSCILLATION || errs.Len() != 1 {
+		t.Fatalf("RunTest() = %v, %v; want a single oscillation", outcome, errs)
+	}
+	if line := errs[0].Pos.Line; line != file.Line(file.Pos(strings.Index(testSrc, "set en: 1"))) {
+		t.Errorf("oscillation reported at line %d", line)
+	}
+}
+
+func TestRunTestFailsOnX(t *testing.T) {
+	testSrc := src + `
+test Memory1 {
+    component: Memory1
+
+    set s, r: 0, 0
+    expect q, 'q is 1, 0
+    set s, r: 0, 1
+    expect q, 'q is 0, 1
+}
+`
+	system, file := logictest.BuildFile(t, "x.mercury", testSrc)
+	if outcome, errs := RunTest(system.Tests["Memory1"], file, RunOptions{}); outcome != PASS || errs.Len() != 0 {
+		t.Errorf("two-valued RunTest() = %v, %v; want no errors", outcome, errs)
+	}
+	outcome, errs := RunTest(system.Tests["Memory1"], file, RunOptions{Options: Options{FourValued: true}})
+	if outcome != FAIL || errs.Len() != 1 || !strings.Contains(errs[0].Msg, "got X") {
+		t.Errorf("four-valued RunTest() = %v, %v; want a single failure on X", outcome, errs)
+	}
+}
+
+func TestRunTestReportsConflict(t *testing.T) {
+	testSrc := src + `
+test Clash {
+    component: Clash
+
+    set ea, eb, a, b: 1, 0, 1, 0
+    expect r is 1
+    set ea, eb, a, b: 1, 1, 1, 0
+    expect r is 1
+}
+`
+	system, file := logictest.BuildFile(t, "conflict.mercury", testSrc)
+	outcome, errs := RunTest(system.Tests["Clash"], file, RunOptions{})
+	if outcome != CONFLICT || errs.Len() != 1 {
+		t.Fatalf("RunTest() = %v, %v; want a single conflict", outcome, errs)
+	}
+	driver := file.Position(file.Pos(strings.Index(testSrc, "r: tri(eb, b)"))).String()
+	if !strings.Contains(errs[0].Msg, driver+" drives 0") {
+		t.Errorf("conflict message %q does not name driver at %s", errs[0].Msg, driver)
+	}
+}
+
+func TestComponentStatesShareNetlist(t *testing.T) {
+	c := buildComponent(t, adderSource(16), "Add16")
+	n := Compile(c)
+	inputs := make([]*logic.Constants, 100)
+	expected := make([]string, len(inputs))
+	rng := rand.New(rand.NewSource(4))
+	s := newStateForNetlist(t, n)
+	for i := range inputs {
+		inputs[i] = randomInputs(rng, c)
+		setInputs(t, s, inputs[i])
+		expected[i] = fmt.Sprint(s.Outputs())
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := NewComponentStateForNetlist(n)
+			if err != nil {
+				t.Errorf("NewComponentStateForNetlist() failed: %v", err)
+				return
+			}
+			for i, input := range inputs {
+				if err := s.SetInputs(input); err != nil {
+					t.Errorf("SetInputs() failed: %v", err)
+					return
+				}
+				if actual := fmt.Sprint(s.Outputs()); actual != expected[i] {
+					t.Errorf("goroutine %d, step %d: outputs = %s; want %s", g, i, actual, expected[i])
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRunTestConcurrently(t *testing.T) {
+	r := NewRunner(RunOptions{VCDDir: t.TempDir()})
+	testSrc := src + `
+test Xor {
+    component: Xor
+
+    set a, b: 1, 0
+    expect r is 1
+    set a, b: 1, 1
+    expect r is 0
+}
+
+test Memory1 {
+    component: Memory1
+
+    set s, r: 1, 0
+    expect q, 'q is 1, 0
+    set s, r: 0, 0
+    expect q, 'q is 0, 1
+}
+
+test Blinker {
+    component: Blinker
+
+    set en: 1
+    expect r is 0
+}
+`
+	system, file := logictest.BuildFile(t, "concurrent.mercury", testSrc)
+	expected := map[string]string{
+		"Xor":     "no errors",
+		"Memory1": file.Position(file.Pos(strings.Index(testSrc, "expect q, 'q is 0, 1"))).String() + ": expect failed: expected 0, 1, got 1, 0",
+		"Blinker": "",
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		for name := range expected {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				outcome, errs := r.RunTest(system.Tests[name], file)
+				if name == "Blinker" {
+					if outcome != OSCILLATION {
+						t.Errorf("%s: RunTest() = %v, %v; want an oscillation", name, outcome, errs)
+					}
+				} else if actual := fmt.Sprint(errs); actual != expected[name] {
+					t.Errorf("%s: RunTest() = %s; want %s", name, actual, expected[name])
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	if len(r.netlists) != len(expected) {
+		t.Errorf("Runner compiled %d netlists; want %d", len(r.netlists), len(expected))
+	}
+}
+
+func benchmarkSetInputs(b *testing.B, name string, bits int) {
+	c := buildComponent(b, adderSource(bits), fmt.Sprintf("%s%d", name, bits))
+	rng := rand.New(rand.NewSource(3))
+	inputs := make([]*logic.Constants, 64)
+	for i := range inputs {
+		inputs[i] = randomInputs(rng, c)
+	}
+	s := newState(b, c)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		setInputs(b, s, inputs[i%len(inputs)])
+	}
+}
+
+func benchmarkSweep(b *testing.B, name string, bits int) {
+	c := buildComponent(b, adderSource(bits), fmt.Sprintf("%s%d", name, bits))
+	rng := rand.New(rand.NewSource(3))
+	inputs := make([]*logic.Constants, 64)
+	for i := range inputs {
+		inputs[i] = randomInputs(rng, c)
+	}
+	s := newSweepState(c)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.setInputs(inputs[i%len(inputs)])
+	}
+}
+
+func BenchmarkSetInputsAdd64(b *testing.B)     { benchmarkSetInputs(b, "Add", 64) }
+func BenchmarkSetInputsAdd256(b *testing.B)    { benchmarkSetInputs(b, "Add", 256) }
+func BenchmarkSetInputsAdd1024(b *testing.B)   { benchmarkSetInputs(b, "Add", 1024) }
+func BenchmarkSetInputsMemory64(b *testing.B)  { benchmarkSetInputs(b, "Memory", 64) }
+func BenchmarkSetInputsMemory256(b *testing.B) { benchmarkSetInputs(b, "Memory", 256) }
+func BenchmarkSweepAdd64(b *testing.B)         { benchmarkSweep(b, "Add", 64) }
+func BenchmarkSweepAdd256(b *testing.B)        { benchmarkSweep(b, "Add", 256) }
+func BenchmarkSweepMemory64(b *testing.B)      { benchmarkSweep(b, "Memory", 64) }
+
+func BenchmarkSetInputsAdd8192(b *testing.B) { benchmarkSetInputs(b, "Add", 8192) }
+func BenchmarkSweepAdd8192(b *testing.B)     { benchmarkSweep(b, "Add", 8192) }
+
+func newState(tb testing.TB, c *logic.Component) *ComponentState {
+	tb.Helper()
+	s, err := NewComponentState(c)
+	if err != nil {
+		tb.Fatalf("NewComponentState() failed: %v", err)
+	}
+	return s
+}
+
+func newStateForNetlist(tb testing.TB, n *Netlist) *ComponentState {
+	tb.Helper()
+	s, err := NewComponentStateForNetlist(n)
+	if err != nil {
+		tb.Fatalf("NewComponentStateForNetlist() failed: %v", err)
+	}
+	return s
+}
+
+func setInputs(tb testing.TB, s *ComponentState, inputs *logic.Constants) {
+	tb.Helper()
+	if err := s.SetInputs(inputs); err != nil {
+		tb.Fatalf("SetInputs() failed: %v", err)
+	}
+}
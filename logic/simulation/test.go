@@ -2,45 +2,263 @@ package simulation
 
 import (
 	"fmt"
+	"io"
+	"strings"
+	"sync"
 
 	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/equiv"
+	"github.com/arneph/mercury/logic/vectors"
 
 	errors "go/scanner"
 	positions "go/token"
 )
 
-func RunTest(test *logic.Test, posFile *positions.File) (errs errors.ErrorList) {
-	c := test.Component.Collapse(test.Component.Name())
-	state := NewComponentState(c)
+type RunOptions struct {
+	Options
+	VCDDir      string
+	VCDInternal bool
+}
+
+type Outcome int
+
+const (
+	PASS Outcome = iota
+	FAIL
+	OSCILLATION
+	CONFLICT
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case PASS:
+		return "PASS"
+	case FAIL:
+		return "FAIL"
+	case OSCILLATION:
+		return "OSCILLATION"
+	case CONFLICT:
+		return "CONFLICT"
+	default:
+		panic(fmt.Errorf("unexpected simulation.Outcome: %d", o))
+	}
+}
+
+func describeFailure(err error, context string, posFile *positions.File) (Outcome, string) {
+	if context != "" {
+		context += ": "
+	}
+	switch err := err.(type) {
+	case *OscillationError:
+		return OSCILLATION, "oscillation: " + context + err.Error()
+	case *ConflictError:
+		var sb strings.Builder
+		sb.WriteString("conflict: " + context + err.Error())
+		for i, driver := range err.Drivers {
+			if i == 0 {
+				sb.WriteString(": ")
+			} else {
+				sb.WriteString(", ")
+			}
+			if posFile != nil && driver.Pos.IsValid() {
+				fmt.Fprintf(&sb, "%v drives %v", posFile.Position(driver.Pos), driver.Level)
+			} else {
+				fmt.Fprintf(&sb, "driver drives %v", driver.Level)
+			}
+		}
+		return CONFLICT, sb.String()
+	default:
+		panic(fmt.Errorf("unexpected simulation failure: %t", err))
+	}
+}
+
+type Runner struct {
+	RunOptions
+	mu       sync.Mutex
+	netlists map[*logic.Component]*cachedNetlist
+}
+
+type cachedNetlist struct {
+	once    sync.Once
+	netlist *Netlist
+}
+
+func NewRunner(opts RunOptions) *Runner {
+	return &Runner{
+		RunOptions: opts,
+		netlists:   make(map[*logic.Component]*cachedNetlist),
+	}
+}
+
+func (r *Runner) netlist(c *logic.Component) *Netlist {
+	r.mu.Lock()
+	cached, ok := r.netlists[c]
+	if !ok {
+		cached = &cachedNetlist{}
+		r.netlists[c] = cached
+	}
+	r.mu.Unlock()
+	cached.once.Do(func() {
+		cached.netlist = Compile(c.Collapse(c.Name()))
+	})
+	return cached.netlist
+}
+
+func RunTest(test *logic.Test, posFile *positions.File, opts RunOptions) (Outcome, errors.ErrorList) {
+	return NewRunner(opts).RunTest(test, posFile)
+}
+
+func (r *Runner) RunTest(test *logic.Test, posFile *positions.File) (outcome Outcome, errs errors.ErrorList) {
+	var state *ComponentState
+	var trace *VCDWriter
+	if test.Component != nil {
+		n := r.netlist(test.Component)
+		var err error
+		state, err = NewComponentStateWithOptions(n, r.Options)
+		if err != nil {
+			kind, msg := describeFailure(err, "in initial state", posFile)
+			errs.Add(posFile.Position(test.Pos()), msg)
+			return kind, errs
+		}
+		if r.VCDDir != "" {
+			var closeTrace func() error
+			trace, closeTrace, err = createVCD(test, n, r.VCDDir, r.VCDInternal)
+			if err != nil {
+				errs.Add(posFile.Position(test.Pos()), fmt.Sprintf("could not create VCD file: %v", err))
+				return FAIL, errs
+			}
+			defer func() {
+				if err := closeTrace(); err != nil {
+					errs.Add(posFile.Position(test.Pos()), fmt.Sprintf("could not write VCD file: %v", err))
+					if outcome == PASS {
+						outcome = FAIL
+					}
+				}
+			}()
+			state.Trace(trace)
+		}
+	}
 	for _, step := range test.Steps {
 		switch step := step.(type) {
 		case *logic.SetInputs:
-			state.SetInputs(collapseInput(step.Inputs))
+			if err := state.SetInputs(collapseInput(step.Inputs)); err != nil {
+				kind, msg := describeFailure(err, "", posFile)
+				errs.Add(posFile.Position(step.Pos()), msg)
+				return kind, errs
+			}
 		case *logic.CheckOutputs:
 			expected := step.Outputs
-			actual := groupOutput(state.Outputs(), test.Component)
-			match := true
-			for i, as := range actual.Values() {
-				es := expected.Values()[i]
-				for j, a := range as {
-					e := es[j]
-					if a != e {
-						match = false
-					}
-				}
-			}
-			if match {
+			actual := groupOutputLevels(state.OutputLevels(), test.Component)
+			if matches(expected.Values(), actual) {
 				continue
 			}
-			errs.Add(posFile.Position(step.Pos()), fmt.Sprintf("%v failed: expected %v, got %v", step.Kind, expected, actual))
+			msg := fmt.Sprintf("%v failed: expected %v, got %v", step.Kind, expected, actual)
+			errs.Add(posFile.Position(step.Pos()), msg)
+			if trace != nil {
+				trace.fail(msg)
+			}
 			if step.Kind == logic.ASSERT {
-				return
+				return FAIL, errs
+			}
+		case *logic.ApplyVectors:
+			if kind := applyVectors(step, state, trace, test.Component, posFile, &errs); kind != PASS {
+				return kind, errs
+			}
+		case *logic.CheckEquivalence:
+			counterexample, err := equiv.Check(step.A, step.B)
+			if err != nil {
+				errs.Add(posFile.Position(step.Pos()), fmt.Sprintf("equiv failed: %v", err))
+			} else if counterexample != nil {
+				errs.Add(posFile.Position(step.Pos()), fmt.Sprintf("equiv failed: %v", counterexample))
 			}
 		default:
 			panic(fmt.Errorf("unexpected logic.TestStep: %t", step))
 		}
 	}
-	return
+	if errs.Len() > 0 {
+		return FAIL, errs
+	}
+	return PASS, errs
+}
+
+func applyVectors(step *logic.ApplyVectors, state *ComponentState, trace *VCDWriter, c *logic.Component, posFile *positions.File, errs *errors.ErrorList) Outcome {
+	r, err := vectors.Open(step.Path, c)
+	if err != nil {
+		errs.Add(posFile.Position(step.Pos()), fmt.Sprintf("could not open vectors file: %v", err))
+		return FAIL
+	}
+	defer r.Close()
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			return PASS
+		} else if err != nil {
+			errs.Add(posFile.Position(step.Pos()), fmt.Sprintf("could not read vectors file %s: %v", step.Path, err))
+			return FAIL
+		}
+		inputs := groupInput(state.Inputs(), c).Values()
+		for i, p := range row.Inputs {
+			for j := range inputs[i] {
+				if p.Specified(j) {
+					inputs[i][j] = p.Value[j]
+				}
+			}
+		}
+		if err := state.SetInputs(collapseInput(logic.NewConstants(inputs))); err != nil {
+			kind, msg := describeFailure(err, fmt.Sprintf("at %s:%d", step.Path, row.Line), posFile)
+			errs.Add(posFile.Position(step.Pos()), msg)
+			return kind
+		}
+		actual := groupOutputLevels(state.OutputLevels(), c)
+		if matchesPatterns(row.Outputs, actual) {
+			continue
+		}
+		msg := fmt.Sprintf("vectors failed at %s:%d: expected %s, got %v", step.Path, row.Line, formatPatterns(row.Outputs), actual)
+		errs.Add(posFile.Position(step.Pos()), msg)
+		if trace != nil {
+			trace.fail(msg)
+		}
+	}
+}
+
+func matches(expected []logic.Value, actual busLevels) bool {
+	for i, as := range actual {
+		es := expected[i]
+		if es == nil {
+			continue
+		}
+		for j, a := range as {
+			e := es[j]
+			if !a.Known() || (a == ONE) != e {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchesPatterns(expected []vectors.Pattern, actual busLevels) bool {
+	for i, as := range actual {
+		for j, a := range as {
+			if !expected[i].Specified(j) {
+				continue
+			} else if !a.Known() || (a == ONE) != expected[i].Value[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func formatPatterns(patterns []vectors.Pattern) string {
+	var sb strings.Builder
+	for i, p := range patterns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(p.String())
+	}
+	return sb.String()
 }
 
 func collapseInput(input *logic.Constants) *logic.Constants {
@@ -53,15 +271,48 @@ func collapseInput(input *logic.Constants) *logic.Constants {
 	return logic.NewConstants(vals)
 }
 
+func groupInput(input *logic.Constants, c *logic.Component) *logic.Constants {
+	return group(input, c, c.InputBusNames)
+}
+
 func groupOutput(output *logic.Constants, c *logic.Component) *logic.Constants {
-	vals := make([]logic.Value, len(c.OutputBusNames))
-	outputIndex := 0
+	return group(output, c, c.OutputBusNames)
+}
+
+type busLevels []Levels
+
+func (bl busLevels) String() string {
+	var sb strings.Builder
+	for i, levels := range bl {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(levels.String())
+	}
+	return sb.String()
+}
+
+func groupOutputLevels(output []Levels, c *logic.Component) busLevels {
+	levels := make(busLevels, len(c.OutputBusNames))
 	for i, name := range c.OutputBusNames {
+		levels[i] = make(Levels, c.Buses[name].Wires())
+		for j := range levels[i] {
+			levels[i][j] = output[0][0]
+			output = output[1:]
+		}
+	}
+	return levels
+}
+
+func group(collapsed *logic.Constants, c *logic.Component, busNames []string) *logic.Constants {
+	vals := make([]logic.Value, len(busNames))
+	collapsedIndex := 0
+	for i, name := range busNames {
 		bus := c.Buses[name]
 		val := make(logic.Value, bus.Wires())
 		for j := 0; j < bus.Wires(); j++ {
-			val[j] = output.Values()[outputIndex][0]
-			outputIndex++
+			val[j] = collapsed.Values()[collapsedIndex][0]
+			collapsedIndex++
 		}
 		vals[i] = val
 	}
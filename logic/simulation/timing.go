@@ -0,0 +1,329 @@
+package simulation
+
+import (
+	"container/heap"
+	"fmt"
+	positions "go/token"
+	"slices"
+
+	"github.com/arneph/mercury/logic"
+)
+
+const DefaultDelay = 1
+
+type Transition struct {
+	Time  int
+	Wire  int32
+	Value bool
+}
+
+type TimedState struct {
+	Component *logic.Component
+	netlist   *Netlist
+	delays    []int
+	values    []bool
+	contrib   []Level
+	time      int
+	limit     int
+	events    eventQueue
+	seq       int
+	trace     *VCDWriter
+}
+
+func NewTimedState(n *Netlist, delays []int, opts Options) (*TimedState, error) {
+	if opts.FourValued {
+		return nil, fmt.Errorf("timing simulation does not support four-valued mode")
+	} else if delays == nil {
+		delays = make([]int, len(n.Gates))
+		for i := range delays {
+			delays[i] = DefaultDelay
+		}
+	} else if len(delays) != len(n.Gates) {
+		return nil, fmt.Errorf("expected %d gate delays, got %d", len(n.Gates), len(delays))
+	}
+	for i, d := range delays {
+		if d < 1 {
+			return nil, fmt.Errorf("gate %d has non-positive delay %d", i, d)
+		}
+	}
+	limit := opts.settleLimit()
+	k := newKernel(n)
+	if wires := k.settle(limit); wires != nil {
+		return nil, n.oscillationError(wires, limit)
+	}
+	s := &TimedState{
+		Component: n.Component,
+		netlist:   n,
+		delays:    delays,
+		limit:     max(limit, len(n.Gates)) * slices.Max(append([]int{DefaultDelay}, delays...)),
+		values:    make([]bool, n.Wires),
+		contrib:   make([]Level, len(n.Gates)),
+	}
+	for w := range s.values {
+		s.values[w] = k.get(int32(w))
+	}
+	for i, g := range n.Gates {
+		s.contrib[i] = k.drive(g)
+	}
+	return s, nil
+}
+
+func (s *TimedState) Netlist() *Netlist {
+	return s.netlist
+}
+
+func (s *TimedState) Trace(v *VCDWriter) {
+	s.trace = v
+	v.dump(s.time, s.level)
+}
+
+func (s *TimedState) level(w int32) Level {
+	return levelOf(s.values[w])
+}
+
+func (s *TimedState) Time() int {
+	return s.time
+}
+
+func (s *TimedState) Outputs() *logic.Constants {
+	vals := make([]logic.Value, len(s.Component.OutputBusNames))
+	w := 0
+	for i, name := range s.Component.OutputBusNames {
+		vals[i] = make(logic.Value, s.Component.Buses[name].Wires())
+		for j := range vals[i] {
+			vals[i][j] = s.values[s.netlist.Outputs[w]]
+			w++
+		}
+	}
+	return logic.NewConstants(vals)
+}
+
+func (s *TimedState) SetInputs(c *logic.Constants) ([]Transition, error) {
+	var transitions []Transition
+	var changed []int32
+	w := 0
+	for _, value := range c.Values() {
+		for _, v := range value {
+			input := s.netlist.Inputs[w]
+			if s.values[input] != v {
+				s.values[input] = v
+				transitions = append(transitions, Transition{Time: s.time, Wire: input, Value: v})
+				changed = append(changed, input)
+			}
+			w++
+		}
+	}
+	start := s.time
+	for {
+		if s.trace != nil {
+			s.trace.dump(s.time, s.level)
+		}
+		s.schedule(changed)
+		if s.events.Len() == 0 {
+			break
+		}
+		s.time = s.events[0].time
+		if s.time-start > s.limit {
+			return transitions, s.oscillationError(transitions)
+		}
+		changed = changed[:0]
+		for s.events.Len() > 0 && s.events[0].time == s.time {
+			e := heap.Pop(&s.events).(event)
+			s.contrib[e.gate] = e.level
+			r := s.netlist.Gates[e.gate].R
+			v := s.resolve(r)
+			if s.values[r] != v {
+				s.values[r] = v
+				transitions = append(transitions, Transition{Time: s.time, Wire: r, Value: v})
+				changed = append(changed, r)
+			}
+		}
+	}
+	if err := s.conflict(); err != nil {
+		return transitions, err
+	}
+	return transitions, nil
+}
+
+func (s *TimedState) schedule(changed []int32) {
+	var gates []int32
+	for _, w := range changed {
+		gates = append(gates, s.netlist.Fanout(w)...)
+	}
+	slices.Sort(gates)
+	for _, i := range slices.Compact(gates) {
+		s.seq++
+		heap.Push(&s.events, event{
+			time:  s.time + s.delays[i],
+			seq:   s.seq,
+			gate:  i,
+			level: s.drive(s.netlist.Gates[i]),
+		})
+	}
+}
+
+func (s *TimedState) drive(g Gate) Level {
+	a, b := levelOf(s.values[g.A]), levelOf(s.values[g.B])
+	if !g.Tri {
+		return nandLevel(a, b)
+	} else if a == ZERO {
+		return Z
+	}
+	return b
+}
+
+func (s *TimedState) resolve(w int32) bool {
+	r := Z
+	for _, i := range s.netlist.Drivers(w) {
+		switch l := s.contrib[i]; {
+		case l == Z:
+		case r == Z:
+			r = l
+		case r != l:
+			r = X
+		}
+	}
+	return r == ONE
+}
+
+func (s *TimedState) conflict() *ConflictError {
+	for _, w := range s.netlist.shared {
+		var drivers []int32
+		var levels []Level
+		zero, one := false, false
+		for _, i := range s.netlist.Drivers(w) {
+			if l := s.contrib[i]; l.Known() {
+				drivers = append(drivers, i)
+				levels = append(levels, l)
+				zero = zero || l == ZERO
+				one = one || l == ONE
+			}
+		}
+		if zero && one {
+			return s.netlist.conflictError(w, drivers, levels)
+		}
+	}
+	return nil
+}
+
+func (s *TimedState) oscillationError(transitions []Transition) *OscillationError {
+	seen := make(map[int32]bool)
+	var wires []int32
+	for _, t := range transitions {
+		if t.Time > s.time-s.limit/2 && !seen[t.Wire] {
+			seen[t.Wire] = true
+			wires = append(wires, t.Wire)
+		}
+	}
+	slices.Sort(wires)
+	s.events = s.events[:0]
+	return s.netlist.oscillationError(wires, s.limit)
+}
+
+type StepTiming struct {
+	Pos         positions.Pos
+	Start       int
+	Transitions []Transition
+}
+
+type TestTiming struct {
+	Netlist *Netlist
+	Steps   []StepTiming
+}
+
+func TimeTest(test *logic.Test, delay int, opts RunOptions) (*TestTiming, error) {
+	return NewRunner(opts).TimeTest(test, delay)
+}
+
+func (r *Runner) TimeTest(test *logic.Test, delay int) (timing *TestTiming, err error) {
+	if test.Component == nil {
+		return nil, fmt.Errorf("test %s has no component", test.Name())
+	}
+	n := r.netlist(test.Component)
+	delays := make([]int, len(n.Gates))
+	for i := range delays {
+		delays[i] = delay
+	}
+	s, err := NewTimedState(n, delays, r.Options)
+	if err != nil {
+		return nil, err
+	}
+	if r.VCDDir != "" {
+		trace, closeTrace, err := createVCD(test, n, r.VCDDir, r.VCDInternal)
+		if err != nil {
+			return nil, fmt.Errorf("could not create VCD file: %v", err)
+		}
+		defer func() {
+			if closeErr := closeTrace(); closeErr != nil && err == nil {
+				err = fmt.Errorf("could not write VCD file: %v", closeErr)
+			}
+		}()
+		s.Trace(trace)
+	}
+	timing = &TestTiming{Netlist: n}
+	for _, step := range test.Steps {
+		set, ok := step.(*logic.SetInputs)
+		if !ok {
+			continue
+		}
+		start := s.Time()
+		transitions, err := s.SetInputs(collapseInput(set.Inputs))
+		timing.Steps = append(timing.Steps, StepTiming{Pos: set.Pos(), Start: start, Transitions: transitions})
+		if err != nil {
+			return timing, err
+		}
+	}
+	return timing, nil
+}
+
+func SettleTime(start int, transitions []Transition) int {
+	settle := 0
+	for _, t := range transitions {
+		settle = max(settle, t.Time-start)
+	}
+	return settle
+}
+
+func Glitches(transitions []Transition) []int32 {
+	counts := make(map[int32]int)
+	for _, t := range transitions {
+		counts[t.Wire]++
+	}
+	var wires []int32
+	for w, count := range counts {
+		if count > 1 {
+			wires = append(wires, w)
+		}
+	}
+	slices.Sort(wires)
+	return wires
+}
+
+type event struct {
+	time  int
+	seq   int
+	gate  int32
+	level Level
+}
+
+type eventQueue []event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].time != q[j].time {
+		return q[i].time < q[j].time
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x any) { *q = append(*q, x.(event)) }
+
+func (q *eventQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
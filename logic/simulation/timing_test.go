@@ -0,0 +1,174 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+func newTimedState(tb testing.TB, c *logic.Component, delays []int) *TimedState {
+	tb.Helper()
+	return newTimedStateWithOptions(tb, c, delays, Options{})
+}
+
+func newTimedStateWithOptions(tb testing.TB, c *logic.Component, delays []int, opts Options) *TimedState {
+	tb.Helper()
+	s, err := NewTimedState(Compile(c), delays, opts)
+	if err != nil {
+		tb.Fatalf("NewTimedState() failed: %v", err)
+	}
+	return s
+}
+
+func formatTransitions(n *Netlist, transitions []Transition) string {
+	var b strings.Builder
+	for _, t := range transitions {
+		v := 0
+		if t.Value {
+			v = 1
+		}
+		fmt.Fprintf(&b, "%d %s=%d\n", t.Time, n.WireName(t.Wire), v)
+	}
+	return b.String()
+}
+
+func TestTimedXorGlitch(t *testing.T) {
+	s := newTimedState(t, buildComponent(t, src, "Xor"), nil)
+	if _, err := s.SetInputs(logic.NewConstants([]logic.Value{{true}, {false}})); err != nil {
+		t.Fatalf("SetInputs() failed: %v", err)
+	}
+	start := s.Time()
+	transitions, err := s.SetInputs(logic.NewConstants([]logic.Value{{true}, {true}}))
+	if err != nil {
+		t.Fatalf("SetInputs() failed: %v", err)
+	}
+	expected := fmt.Sprintf("%[1]d b=1\n%[2]d i1=0\n%[2]d i3=0\n%[3]d i2=1\n%[3]d i3=1\n%[4]d r=0\n", start, start+1, start+2, start+3)
+	if actual := formatTransitions(s.Netlist(), transitions); actual != expected {
+		t.Errorf("transitions:\n%s\nwant:\n%s", actual, expected)
+	}
+	if settle := SettleTime(start, transitions); settle != 3 {
+		t.Errorf("SettleTime() = %d; want 3", settle)
+	}
+	glitches := Glitches(transitions)
+	if len(glitches) != 1 || s.Netlist().WireName(glitches[0]) != "i3" {
+		t.Errorf("Glitches() = %v; want [i3]", glitches)
+	}
+}
+
+func TestTimedMatchesComponentState(t *testing.T) {
+	src := adderSource(16)
+	rng := rand.New(rand.NewSource(3))
+	for _, name := range []string{"Add1", "Add16"} {
+		c := buildComponent(t, src, name)
+		expected := newState(t, c)
+		actual := newTimedState(t, c, nil)
+		for step := 0; step < 100; step++ {
+			inputs := randomInputs(rng, c)
+			setInputs(t, expected, inputs)
+			if _, err := actual.SetInputs(inputs); err != nil {
+				t.Fatalf("%s: SetInputs() failed: %v", name, err)
+			}
+			if fmt.Sprint(actual.Outputs()) != fmt.Sprint(expected.Outputs()) {
+				t.Fatalf("%s after %d steps: outputs = %v; want %v", name, step, actual.Outputs(), expected.Outputs())
+			}
+		}
+	}
+}
+
+func TestTimedDelays(t *testing.T) {
+	c := buildComponent(t, adderSource(8), "Add8")
+	inputs := logic.NewConstants(append(append(bitValues(0xff)[:8], bitValues(0)[:8]...), logic.Value{false}))
+	carry := logic.NewConstants(append(append(bitValues(0xff)[:8], bitValues(0)[:8]...), logic.Value{true}))
+	settle := func(delays []int) int {
+		s := newTimedState(t, c, delays)
+		if _, err := s.SetInputs(inputs); err != nil {
+			t.Fatalf("SetInputs() failed: %v", err)
+		}
+		start := s.Time()
+		transitions, err := s.SetInputs(carry)
+		if err != nil {
+			t.Fatalf("SetInputs() failed: %v", err)
+		}
+		return SettleTime(start, transitions)
+	}
+	unit := settle(nil)
+	delays := make([]int, len(Compile(c).Gates))
+	for i := range delays {
+		delays[i] = 5
+	}
+	if scaled := settle(delays); scaled != 5*unit {
+		t.Errorf("settle time with delay 5 = %d; want %d", scaled, 5*unit)
+	}
+	if unit <= 8 {
+		t.Errorf("carry through 8 bits settled after %d", unit)
+	}
+}
+
+func TestTimedInvalidDelays(t *testing.T) {
+	n := Compile(buildComponent(t, src, "Xor"))
+	if _, err := NewTimedState(n, []int{1}, Options{}); err == nil {
+		t.Errorf("NewTimedState() with too few delays succeeded")
+	}
+	if _, err := NewTimedState(n, []int{1, 1, 0, 1}, Options{}); err == nil {
+		t.Errorf("NewTimedState() with zero delay succeeded")
+	}
+	if _, err := NewTimedState(n, nil, Options{FourValued: true}); err == nil {
+		t.Errorf("NewTimedState() in four-valued mode succeeded")
+	}
+}
+
+func TestTimedOscillation(t *testing.T) {
+	s := newTimedStateWithOptions(t, buildComponent(t, src, "Ring"), nil, Options{SettleLimit: 100})
+	_, err := s.SetInputs(logic.NewConstants([]logic.Value{{true}}))
+	oscillation, ok := err.(*OscillationError)
+	if !ok {
+		t.Fatalf("SetInputs() = %v; want *OscillationError", err)
+	}
+	if actual := strings.Join(oscillation.Wires, ", "); actual != "r, n1, n2, n3" {
+		t.Errorf("oscillating wires = %s; want r, n1, n2, n3", actual)
+	}
+}
+
+func TestTimedConflict(t *testing.T) {
+	s := newTimedState(t, buildComponent(t, src, "Clash"), nil)
+	_, err := s.SetInputs(logic.NewConstants([]logic.Value{{true}, {true}, {true}, {false}}))
+	if conflict, ok := err.(*ConflictError); !ok || conflict.Wire != "r" {
+		t.Fatalf("SetInputs() = %v; want *ConflictError on r", err)
+	}
+}
+
+func TestTimeTest(t *testing.T) {
+	testSrc := src + `
+test Xor {
+    component: Xor
+
+    set a, b: 0, 0
+    expect r is 0
+    set a, b: 1, 0
+    expect r is 1
+    set a, b: 1, 1
+    expect r is 0
+}
+`
+	system := logictest.Build(t, testSrc)
+	timing, err := TimeTest(system.Tests["Xor"], 2, RunOptions{})
+	if err != nil {
+		t.Fatalf("TimeTest() failed: %v", err)
+	}
+	if len(timing.Steps) != 3 {
+		t.Fatalf("TimeTest() returned %d steps; want 3", len(timing.Steps))
+	}
+	for i, expected := range []int{0, 4, 6} {
+		step := timing.Steps[i]
+		if settle := SettleTime(step.Start, step.Transitions); settle != expected {
+			t.Errorf("step %d settled after %d; want %d", i+1, settle, expected)
+		}
+		if !step.Pos.IsValid() {
+			t.Errorf("step %d has no position", i+1)
+		}
+	}
+}
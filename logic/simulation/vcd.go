@@ -0,0 +1,239 @@
+package simulation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+type VCDWriter struct {
+	w       *bufio.Writer
+	netlist *Netlist
+	root    *vcdScope
+	vars    []*vcdVar
+	failed  *vcdVar
+	time    int
+	written int
+	started bool
+}
+
+type vcdScope struct {
+	name   string
+	vars   []*vcdVar
+	scopes []*vcdScope
+}
+
+type vcdVar struct {
+	id    string
+	name  string
+	wires []int32
+	value string
+}
+
+func NewVCDWriter(w io.Writer, n *Netlist, internal bool) *VCDWriter {
+	v := &VCDWriter{
+		w:       bufio.NewWriter(w),
+		netlist: n,
+		root:    &vcdScope{name: n.Component.Name()},
+	}
+	external := make(map[int32]bool)
+	for _, wires := range [][]int32{n.Inputs, n.Outputs} {
+		for _, w := range wires {
+			if !external[w] {
+				external[w] = true
+				v.addWire(w)
+			}
+		}
+	}
+	if internal {
+		for w := int32(OneWire + 1); w < int32(n.Wires); w++ {
+			if !external[w] && n.WireName(w) != "" {
+				v.addWire(w)
+			}
+		}
+	}
+	v.failed = &vcdVar{id: vcdID(len(v.vars)), name: "failed", value: "0"}
+	return v
+}
+
+func (v *VCDWriter) addWire(w int32) {
+	path := strings.Split(v.netlist.WireName(w), "/")
+	scope := v.root
+	for _, name := range path[:len(path)-1] {
+		scope = scope.scope(name)
+	}
+	name, index := path[len(path)-1], 0
+	if i := strings.LastIndexByte(name, '['); i > 0 && strings.HasSuffix(name, "]") {
+		if x, err := strconv.Atoi(name[i+1 : len(name)-1]); err == nil {
+			name, index = name[:i], x
+		}
+	}
+	var vv *vcdVar
+	for _, other := range scope.vars {
+		if other.name == name {
+			vv = other
+			break
+		}
+	}
+	if vv == nil {
+		vv = &vcdVar{id: vcdID(len(v.vars)), name: name}
+		scope.vars = append(scope.vars, vv)
+		v.vars = append(v.vars, vv)
+	}
+	for len(vv.wires) <= index {
+		vv.wires = append(vv.wires, -1)
+	}
+	vv.wires[index] = w
+}
+
+func (s *vcdScope) scope(name string) *vcdScope {
+	for _, scope := range s.scopes {
+		if scope.name == name {
+			return scope
+		}
+	}
+	scope := &vcdScope{name: name}
+	s.scopes = append(s.scopes, scope)
+	return scope
+}
+
+func vcdID(i int) string {
+	var sb strings.Builder
+	for {
+		sb.WriteByte(byte('!' + i%94))
+		i /= 94
+		if i == 0 {
+			return sb.String()
+		}
+		i--
+	}
+}
+
+func (v *VCDWriter) Flush() error {
+	if !v.started {
+		v.writeHeader()
+	}
+	return v.w.Flush()
+}
+
+func (v *VCDWriter) writeHeader() {
+	v.started = true
+	fmt.Fprintf(v.w, "$version mercury $end\n$timescale 1ns $end\n")
+	v.writeScope(v.root)
+	fmt.Fprintf(v.w, "$scope module test $end\n$var wire 1 %s %s $end\n$upscope $end\n", v.failed.id, v.failed.name)
+	fmt.Fprintf(v.w, "$enddefinitions $end\n")
+}
+
+func (v *VCDWriter) writeScope(s *vcdScope) {
+	fmt.Fprintf(v.w, "$scope module %s $end\n", s.name)
+	for _, vv := range s.vars {
+		if len(vv.wires) == 1 {
+			fmt.Fprintf(v.w, "$var wire 1 %s %s $end\n", vv.id, vv.name)
+		} else {
+			fmt.Fprintf(v.w, "$var wire %d %s %s [%d:0] $end\n", len(vv.wires), vv.id, vv.name, len(vv.wires)-1)
+		}
+	}
+	for _, scope := range s.scopes {
+		v.writeScope(scope)
+	}
+	fmt.Fprintf(v.w, "$upscope $end\n")
+}
+
+func (v *VCDWriter) dump(time int, level func(int32) Level) {
+	if !v.started {
+		v.writeHeader()
+		v.time, v.written = time, time
+		fmt.Fprintf(v.w, "#%d\n$dumpvars\n", time)
+		for _, vv := range v.vars {
+			vv.value = vv.format(level)
+			v.writeValue(vv)
+		}
+		v.writeValue(v.failed)
+		fmt.Fprintf(v.w, "$end\n")
+		return
+	}
+	v.time = time
+	if v.failed.value != "0" {
+		v.failed.value = "0"
+		v.writeTime()
+		v.writeValue(v.failed)
+	}
+	for _, vv := range v.vars {
+		if value := vv.format(level); value != vv.value {
+			vv.value = value
+			v.writeTime()
+			v.writeValue(vv)
+		}
+	}
+}
+
+func (v *VCDWriter) next(level func(int32) Level) {
+	time := v.time
+	if v.started {
+		time++
+	}
+	v.dump(time, level)
+}
+
+func (v *VCDWriter) fail(msg string) {
+	if !v.started {
+		return
+	}
+	v.writeTime()
+	fmt.Fprintf(v.w, "$comment %s $end\n", strings.ReplaceAll(msg, "$end", "$ end"))
+	if v.failed.value != "1" {
+		v.failed.value = "1"
+		v.writeValue(v.failed)
+	}
+}
+
+func (v *VCDWriter) writeTime() {
+	if v.time != v.written {
+		v.written = v.time
+		fmt.Fprintf(v.w, "#%d\n", v.time)
+	}
+}
+
+func (v *VCDWriter) writeValue(vv *vcdVar) {
+	if len(vv.value) == 1 {
+		fmt.Fprintf(v.w, "%s%s\n", vv.value, vv.id)
+	} else {
+		fmt.Fprintf(v.w, "b%s %s\n", vv.value, vv.id)
+	}
+}
+
+func (vv *vcdVar) format(level func(int32) Level) string {
+	b := make([]byte, len(vv.wires))
+	for i, w := range vv.wires {
+		l := X
+		if w >= 0 {
+			l = level(w)
+		}
+		b[len(b)-1-i] = "01xz"[l]
+	}
+	return string(b)
+}
+
+func createVCD(test *logic.Test, n *Netlist, dir string, internal bool) (*VCDWriter, func() error, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Create(filepath.Join(dir, test.Name()+".vcd"))
+	if err != nil {
+		return nil, nil, err
+	}
+	v := NewVCDWriter(f, n, internal)
+	return v, func() error {
+		if err := v.Flush(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}, nil
+}
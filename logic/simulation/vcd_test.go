@@ -0,0 +1,141 @@
+package simulation
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+func TestVCDComponentState(t *testing.T) {
+	s := newState(t, buildComponent(t, src, "Xor"))
+	var sb strings.Builder
+	v := NewVCDWriter(&sb, s.netlist, false)
+	s.Trace(v)
+	setInputs(t, s, logic.NewConstants([]logic.Value{{true}, {false}}))
+	setInputs(t, s, logic.NewConstants([]logic.Value{{true}, {false}}))
+	setInputs(t, s, logic.NewConstants([]logic.Value{{true}, {true}}))
+	if err := v.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	expected := `$version mercury $end
+$timescale 1ns $end
+$scope module Xor $end
+$var wire 1 ! a $end
+$var wire 1 " b $end
+$var wire 1 # r $end
+$upscope $end
+$scope module test $end
+$var wire 1 $ failed $end
+$upscope $end
+$enddefinitions $end
+#0
+$dumpvars
+0!
+0"
+0#
+0$
+$end
+#1
+1!
+1#
+#3
+1"
+0#
+`
+	if actual := sb.String(); actual != expected {
+		t.Errorf("VCD:\n%s\nwant:\n%s", actual, expected)
+	}
+}
+
+func TestVCDBusesAndScopes(t *testing.T) {
+	c := buildComponent(t, adderSource(4), "Add4")
+	for _, internal := range []bool{false, true} {
+		s := newState(t, c)
+		var sb strings.Builder
+		v := NewVCDWriter(&sb, s.netlist, internal)
+		s.Trace(v)
+		setInputs(t, s, logic.NewConstants(append(append(bitValues(5)[:4], bitValues(3)[:4]...), logic.Value{false})))
+		if err := v.Flush(); err != nil {
+			t.Fatalf("Flush() failed: %v", err)
+		}
+		actual := sb.String()
+		for _, line := range []string{"$var wire 4 ! a [3:0] $end", "$var wire 4 $ r [3:0] $end", "b0101 !", "b1000 $"} {
+			if !strings.Contains(actual, line+"\n") {
+				t.Errorf("internal=%v: VCD does not contain %q:\n%s", internal, line, actual)
+			}
+		}
+		for _, line := range []string{"$var wire 4 \\ c [3:0] $end", "$scope module Add1_i2 $end", "$scope module Xor_i3 $end"} {
+			if strings.Contains(actual, line+"\n") != internal {
+				t.Errorf("internal=%v: VCD contains %q: %v; want %v", internal, line, !internal, internal)
+			}
+		}
+	}
+}
+
+func TestVCDFourValued(t *testing.T) {
+	s, err := NewComponentStateWithOptions(Compile(buildComponent(t, src, "Memory1")), Options{FourValued: true})
+	if err != nil {
+		t.Fatalf("NewComponentStateWithOptions() failed: %v", err)
+	}
+	var sb strings.Builder
+	v := NewVCDWriter(&sb, s.netlist, false)
+	s.Trace(v)
+	setInputs(t, s, logic.NewConstants([]logic.Value{{true}, {false}}))
+	if err := v.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	if actual := sb.String(); !strings.HasSuffix(actual, "$dumpvars\nx!\nx\"\nx#\nx$\n0%\n$end\n#1\n1!\n0\"\n1#\n0$\n") {
+		t.Errorf("VCD:\n%s", actual)
+	}
+}
+
+func TestVCDTimedState(t *testing.T) {
+	s := newTimedState(t, buildComponent(t, src, "Xor"), nil)
+	if _, err := s.SetInputs(logic.NewConstants([]logic.Value{{true}, {false}})); err != nil {
+		t.Fatalf("SetInputs() failed: %v", err)
+	}
+	var sb strings.Builder
+	v := NewVCDWriter(&sb, s.Netlist(), true)
+	s.Trace(v)
+	if _, err := s.SetInputs(logic.NewConstants([]logic.Value{{true}, {true}})); err != nil {
+		t.Fatalf("SetInputs() failed: %v", err)
+	}
+	if err := v.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	start := s.Time() - 3
+	expected := "$end\n1\"\n#" + strconv.Itoa(start+1) + "\n0$\n0&\n#" + strconv.Itoa(start+2) + "\n1%\n1&\n#" + strconv.Itoa(start+3) + "\n0#\n"
+	if actual := sb.String(); !strings.HasSuffix(actual, expected) {
+		t.Errorf("VCD:\n%s\nwant suffix:\n%s", actual, expected)
+	}
+}
+
+func TestRunTestWritesVCD(t *testing.T) {
+	opts := RunOptions{VCDDir: filepath.Join(t.TempDir(), "out")}
+	testSrc := src + `
+test Not {
+    component: Not
+
+    set a: 0
+    expect r is 0
+    set a: 1
+    expect r is 0
+}
+`
+	system, file := logictest.BuildFile(t, "vcd.mercury", testSrc)
+	if outcome, errs := RunTest(system.Tests["Not"], file, opts); outcome != FAIL || errs.Len() != 1 {
+		t.Fatalf("RunTest() = %v, %v; want a single failure", outcome, errs)
+	}
+	dump, err := os.ReadFile(filepath.Join(opts.VCDDir, "Not.vcd"))
+	if err != nil {
+		t.Fatalf("could not read VCD file: %v", err)
+	}
+	if !strings.HasSuffix(string(dump), "#1\n$comment expect failed: expected 0, got 1 $end\n1#\n#2\n0#\n1!\n0\"\n") {
+		t.Errorf("VCD:\n%s", dump)
+	}
+}
@@ -0,0 +1,357 @@
+package synth
+
+import (
+	"math/bits"
+	"slices"
+)
+
+const (
+	MaxInputs         = 64
+	DefaultExactLimit = 10
+	MaxExactLimit     = 20
+	DefaultPrimeLimit = 1000
+)
+
+type Options struct {
+	ExactLimit int
+	PrimeLimit int
+}
+
+func DefaultOptions() Options {
+	return Options{ExactLimit: DefaultExactLimit, PrimeLimit: DefaultPrimeLimit}
+}
+
+type Cube struct {
+	Care  uint64
+	Value uint64
+}
+
+func MintermCube(inputs int, minterm uint64) Cube {
+	care := mask(inputs)
+	return Cube{Care: care, Value: minterm & care}
+}
+
+func mask(inputs int) uint64 {
+	if inputs == 64 {
+		return ^uint64(0)
+	}
+	return 1<<inputs - 1
+}
+
+func (c Cube) Literals() int {
+	return bits.OnesCount64(c.Care)
+}
+
+func (c Cube) Contains(minterm uint64) bool {
+	return minterm&c.Care == c.Value
+}
+
+func (c Cube) Covers(d Cube) bool {
+	return c.Care&^d.Care == 0 && d.Value&c.Care == c.Value
+}
+
+func (c Cube) Intersects(d Cube) bool {
+	common := c.Care & d.Care
+	return c.Value&common == d.Value&common
+}
+
+func (c Cube) Intersection(d Cube) Cube {
+	return Cube{Care: c.Care | d.Care, Value: c.Value | d.Value}
+}
+
+func (c Cube) String(inputs int) string {
+	b := make([]byte, inputs)
+	for i := range b {
+		bit := uint64(1) << i
+		switch {
+		case c.Care&bit == 0:
+			b[i] = '-'
+		case c.Value&bit == 0:
+			b[i] = '0'
+		default:
+			b[i] = '1'
+		}
+	}
+	return string(b)
+}
+
+type Function struct {
+	Inputs      int
+	On          []Cube
+	DontCare    []Cube
+	Off         []Cube
+	ExplicitOff bool
+}
+
+func Minimize(f Function) []Cube {
+	return MinimizeWithOptions(f, DefaultOptions())
+}
+
+func MinimizeWithOptions(f Function, opts Options) []Cube {
+	var cover []Cube
+	exact := false
+	if f.Inputs <= min(opts.ExactLimit, MaxExactLimit) {
+		cover, exact = minimizeExact(f, opts.PrimeLimit)
+	}
+	if !exact {
+		cover = minimizeHeuristic(f)
+	}
+	slices.SortFunc(cover, compareCubes)
+	return cover
+}
+
+func compareCubes(a, b Cube) int {
+	if a.Care != b.Care {
+		if a.Care < b.Care {
+			return -1
+		}
+		return 1
+	}
+	if a.Value != b.Value {
+		if a.Value < b.Value {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func minterms(inputs int, cubes []Cube, visit func(m uint64)) {
+	full := mask(inputs)
+	for _, c := range cubes {
+		free := full &^ c.Care
+		sub := uint64(0)
+		for {
+			visit(c.Value | sub)
+			if sub == free {
+				break
+			}
+			sub = (sub - free) & free
+		}
+	}
+}
+
+func minimizeExact(f Function, primeLimit int) ([]Cube, bool) {
+	dc := make(map[uint64]bool)
+	minterms(f.Inputs, f.DontCare, func(m uint64) { dc[m] = true })
+	on := make(map[uint64]bool)
+	minterms(f.Inputs, f.On, func(m uint64) {
+		if !dc[m] {
+			on[m] = true
+		}
+	})
+	if len(on) == 0 {
+		return nil, true
+	}
+	if f.ExplicitOff {
+		off := make(map[uint64]bool)
+		minterms(f.Inputs, f.Off, func(m uint64) { off[m] = true })
+		for m := uint64(0); ; m++ {
+			if !on[m] && !off[m] {
+				dc[m] = true
+			}
+			if m == mask(f.Inputs) {
+				break
+			}
+		}
+	}
+	current := make(map[Cube]bool)
+	for m := range on {
+		current[MintermCube(f.Inputs, m)] = true
+	}
+	for m := range dc {
+		if !on[m] {
+			current[MintermCube(f.Inputs, m)] = true
+		}
+	}
+	var primes []Cube
+	for len(current) > 0 {
+		next := make(map[Cube]bool)
+		combined := make(map[Cube]bool)
+		for c := range current {
+			for care := c.Care; care != 0; care &= care - 1 {
+				bit := care & -care
+				if c.Value&bit != 0 {
+					continue
+				}
+				partner := Cube{Care: c.Care, Value: c.Value | bit}
+				if !current[partner] {
+					continue
+				}
+				combined[c] = true
+				combined[partner] = true
+				next[Cube{Care: c.Care &^ bit, Value: c.Value}] = true
+			}
+		}
+		for c := range current {
+			if !combined[c] {
+				primes = append(primes, c)
+			}
+		}
+		if len(primes) > primeLimit {
+			return nil, false
+		}
+		current = next
+	}
+	slices.SortFunc(primes, compareCubes)
+	return selectCover(on, primes), true
+}
+
+func selectCover(on map[uint64]bool, primes []Cube) []Cube {
+	uncovered := make(map[uint64]bool, len(on))
+	for m := range on {
+		uncovered[m] = true
+	}
+	selected := make([]bool, len(primes))
+	var cover []Cube
+	choose := func(i int) {
+		selected[i] = true
+		cover = append(cover, primes[i])
+		for m := range uncovered {
+			if primes[i].Contains(m) {
+				delete(uncovered, m)
+			}
+		}
+	}
+	for m := range on {
+		if !uncovered[m] {
+			continue
+		}
+		only := -1
+		for i, p := range primes {
+			if !p.Contains(m) {
+				continue
+			} else if only != -1 {
+				only = -1
+				break
+			}
+			only = i
+		}
+		if only != -1 && !selected[only] {
+			choose(only)
+		}
+	}
+	for len(uncovered) > 0 {
+		best, bestCount := -1, 0
+		for i, p := range primes {
+			if selected[i] {
+				continue
+			}
+			count := 0
+			for m := range uncovered {
+				if p.Contains(m) {
+					count++
+				}
+			}
+			if count > bestCount || (count == bestCount && count > 0 && p.Literals() < primes[best].Literals()) {
+				best, bestCount = i, count
+			}
+		}
+		choose(best)
+	}
+	return cover
+}
+
+func minimizeHeuristic(f Function) []Cube {
+	care := append(slices.Clone(f.On), f.DontCare...)
+	valid := func(c Cube) bool {
+		if f.ExplicitOff {
+			for _, off := range f.Off {
+				if c.Intersects(off) && !tautology(cofactor(f.DontCare, c.Intersection(off))) {
+					return false
+				}
+			}
+			return true
+		}
+		return tautology(cofactor(care, c))
+	}
+	cover := slices.Clone(f.On)
+	slices.SortFunc(cover, func(a, b Cube) int {
+		return a.Literals() - b.Literals()
+	})
+	var expanded []Cube
+	for _, c := range cover {
+		if coveredByAny(expanded, c) {
+			continue
+		}
+		for lits := c.Care; lits != 0; lits &= lits - 1 {
+			bit := lits & -lits
+			d := Cube{Care: c.Care &^ bit, Value: c.Value &^ bit}
+			if valid(d) {
+				c = d
+			}
+		}
+		expanded = append(expanded, c)
+	}
+	return irredundant(expanded, f)
+}
+
+func coveredByAny(cover []Cube, c Cube) bool {
+	for _, d := range cover {
+		if d.Covers(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func irredundant(cover []Cube, f Function) []Cube {
+	for i := len(cover) - 1; i >= 0; i-- {
+		rest := append(slices.Clone(cover[:i]), cover[i+1:]...)
+		rest = append(rest, f.DontCare...)
+		redundant := true
+		for _, on := range f.On {
+			if !on.Intersects(cover[i]) {
+				continue
+			}
+			if !tautology(cofactor(rest, on.Intersection(cover[i]))) {
+				redundant = false
+				break
+			}
+		}
+		if redundant {
+			cover = append(cover[:i], cover[i+1:]...)
+		}
+	}
+	return cover
+}
+
+func cofactor(cover []Cube, c Cube) []Cube {
+	var result []Cube
+	for _, d := range cover {
+		if d.Intersects(c) {
+			result = append(result, Cube{Care: d.Care &^ c.Care, Value: d.Value &^ c.Care})
+		}
+	}
+	return result
+}
+
+func tautology(cover []Cube) bool {
+	if len(cover) == 0 {
+		return false
+	}
+	var pos, neg uint64
+	counts := make(map[uint64]int)
+	for _, c := range cover {
+		if c.Care == 0 {
+			return true
+		}
+		pos |= c.Care & c.Value
+		neg |= c.Care &^ c.Value
+		for care := c.Care; care != 0; care &= care - 1 {
+			counts[care&-care]++
+		}
+	}
+	if pos&neg == 0 {
+		return false
+	}
+	var split uint64
+	for binate := pos & neg; binate != 0; binate &= binate - 1 {
+		bit := binate & -binate
+		if split == 0 || counts[bit] > counts[split] {
+			split = bit
+		}
+	}
+	return tautology(cofactor(cover, Cube{Care: split})) &&
+		tautology(cofactor(cover, Cube{Care: split, Value: split}))
+}
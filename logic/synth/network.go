@@ -0,0 +1,239 @@
+package synth
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+func Synthesize(t *Table) (*logic.Component, error) {
+	return SynthesizeWithOptions(t, DefaultOptions())
+}
+
+func SynthesizeWithOptions(t *Table, opts Options) (*logic.Component, error) {
+	if t.InputWires() > MaxInputs {
+		return nil, fmt.Errorf("table %s has too many input wires: %d > %d", t.Name, t.InputWires(), MaxInputs)
+	}
+	covers := make([][]Cube, t.OutputWires())
+	for i := range covers {
+		covers[i] = MinimizeWithOptions(t.Function(i), opts)
+	}
+	c := logic.NewComponent(t.Name, t.Inputs, t.Outputs)
+	Implement(c, wires(t.Inputs), wires(t.Outputs), covers)
+	return c, nil
+}
+
+func Implement(c *logic.Component, inputs, outputs []logic.BusWire, covers [][]Cube) {
+	n := &network{
+		component: c,
+		inputs:    inputs,
+		outputs:   outputs,
+		inverted:  make(map[logic.BusWire]logic.BusWire),
+		terms:     make(map[Cube]logic.BusWire),
+		nands:     make(map[string]logic.BusWire),
+		counts:    make(map[string]int),
+	}
+	n.build(covers)
+}
+
+type network struct {
+	component *logic.Component
+	inputs    []logic.BusWire
+	outputs   []logic.BusWire
+	inverted  map[logic.BusWire]logic.BusWire
+	terms     map[Cube]logic.BusWire
+	nands     map[string]logic.BusWire
+	counts    map[string]int
+}
+
+func wires(buses []*logic.Bus) []logic.BusWire {
+	var ws []logic.BusWire
+	for _, bus := range buses {
+		for i := 0; i < bus.Wires(); i++ {
+			ws = append(ws, logic.BusWire{
+				Bus:       bus,
+				WireIndex: logic.WireIndex(i),
+			})
+		}
+	}
+	return ws
+}
+
+func (n *network) build(covers [][]Cube) {
+	var constantOutputs []logic.BusWire
+	var constantValues []logic.Value
+	for i, cover := range covers {
+		if value, ok := constantCover(cover); ok {
+			constantOutputs = append(constantOutputs, n.outputs[i])
+			constantValues = append(constantValues, logic.Value{value})
+		}
+	}
+	if len(constantOutputs) > 0 {
+		n.component.Instances = append(n.component.Instances, &logic.Instance{
+			Definition: logic.NewConstants(constantValues),
+			Inputs:     nil,
+			Outputs:    constantOutputs,
+		})
+	}
+	for i, cover := range covers {
+		if _, ok := constantCover(cover); ok {
+			continue
+		}
+		n.buildOutput(cover, n.outputs[i])
+	}
+}
+
+func constantCover(cover []Cube) (bool, bool) {
+	if len(cover) == 0 {
+		return false, true
+	}
+	for _, c := range cover {
+		if c.Care == 0 {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+func (n *network) buildOutput(cover []Cube, output logic.BusWire) {
+	if len(cover) == 1 {
+		term := n.term(cover[0])
+		n.nand(term, term, output)
+		return
+	}
+	terms := make([]logic.BusWire, len(cover))
+	for i, c := range cover {
+		terms[i] = n.term(c)
+	}
+	n.nandOf(terms, &output)
+}
+
+func (n *network) literals(c Cube) []logic.BusWire {
+	var literals []logic.BusWire
+	for i, input := range n.inputs {
+		bit := uint64(1) << i
+		if c.Care&bit == 0 {
+			continue
+		} else if c.Value&bit != 0 {
+			literals = append(literals, input)
+		} else {
+			literals = append(literals, n.not(input))
+		}
+	}
+	return literals
+}
+
+func (n *network) term(c Cube) logic.BusWire {
+	if wire, ok := n.terms[c]; ok {
+		return wire
+	}
+	var wire logic.BusWire
+	if c.Literals() == 1 {
+		i := bits.TrailingZeros64(c.Care)
+		if c.Value != 0 {
+			wire = n.not(n.inputs[i])
+		} else {
+			wire = n.inputs[i]
+		}
+	} else {
+		wire = n.nandOf(n.literals(c), nil)
+	}
+	n.terms[c] = wire
+	return wire
+}
+
+func (n *network) nandOf(ws []logic.BusWire, target *logic.BusWire) logic.BusWire {
+	if len(ws) == 1 && target == nil {
+		return n.not(ws[0])
+	}
+	var key string
+	var r logic.BusWire
+	if target != nil {
+		r = *target
+	} else {
+		key = nandKey(ws)
+		if wire, ok := n.nands[key]; ok {
+			return wire
+		}
+		r = n.newWire("t")
+		n.nands[key] = r
+	}
+	switch len(ws) {
+	case 1:
+		n.nand(ws[0], ws[0], r)
+	case 2:
+		n.nand(ws[0], ws[1], r)
+	default:
+		half := len(ws) / 2
+		n.nand(n.andOf(ws[:half]), n.andOf(ws[half:]), r)
+	}
+	return r
+}
+
+func nandKey(ws []logic.BusWire) string {
+	var sb strings.Builder
+	for i, w := range ws {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(w.Bus.Name)
+		sb.WriteString("[")
+		sb.WriteString(strconv.Itoa(int(w.WireIndex)))
+		sb.WriteString("]")
+	}
+	return sb.String()
+}
+
+func (n *network) andOf(ws []logic.BusWire) logic.BusWire {
+	if len(ws) == 1 {
+		return ws[0]
+	}
+	return n.not(n.nandOf(ws, nil))
+}
+
+func (n *network) not(w logic.BusWire) logic.BusWire {
+	if inverted, ok := n.inverted[w]; ok {
+		return inverted
+	}
+	name := "'" + logic.CollapsedWireName(w.Bus, w.WireIndex)
+	var r logic.BusWire
+	if _, ok := n.component.Buses[name]; ok {
+		r = n.newWire("n")
+	} else {
+		r = n.addWire(name)
+	}
+	n.nand(w, w, r)
+	n.inverted[w] = r
+	n.inverted[r] = w
+	return r
+}
+
+func (n *network) newWire(prefix string) logic.BusWire {
+	for {
+		name := fmt.Sprintf("%s%d", prefix, n.counts[prefix])
+		n.counts[prefix]++
+		if _, ok := n.component.Buses[name]; !ok {
+			return n.addWire(name)
+		}
+	}
+}
+
+func (n *network) addWire(name string) logic.BusWire {
+	bus := logic.NewBus(name, 1)
+	n.component.Buses[name] = bus
+	return logic.BusWire{
+		Bus:       bus,
+		WireIndex: 0,
+	}
+}
+
+func (n *network) nand(a, b, r logic.BusWire) {
+	n.component.Instances = append(n.component.Instances, &logic.Instance{
+		Definition: logic.Nand,
+		Inputs:     []logic.BusWire{a, b},
+		Outputs:    []logic.BusWire{r},
+	})
+}
@@ -0,0 +1,244 @@
+package synth
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+func ReadPLA(r io.Reader, name string) (*Table, error) {
+	p := &plaReader{
+		name:    name,
+		outType: "fd",
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p.line++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var err error
+		if strings.HasPrefix(fields[0], ".") {
+			var end bool
+			end, err = p.readDirective(fields)
+			if end {
+				break
+			}
+		} else {
+			err = p.readRow(fields)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", p.line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+	return p.table, nil
+}
+
+type plaReader struct {
+	name        string
+	line        int
+	inputs      int
+	outputs     int
+	inputNames  []string
+	outputNames []string
+	outType     string
+	table       *Table
+}
+
+func (p *plaReader) readDirective(fields []string) (bool, error) {
+	if p.table != nil && fields[0] != ".e" && fields[0] != ".end" {
+		return false, fmt.Errorf("directive %s after first product term", fields[0])
+	}
+	switch fields[0] {
+	case ".i", ".o":
+		if len(fields) != 2 {
+			return false, fmt.Errorf("expected one argument for %s", fields[0])
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n <= 0 {
+			return false, fmt.Errorf("invalid argument for %s: %s", fields[0], fields[1])
+		}
+		if fields[0] == ".i" {
+			p.inputs = n
+		} else {
+			p.outputs = n
+		}
+	case ".ilb":
+		p.inputNames = fields[1:]
+	case ".ob":
+		p.outputNames = fields[1:]
+	case ".p":
+		break
+	case ".type":
+		if len(fields) != 2 {
+			return false, fmt.Errorf("expected one argument for .type")
+		}
+		switch fields[1] {
+		case "f", "fd", "fr", "fdr":
+			p.outType = fields[1]
+		default:
+			return false, fmt.Errorf("unsupported type: %s", fields[1])
+		}
+	case ".e", ".end":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported directive: %s", fields[0])
+	}
+	return false, nil
+}
+
+func (p *plaReader) init() error {
+	if p.table != nil {
+		return nil
+	}
+	if p.inputs == 0 {
+		return fmt.Errorf("missing .i directive")
+	} else if p.outputs == 0 {
+		return fmt.Errorf("missing .o directive")
+	} else if p.inputs > MaxInputs {
+		return fmt.Errorf("too many inputs: %d > %d", p.inputs, MaxInputs)
+	}
+	inputNames, err := plaNames(p.inputNames, p.inputs, "i", ".ilb")
+	if err != nil {
+		return err
+	}
+	outputNames, err := plaNames(p.outputNames, p.outputs, "o", ".ob")
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	var buses [2][]*logic.Bus
+	for i, names := range [2][]string{inputNames, outputNames} {
+		for _, name := range names {
+			if seen[name] {
+				return fmt.Errorf("repeated signal name: %s", name)
+			}
+			seen[name] = true
+			buses[i] = append(buses[i], logic.NewBus(name, 1))
+		}
+	}
+	p.table = NewTable(p.name, buses[0], buses[1])
+	if p.outType == "fr" || p.outType == "fdr" {
+		p.table.Default = DONT_CARE
+	}
+	return nil
+}
+
+func plaNames(names []string, n int, prefix, directive string) ([]string, error) {
+	if names == nil {
+		names = make([]string, n)
+		for i := range names {
+			names[i] = prefix + strconv.Itoa(i)
+		}
+		return names, nil
+	} else if len(names) != n {
+		return nil, fmt.Errorf("%s has %d names, expected %d", directive, len(names), n)
+	}
+	result := make([]string, n)
+	for i, name := range names {
+		result[i] = Identifier(name)
+	}
+	return result, nil
+}
+
+func Identifier(name string) string {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		switch {
+		case ch == '\'' || ch == '_' || ('A' <= ch && ch <= 'Z') || ('a' <= ch && ch <= 'z'):
+			sb.WriteByte(ch)
+		case '0' <= ch && ch <= '9':
+			if i == 0 {
+				sb.WriteByte('_')
+			}
+			sb.WriteByte(ch)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	if sb.Len() == 0 {
+		return "_"
+	} else if keywords[sb.String()] {
+		sb.WriteByte('_')
+	}
+	return sb.String()
+}
+
+var keywords = map[string]bool{
+	"component": true,
+	"test":      true,
+	"define":    true,
+	"set":       true,
+	"assert":    true,
+	"expect":    true,
+	"is":        true,
+	"for":       true,
+	"from":      true,
+	"to":        true,
+	"vectors":   true,
+	"equiv":     true,
+	"table":     true,
+}
+
+func (p *plaReader) readRow(fields []string) error {
+	if err := p.init(); err != nil {
+		return err
+	}
+	row := strings.Join(fields, "")
+	if len(row) != p.inputs+p.outputs {
+		return fmt.Errorf("expected %d input and %d output columns, got %d columns", p.inputs, p.outputs, len(row))
+	}
+	inputs := make([]Trit, p.inputs)
+	for i := range inputs {
+		switch row[i] {
+		case '0':
+			inputs[i] = ZERO
+		case '1':
+			inputs[i] = ONE
+		case '-', '2':
+			inputs[i] = DONT_CARE
+		default:
+			return fmt.Errorf("invalid input character: %q", row[i])
+		}
+	}
+	outputs := make([]Trit, p.outputs)
+	for i := range outputs {
+		switch row[p.inputs+i] {
+		case '1', '4':
+			outputs[i] = ONE
+		case '0':
+			if strings.Contains(p.outType, "r") {
+				outputs[i] = ZERO
+			} else {
+				outputs[i] = UNSPECIFIED
+			}
+		case '-', '2':
+			if strings.Contains(p.outType, "d") {
+				outputs[i] = DONT_CARE
+			} else {
+				outputs[i] = UNSPECIFIED
+			}
+		case '~', '3':
+			outputs[i] = UNSPECIFIED
+		default:
+			return fmt.Errorf("invalid output character: %q", row[p.inputs+i])
+		}
+	}
+	return p.table.AddRow(inputs, outputs)
+}
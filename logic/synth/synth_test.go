@@ -0,0 +1,357 @@
+package synth
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/bdd"
+)
+
+func checkCover(t *testing.T, f Function, cover []Cube, on, off func(m uint64) bool) {
+	t.Helper()
+	for m := uint64(0); m < 1<<f.Inputs; m++ {
+		covered := false
+		for _, c := range cover {
+			if c.Contains(m) {
+				covered = true
+				break
+			}
+		}
+		if on(m) && !covered {
+			t.Errorf("minterm %d of on-set is not covered", m)
+		} else if off(m) && covered {
+			t.Errorf("minterm %d of off-set is covered", m)
+		}
+	}
+}
+
+func withModes(t *testing.T, test func(t *testing.T, opts Options)) {
+	t.Run("exact", func(t *testing.T) {
+		test(t, DefaultOptions())
+	})
+	t.Run("heuristic", func(t *testing.T) {
+		test(t, Options{})
+	})
+	t.Run("fallback", func(t *testing.T) {
+		test(t, Options{ExactLimit: DefaultExactLimit})
+	})
+}
+
+func TestMinimizeCyclic(t *testing.T) {
+	withModes(t, func(t *testing.T, opts Options) {
+		f := Function{Inputs: 3}
+		onSet := map[uint64]bool{0: true, 1: true, 2: true, 5: true, 6: true, 7: true}
+		for m := range onSet {
+			f.On = append(f.On, MintermCube(3, m))
+		}
+		cover := MinimizeWithOptions(f, opts)
+		checkCover(t, f, cover, func(m uint64) bool { return onSet[m] }, func(m uint64) bool { return !onSet[m] })
+		if len(cover) > 4 {
+			t.Errorf("expected at most 4 cubes, got %d", len(cover))
+		}
+		for _, c := range cover {
+			if c.Literals() != 2 {
+				t.Errorf("expected prime implicant with 2 literals, got %s", c.String(3))
+			}
+		}
+	})
+}
+
+func TestMinimizeRandom(t *testing.T) {
+	withModes(t, func(t *testing.T, opts Options) {
+		rng := rand.New(rand.NewSource(42))
+		for i := 0; i < 50; i++ {
+			inputs := 1 + rng.Intn(7)
+			explicitOff := i%3 == 0
+			f := Function{Inputs: inputs, ExplicitOff: explicitOff}
+			kinds := make([]int, 1<<inputs)
+			for m := range kinds {
+				kinds[m] = rng.Intn(4)
+				c := MintermCube(inputs, uint64(m))
+				switch kinds[m] {
+				case 0:
+					f.Off = append(f.Off, c)
+				case 1:
+					f.On = append(f.On, c)
+				case 2:
+					f.DontCare = append(f.DontCare, c)
+				}
+			}
+			cover := MinimizeWithOptions(f, opts)
+			checkCover(t, f, cover,
+				func(m uint64) bool { return kinds[m] == 1 },
+				func(m uint64) bool { return kinds[m] == 0 || (kinds[m] == 3 && !explicitOff) })
+		}
+	})
+}
+
+func TestMinimizeBoundsExactLimit(t *testing.T) {
+	f := Function{
+		Inputs:      MaxInputs,
+		On:          []Cube{{Care: 1, Value: 1}},
+		Off:         []Cube{{Care: 1, Value: 0}},
+		ExplicitOff: true,
+	}
+	cover := MinimizeWithOptions(f, Options{ExactLimit: MaxInputs, PrimeLimit: DefaultPrimeLimit})
+	if len(cover) != 1 || cover[0] != (Cube{Care: 1, Value: 1}) {
+		t.Errorf("MinimizeWithOptions() = %v; want [{1 1}]", cover)
+	}
+}
+
+func TestMinimizeMergesCubes(t *testing.T) {
+	withModes(t, func(t *testing.T, opts Options) {
+		f := Function{
+			Inputs: 4,
+			On: []Cube{
+				{Care: 0b0011, Value: 0b0001},
+				{Care: 0b0011, Value: 0b0011},
+			},
+			DontCare: []Cube{
+				{Care: 0b1111, Value: 0b0000},
+			},
+		}
+		cover := MinimizeWithOptions(f, opts)
+		if len(cover) != 1 || cover[0] != (Cube{Care: 0b0001, Value: 0b0001}) {
+			t.Errorf("expected cover {1---}, got %v", cover)
+		}
+	})
+}
+
+func newSevenSegmentTable() *Table {
+	d := logic.NewBus("d", 4)
+	s := logic.NewBus("s", 7)
+	t := NewTable("Seg7", []*logic.Bus{d}, []*logic.Bus{s})
+	segments := []int{0x3f, 0x06, 0x5b, 0x4f, 0x66, 0x6d, 0x7d, 0x07, 0x7f, 0x6f}
+	for digit, segment := range segments {
+		inputs := make([]Trit, 4)
+		for i := range inputs {
+			inputs[i] = Trit((digit >> i) & 1)
+		}
+		outputs := make([]Trit, 7)
+		for i := range outputs {
+			outputs[i] = Trit((segment >> i) & 1)
+		}
+		if err := t.AddRow(inputs, outputs); err != nil {
+			panic(err)
+		}
+	}
+	for digit := 10; digit < 16; digit++ {
+		inputs := make([]Trit, 4)
+		for i := range inputs {
+			inputs[i] = Trit((digit >> i) & 1)
+		}
+		outputs := make([]Trit, 7)
+		for i := range outputs {
+			outputs[i] = DONT_CARE
+		}
+		if err := t.AddRow(inputs, outputs); err != nil {
+			panic(err)
+		}
+	}
+	return t
+}
+
+func evaluate(t *testing.T, c *logic.Component) func(inputs []bool) []bool {
+	t.Helper()
+	for _, instance := range c.Instances {
+		switch instance.Definition.(type) {
+		case logic.NandGate, *logic.Constants:
+		default:
+			t.Fatalf("unexpected definition in synthesized component: %s", instance.Definition.Name())
+		}
+	}
+	vars := make([]int, c.InputWires())
+	for i := range vars {
+		vars[i] = i
+	}
+	circuit, err := bdd.NewManager(len(vars)).Build(c, vars)
+	if err != nil {
+		t.Fatalf("bdd.Build failed: %v", err)
+	}
+	return func(inputs []bool) []bool {
+		outputs := make([]bool, len(circuit.Outputs))
+		for i, output := range circuit.Outputs {
+			outputs[i] = circuit.Manager.Eval(output, inputs)
+		}
+		return outputs
+	}
+}
+
+func checkTable(t *testing.T, table *Table, c *logic.Component) {
+	t.Helper()
+	eval := evaluate(t, c)
+	n := table.InputWires()
+	for m := 0; m < 1<<n; m++ {
+		inputs := make([]bool, n)
+		for i := range inputs {
+			inputs[i] = (m>>i)&1 == 1
+		}
+		outputs := eval(inputs)
+		for j := range outputs {
+			expected := table.Default
+			dontCare := false
+			for _, row := range table.Rows {
+				if !intersects(row.Inputs, mintermTrits(m, n)) || row.Outputs[j] == UNSPECIFIED {
+					continue
+				} else if row.Outputs[j] == DONT_CARE {
+					dontCare = true
+				} else {
+					expected = row.Outputs[j]
+				}
+			}
+			if dontCare || expected == DONT_CARE {
+				continue
+			} else if outputs[j] != (expected == ONE) {
+				t.Errorf("output %d for input %d: expected %v, got %v", j, m, expected, outputs[j])
+			}
+		}
+	}
+}
+
+func mintermTrits(m, n int) []Trit {
+	trits := make([]Trit, n)
+	for i := range trits {
+		trits[i] = Trit((m >> i) & 1)
+	}
+	return trits
+}
+
+func TestSynthesizeSevenSegment(t *testing.T) {
+	withModes(t, func(t *testing.T, opts Options) {
+		table := newSevenSegmentTable()
+		c, err := SynthesizeWithOptions(table, opts)
+		if err != nil {
+			t.Fatalf("Synthesize failed: %v", err)
+		}
+		checkTable(t, table, c)
+		if len(c.Instances) > 80 {
+			t.Errorf("expected at most 80 gates, got %d", len(c.Instances))
+		}
+	})
+}
+
+func TestSynthesizeConstantsAndLiterals(t *testing.T) {
+	a := logic.NewBus("a", 1)
+	b := logic.NewBus("b", 1)
+	r := logic.NewBus("r", 4)
+	table := NewTable("Misc", []*logic.Bus{a, b}, []*logic.Bus{r})
+	for m := 0; m < 4; m++ {
+		inputs := mintermTrits(m, 2)
+		outputs := []Trit{ZERO, ONE, inputs[0], 1 - inputs[1]}
+		if err := table.AddRow(inputs, outputs); err != nil {
+			t.Fatalf("AddRow failed: %v", err)
+		}
+	}
+	c, err := Synthesize(table)
+	if err != nil {
+		t.Fatalf("Synthesize failed: %v", err)
+	}
+	checkTable(t, table, c)
+	if len(c.Instances) != 4 {
+		t.Errorf("expected constants and 3 gates, got:\n%s", c)
+	}
+}
+
+func TestAddRowConflict(t *testing.T) {
+	table := NewTable("Conflict", []*logic.Bus{logic.NewBus("a", 2)}, []*logic.Bus{logic.NewBus("r", 1)})
+	if err := table.AddRow([]Trit{ONE, DONT_CARE}, []Trit{ONE}); err != nil {
+		t.Fatalf("AddRow failed: %v", err)
+	}
+	if err := table.AddRow([]Trit{DONT_CARE, ONE}, []Trit{DONT_CARE}); err != nil {
+		t.Fatalf("AddRow failed: %v", err)
+	}
+	if err := table.AddRow([]Trit{ONE, ONE}, []Trit{ZERO}); err == nil {
+		t.Errorf("expected AddRow to report conflict")
+	}
+}
+
+func TestReadPLA(t *testing.T) {
+	src := `# full adder
+.i 3
+.o 2
+.ilb a b c-in
+.ob sum carry
+.p 7
+001 10
+010 10
+100 10
+111 11
+11- 01
+1-1 01
+-11 01
+.e
+`
+	table, err := ReadPLA(strings.NewReader(src), "FullAdder")
+	if err != nil {
+		t.Fatalf("ReadPLA failed: %v", err)
+	}
+	if table.Inputs[2].Name != "c_in" || table.Outputs[1].Name != "carry" {
+		t.Errorf("unexpected bus names: %s, %s", table.Inputs[2].Name, table.Outputs[1].Name)
+	}
+	c, err := Synthesize(table)
+	if err != nil {
+		t.Fatalf("Synthesize failed: %v", err)
+	}
+	eval := evaluate(t, c)
+	for m := 0; m < 8; m++ {
+		inputs := []bool{m&1 == 1, m&2 == 2, m&4 == 4}
+		count := m&1 + (m>>1)&1 + (m>>2)&1
+		outputs := eval(inputs)
+		if outputs[0] != (count%2 == 1) || outputs[1] != (count >= 2) {
+			t.Errorf("full adder for input %d: got %v", m, outputs)
+		}
+	}
+}
+
+func TestReadPLAExplicitOffSet(t *testing.T) {
+	src := `.i 2
+.o 1
+.type fr
+00 0
+11 1
+`
+	table, err := ReadPLA(strings.NewReader(src), "Fr")
+	if err != nil {
+		t.Fatalf("ReadPLA failed: %v", err)
+	}
+	if table.Default != DONT_CARE {
+		t.Errorf("expected unlisted minterms to be don't-care")
+	}
+	cover := Minimize(table.Function(0))
+	if len(cover) != 1 || cover[0].Literals() != 1 {
+		t.Errorf("expected single literal cover, got %v", cover)
+	}
+}
+
+func TestReadPLAErrors(t *testing.T) {
+	for _, src := range []string{
+		".o 1\n0 1\n",
+		".i 2\n.o 1\n0 1\n",
+		".i 1\n.o 1\n2x\n",
+		".i 1\n.o 1\n.ilb a b\n0 1\n",
+		".i 1\n.o 1\n.phase 1\n",
+		".i 1\n.o 1\n.type fr\n0 1\n0 0\n",
+	} {
+		if _, err := ReadPLA(strings.NewReader(src), "Bad"); err == nil {
+			t.Errorf("expected ReadPLA to fail for:\n%s", src)
+		}
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	for name, expected := range map[string]string{
+		"a":      "a",
+		"c-in":   "c_in",
+		"0x":     "_0x",
+		"x[3]":   "x_3_",
+		"table":  "table_",
+		"":       "_",
+		"'carry": "'carry",
+	} {
+		if actual := Identifier(name); actual != expected {
+			t.Errorf("Identifier(%q): expected %q, got %q", name, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package synth
+
+import (
+	"fmt"
+
+	"github.com/arneph/mercury/logic"
+)
+
+type Trit int8
+
+const (
+	ZERO Trit = iota
+	ONE
+	DONT_CARE
+	UNSPECIFIED
+)
+
+func (t Trit) String() string {
+	switch t {
+	case ZERO:
+		return "0"
+	case ONE:
+		return "1"
+	case DONT_CARE:
+		return "-"
+	case UNSPECIFIED:
+		return "~"
+	default:
+		panic(fmt.Errorf("unexpected synth.Trit: %d", t))
+	}
+}
+
+type Row struct {
+	Inputs  []Trit
+	Outputs []Trit
+}
+
+type Table struct {
+	Name    string
+	Inputs  []*logic.Bus
+	Outputs []*logic.Bus
+	Rows    []Row
+	Default Trit
+}
+
+func NewTable(name string, inputs, outputs []*logic.Bus) *Table {
+	return &Table{
+		Name:    name,
+		Inputs:  inputs,
+		Outputs: outputs,
+		Default: ZERO,
+	}
+}
+
+func (t *Table) InputWires() int {
+	wires := 0
+	for _, bus := range t.Inputs {
+		wires += bus.Wires()
+	}
+	return wires
+}
+
+func (t *Table) OutputWires() int {
+	wires := 0
+	for _, bus := range t.Outputs {
+		wires += bus.Wires()
+	}
+	return wires
+}
+
+func (t *Table) AddRow(inputs, outputs []Trit) error {
+	if len(inputs) != t.InputWires() {
+		return fmt.Errorf("wrong number of input wires: expected %d, got %d", t.InputWires(), len(inputs))
+	} else if len(outputs) != t.OutputWires() {
+		return fmt.Errorf("wrong number of output wires: expected %d, got %d", t.OutputWires(), len(outputs))
+	}
+	row := Row{Inputs: inputs, Outputs: outputs}
+	for i, other := range t.Rows {
+		if !intersects(row.Inputs, other.Inputs) {
+			continue
+		}
+		for j := range outputs {
+			if (outputs[j] == ZERO && other.Outputs[j] == ONE) || (outputs[j] == ONE && other.Outputs[j] == ZERO) {
+				return fmt.Errorf("row conflicts with row %d in output wire %d", i+1, j)
+			}
+		}
+	}
+	t.Rows = append(t.Rows, row)
+	return nil
+}
+
+func intersects(a, b []Trit) bool {
+	for i := range a {
+		if a[i] != DONT_CARE && b[i] != DONT_CARE && a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Table) Function(output int) Function {
+	f := Function{
+		Inputs:      t.InputWires(),
+		ExplicitOff: t.Default == DONT_CARE,
+	}
+	for _, row := range t.Rows {
+		c := rowCube(row.Inputs)
+		switch row.Outputs[output] {
+		case ZERO:
+			f.Off = append(f.Off, c)
+		case ONE:
+			f.On = append(f.On, c)
+		case DONT_CARE:
+			f.DontCare = append(f.DontCare, c)
+		}
+	}
+	return f
+}
+
+func rowCube(inputs []Trit) Cube {
+	var c Cube
+	for i, input := range inputs {
+		bit := uint64(1) << i
+		switch input {
+		case ZERO:
+			c.Care |= bit
+		case ONE:
+			c.Care |= bit
+			c.Value |= bit
+		}
+	}
+	return c
+}
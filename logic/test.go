@@ -28,6 +28,10 @@ func (t *Test) Name() string {
 	return t.name
 }
 
+func (t *Test) Pos() positions.Pos {
+	return t.pos
+}
+
 type SetInputs struct {
 	pos    positions.Pos
 	Inputs *Constants
@@ -79,3 +83,37 @@ func NewCheckOutputsStep(pos positions.Pos, kind CheckKind, outputs *Constants)
 func (c *CheckOutputs) Pos() positions.Pos {
 	return c.pos
 }
+
+type ApplyVectors struct {
+	pos  positions.Pos
+	Path string
+}
+
+func NewApplyVectorsStep(pos positions.Pos, path string) *ApplyVectors {
+	return &ApplyVectors{
+		pos:  pos,
+		Path: path,
+	}
+}
+
+func (a *ApplyVectors) Pos() positions.Pos {
+	return a.pos
+}
+
+type CheckEquivalence struct {
+	pos positions.Pos
+	A   *Component
+	B   *Component
+}
+
+func NewCheckEquivalenceStep(pos positions.Pos, a, b *Component) *CheckEquivalence {
+	return &CheckEquivalence{
+		pos: pos,
+		A:   a,
+		B:   b,
+	}
+}
+
+func (c *CheckEquivalence) Pos() positions.Pos {
+	return c.pos
+}
@@ -31,3 +31,16 @@ func (n *Number) End() positions.Pos {
 }
 
 func (n *Number) expr() {}
+
+type String struct {
+	Value string
+	Start positions.Pos
+}
+
+func (s *String) Pos() positions.Pos {
+	return s.Start
+}
+
+func (s *String) End() positions.Pos {
+	return s.Start + positions.Pos(len(s.Value))
+}
@@ -0,0 +1,41 @@
+package ast
+
+import positions "go/token"
+
+type Table struct {
+	Table        positions.Pos
+	Name         *Identifier
+	InputLParen  positions.Pos
+	Inputs       *BusDefinitionList
+	InputRParen  positions.Pos
+	OutputLParen positions.Pos
+	Outputs      *BusDefinitionList
+	OutputRParen positions.Pos
+	LBrace       positions.Pos
+	Rows         []*TableRow
+	RBrace       positions.Pos
+}
+
+func (t *Table) Pos() positions.Pos {
+	return t.Table
+}
+
+func (t *Table) End() positions.Pos {
+	return t.RBrace + 1
+}
+
+func (t *Table) fileNode() {}
+
+type TableRow struct {
+	Inputs  []Expr
+	Colon   positions.Pos
+	Outputs []Expr
+}
+
+func (r *TableRow) Pos() positions.Pos {
+	return r.Inputs[0].Pos()
+}
+
+func (r *TableRow) End() positions.Pos {
+	return r.Outputs[len(r.Outputs)-1].End()
+}
@@ -91,3 +91,35 @@ func (e *Expectation) End() positions.Pos {
 }
 
 func (e *Expectation) testEntry() {}
+
+type VectorsInstr struct {
+	Vectors positions.Pos
+	Path    *String
+}
+
+func (v *VectorsInstr) Pos() positions.Pos {
+	return v.Vectors
+}
+
+func (v *VectorsInstr) End() positions.Pos {
+	return v.Path.End()
+}
+
+func (v *VectorsInstr) testEntry() {}
+
+type EquivInstr struct {
+	Equiv positions.Pos
+	Lhs   *Identifier
+	Comma positions.Pos
+	Rhs   *Identifier
+}
+
+func (e *EquivInstr) Pos() positions.Pos {
+	return e.Equiv
+}
+
+func (e *EquivInstr) End() positions.Pos {
+	return e.Rhs.End()
+}
+
+func (e *EquivInstr) testEntry() {}
@@ -4,16 +4,23 @@ import (
 	"fmt"
 	errors "go/scanner"
 	positions "go/token"
-	"math"
+	"math/bits"
+	"path/filepath"
 	"strconv"
 
 	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/synth"
 	"github.com/arneph/mercury/logic/text/ast"
 	"github.com/arneph/mercury/logic/text/parse"
 	"github.com/arneph/mercury/logic/text/tokens"
+	"github.com/arneph/mercury/logic/vectors"
 )
 
 func BuildFromFile(posFile *positions.File, src []byte) (*logic.System, errors.ErrorList) {
+	return BuildFromFileIntoSystem(posFile, src, logic.NewSystem())
+}
+
+func BuildFromFileIntoSystem(posFile *positions.File, src []byte, system *logic.System) (*logic.System, errors.ErrorList) {
 	astFile, errs := parse.ParseFile(posFile, src)
 	if errs.Len() > 0 {
 		return nil, errs
@@ -21,7 +28,7 @@ func BuildFromFile(posFile *positions.File, src []byte) (*logic.System, errors.E
 	b := &builder{
 		posFile: posFile,
 		astFile: astFile,
-		system:  logic.NewSystem(),
+		system:  system,
 	}
 	for _, astFileNode := range astFile.Nodes {
 		b.buildFileNodeDeclaration(astFileNode)
@@ -46,6 +53,11 @@ func (b *builder) buildFileNodeDeclaration(astFileNode ast.FileNode) {
 		if component != nil {
 			b.system.Components[component.Name()] = component
 		}
+	case *ast.Table:
+		component := b.buildTable(astFileNode)
+		if component != nil {
+			b.system.Components[component.Name()] = component
+		}
 	case *ast.Test:
 		break
 	default:
@@ -58,6 +70,8 @@ func (b *builder) buildFileNodeDefinition(astFileNode ast.FileNode) {
 	case *ast.Component:
 		component := b.system.Components[astFileNode.Name.Name]
 		b.buildComponentInstances(astFileNode, component)
+	case *ast.Table:
+		break
 	case *ast.Test:
 		test := b.buildTest(astFileNode)
 		if test != nil {
@@ -100,6 +114,11 @@ func (b *builder) buildComponentInstances(astComponent *ast.Component, c *logic.
 		case *ast.ForLoop:
 			cis := cb.buildForLoop(astEntry)
 			c.Instances = append(c.Instances, cis...)
+		case *ast.ConstantsInstace:
+			ci := cb.buildConstantsInstance(astEntry)
+			if ci != nil {
+				c.Instances = append(c.Instances, ci)
+			}
 		case *ast.ComponentInstance:
 			ci := cb.buildComponentInstance(astEntry)
 			if ci != nil {
@@ -111,6 +130,97 @@ func (b *builder) buildComponentInstances(astComponent *ast.Component, c *logic.
 	}
 }
 
+func (b *builder) buildTable(astTable *ast.Table) *logic.Component {
+	name := astTable.Name.Name
+	if _, ok := b.system.Components[name]; ok {
+		b.errs.Add(b.posFile.Position(astTable.Name.Pos()), fmt.Sprintf("redefinition of component: %s", name))
+		return nil
+	}
+	cb := b.newComponentBuilder()
+	var inputs, outputs []*logic.Bus
+	if astTable.Inputs != nil {
+		for _, astBus := range astTable.Inputs.Defintions {
+			input := cb.addBus(astBus)
+			if input == nil {
+				return nil
+			}
+			inputs = append(inputs, input)
+		}
+	}
+	if astTable.Outputs == nil {
+		b.errs.Add(b.posFile.Position(astTable.OutputLParen), "table has no outputs")
+		return nil
+	}
+	for _, astBus := range astTable.Outputs.Defintions {
+		output := cb.addBus(astBus)
+		if output == nil {
+			return nil
+		}
+		outputs = append(outputs, output)
+	}
+	t := synth.NewTable(name, inputs, outputs)
+	for _, astRow := range astTable.Rows {
+		rowInputs, ok := b.buildTableValues(astRow.Inputs, inputs, "input")
+		if !ok {
+			continue
+		}
+		rowOutputs, ok := b.buildTableValues(astRow.Outputs, outputs, "output")
+		if !ok {
+			continue
+		}
+		if err := t.AddRow(rowInputs, rowOutputs); err != nil {
+			b.errs.Add(b.posFile.Position(astRow.Pos()), err.Error())
+		}
+	}
+	c, err := synth.Synthesize(t)
+	if err != nil {
+		b.errs.Add(b.posFile.Position(astTable.Pos()), err.Error())
+		return nil
+	}
+	return c
+}
+
+func (b *builder) buildTableValues(astValues []ast.Expr, buses []*logic.Bus, kind string) ([]synth.Trit, bool) {
+	if len(astValues) != len(buses) {
+		b.errs.Add(b.posFile.Position(astValues[0].Pos()), fmt.Sprintf("wrong number of %s values: expected %d, got %d", kind, len(buses), len(astValues)))
+		return nil, false
+	}
+	var trits []synth.Trit
+	for i, astValue := range astValues {
+		wires := buses[i].Wires()
+		switch astValue := astValue.(type) {
+		case *ast.Identifier:
+			if astValue.Name != "x" {
+				b.errs.Add(b.posFile.Position(astValue.Pos()), fmt.Sprintf("expected number or x, got: %s", astValue.Name))
+				return nil, false
+			}
+			for j := 0; j < wires; j++ {
+				trits = append(trits, synth.DONT_CARE)
+			}
+		case *ast.Number:
+			value, ok := b.evalInt(astValue)
+			if !ok {
+				return nil, false
+			}
+			if minWires := bits.Len(uint(value)); minWires > wires {
+				b.errs.Add(b.posFile.Position(astValue.Pos()), fmt.Sprintf("too many %s wires: expected %d, got at least %d", kind, wires, minWires))
+				return nil, false
+			}
+			for j := 0; j < wires; j++ {
+				if (value>>j)%2 == 1 {
+					trits = append(trits, synth.ONE)
+				} else {
+					trits = append(trits, synth.ZERO)
+				}
+			}
+		default:
+			b.errs.Add(b.posFile.Position(astValue.Pos()), fmt.Sprintf("unexpected ast.Expr: %v", astValue))
+			return nil, false
+		}
+	}
+	return trits, true
+}
+
 func (b *builder) newComponentBuilder() *componentBuilder {
 	return &componentBuilder{
 		builder: b,
@@ -327,6 +437,11 @@ func (b *componentBuilder) buildForLoop(astForLoop *ast.ForLoop) []*logic.Instan
 			case *ast.ForLoop:
 				cis := b.buildForLoop(astEntry)
 				instances = append(instances, cis...)
+			case *ast.ConstantsInstace:
+				ci := b.buildConstantsInstance(astEntry)
+				if ci != nil {
+					instances = append(instances, ci)
+				}
 			case *ast.ComponentInstance:
 				ci := b.buildComponentInstance(astEntry)
 				if ci != nil {
@@ -341,6 +456,43 @@ func (b *componentBuilder) buildForLoop(astForLoop *ast.ForLoop) []*logic.Instan
 	return instances
 }
 
+func (b *componentBuilder) buildConstantsInstance(astConstantsInstance *ast.ConstantsInstace) *logic.Instance {
+	astReferences := astConstantsInstance.Outputs.References
+	astValues := astConstantsInstance.Constants.Values
+	if len(astValues) != len(astReferences) {
+		b.errs.Add(b.posFile.Position(astConstantsInstance.Constants.Pos()), fmt.Sprintf("wrong number of constant values: expected %d, got %d", len(astReferences), len(astValues)))
+		return nil
+	}
+	var outputs []logic.BusWire
+	var values []logic.Value
+	for i, astReference := range astReferences {
+		wires := b.buildBusReference(astReference, DEFINITION_ALLOWED)
+		if wires == nil {
+			return nil
+		}
+		value, ok := b.evalInt(astValues[i])
+		if !ok {
+			return nil
+		}
+		if minWires := bits.Len(uint(value)); minWires > len(wires) {
+			b.errs.Add(b.posFile.Position(astValues[i].Pos()), fmt.Sprintf("too many output wires: expected %d, got at least %d", len(wires), minWires))
+			return nil
+		}
+		v := make(logic.Value, len(wires))
+		for j := range v {
+			v[j] = (value>>j)%2 == 1
+		}
+		outputs = append(outputs, wires...)
+		values = append(values, v)
+	}
+	return &logic.Instance{
+		Definition: logic.NewConstants(values),
+		Inputs:     nil,
+		Outputs:    outputs,
+		Pos:        astConstantsInstance.Pos(),
+	}
+}
+
 func (b *componentBuilder) buildComponentInstance(astComponentInstance *ast.ComponentInstance) *logic.Instance {
 	componentName := astComponentInstance.DefinitionName.Name
 	var def logic.Definition
@@ -349,6 +501,10 @@ func (b *componentBuilder) buildComponentInstance(astComponentInstance *ast.Comp
 		def = logic.Nand
 		expectedInputs = 2
 		expectedOutputs = 1
+	} else if componentName == "tri" {
+		def = logic.Tri
+		expectedInputs = 2
+		expectedOutputs = 1
 	} else {
 		component, ok := b.system.Components[componentName]
 		if !ok {
@@ -377,6 +533,7 @@ func (b *componentBuilder) buildComponentInstance(astComponentInstance *ast.Comp
 		Definition: def,
 		Inputs:     inputs,
 		Outputs:    outputs,
+		Pos:        astComponentInstance.Pos(),
 	}
 }
 
@@ -426,6 +583,16 @@ func (b *builder) buildTest(astTest *ast.Test) *logic.Test {
 			if s != nil {
 				t.Steps = append(t.Steps, s)
 			}
+		case *ast.VectorsInstr:
+			s := tb.buildVectorsInstr(astTestEntry)
+			if s != nil {
+				t.Steps = append(t.Steps, s)
+			}
+		case *ast.EquivInstr:
+			s := tb.buildEquivInstr(astTestEntry)
+			if s != nil {
+				t.Steps = append(t.Steps, s)
+			}
 		default:
 			b.errs.Add(b.posFile.Position(astTestEntry.Pos()), fmt.Sprintf("unexpected ast.TestEntry: %v", astTestEntry))
 		}
@@ -445,7 +612,18 @@ type testBuilder struct {
 	test *logic.Test
 }
 
+func (b *testBuilder) checkComponentDeclared(astTestEntry ast.TestEntry) bool {
+	if b.test.Component == nil {
+		b.errs.Add(b.posFile.Position(astTestEntry.Pos()), "test component is undeclared")
+		return false
+	}
+	return true
+}
+
 func (b *testBuilder) buildSetInstr(astSetInstr *ast.SetInstr) *logic.SetInputs {
+	if !b.checkComponentDeclared(astSetInstr) {
+		return nil
+	}
 	expectedBuses := len(b.test.Component.InputBusNames)
 	actualBuses := len(astSetInstr.Inputs.References)
 	if actualBuses > expectedBuses {
@@ -481,7 +659,7 @@ func (b *testBuilder) buildSetInstr(astSetInstr *ast.SetInstr) *logic.SetInputs
 		expectedWires := bus.Wires()
 		minActualWires := 1
 		if value > 0 {
-			minActualWires = 1 + int(math.Ceil(math.Log2(float64(value))))
+			minActualWires = bits.Len(uint(value))
 		}
 		if minActualWires > expectedWires {
 			b.errs.Add(b.posFile.Position(astSetInstr.Constants.Values[i].Pos()), fmt.Sprintf("too many input wires: expected %d, got at least %d", expectedWires, minActualWires))
@@ -497,6 +675,9 @@ func (b *testBuilder) buildSetInstr(astSetInstr *ast.SetInstr) *logic.SetInputs
 }
 
 func (b *testBuilder) buildAssertion(astAssertion *ast.Assertion) *logic.CheckOutputs {
+	if !b.checkComponentDeclared(astAssertion) {
+		return nil
+	}
 	expectedBuses := len(b.test.Component.OutputBusNames)
 	actualBuses := len(astAssertion.Outputs.References)
 	if actualBuses > expectedBuses {
@@ -532,7 +713,7 @@ func (b *testBuilder) buildAssertion(astAssertion *ast.Assertion) *logic.CheckOu
 		expectedWires := bus.Wires()
 		minActualWires := 1
 		if value > 0 {
-			minActualWires = 1 + int(math.Ceil(math.Log2(float64(value))))
+			minActualWires = bits.Len(uint(value))
 		}
 		if minActualWires > expectedWires {
 			b.errs.Add(b.posFile.Position(astAssertion.Constants.Values[i].Pos()), fmt.Sprintf("too many output wires: expected %d, got at least %d", expectedWires, minActualWires))
@@ -548,6 +729,9 @@ func (b *testBuilder) buildAssertion(astAssertion *ast.Assertion) *logic.CheckOu
 }
 
 func (b *testBuilder) buildExpectation(astExpectation *ast.Expectation) *logic.CheckOutputs {
+	if !b.checkComponentDeclared(astExpectation) {
+		return nil
+	}
 	expectedBuses := len(b.test.Component.OutputBusNames)
 	actualBuses := len(astExpectation.Outputs.References)
 	if actualBuses > expectedBuses {
@@ -583,7 +767,7 @@ func (b *testBuilder) buildExpectation(astExpectation *ast.Expectation) *logic.C
 		expectedWires := bus.Wires()
 		minActualWires := 1
 		if value > 0 {
-			minActualWires = 1 + int(math.Ceil(math.Log2(float64(value))))
+			minActualWires = bits.Len(uint(value))
 		}
 		if minActualWires > expectedWires {
 			b.errs.Add(b.posFile.Position(astExpectation.Constants.Values[i].Pos()), fmt.Sprintf("too many output wires: expected %d, got at least %d", expectedWires, minActualWires))
@@ -597,3 +781,37 @@ func (b *testBuilder) buildExpectation(astExpectation *ast.Expectation) *logic.C
 	}
 	return logic.NewCheckOutputsStep(astExpectation.Pos(), logic.EXPECT, logic.NewConstants(cs))
 }
+
+func (b *testBuilder) buildVectorsInstr(astVectorsInstr *ast.VectorsInstr) *logic.ApplyVectors {
+	if !b.checkComponentDeclared(astVectorsInstr) {
+		return nil
+	}
+	path := astVectorsInstr.Path.Value[1 : len(astVectorsInstr.Path.Value)-1]
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(b.posFile.Name()), path)
+	}
+	r, err := vectors.Open(path, b.test.Component)
+	if err != nil {
+		b.errs.Add(b.posFile.Position(astVectorsInstr.Path.Pos()), fmt.Sprintf("could not open vectors file: %v", err))
+		return nil
+	}
+	r.Close()
+	return logic.NewApplyVectorsStep(astVectorsInstr.Pos(), path)
+}
+
+func (b *testBuilder) buildEquivInstr(astEquivInstr *ast.EquivInstr) *logic.CheckEquivalence {
+	var components [2]*logic.Component
+	for i, astName := range []*ast.Identifier{astEquivInstr.Lhs, astEquivInstr.Rhs} {
+		c, ok := b.system.Components[astName.Name]
+		if !ok {
+			b.errs.Add(b.posFile.Position(astName.Pos()), fmt.Sprintf("undefined component: %s", astName.Name))
+			return nil
+		}
+		components[i] = c
+	}
+	if err := logic.ComparePorts(components[0], components[1]); err != nil {
+		b.errs.Add(b.posFile.Position(astEquivInstr.Pos()), fmt.Sprintf("incompatible components: %v", err))
+		return nil
+	}
+	return logic.NewCheckEquivalenceStep(astEquivInstr.Pos(), components[0], components[1])
+}
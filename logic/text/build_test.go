@@ -0,0 +1,43 @@
+package text
+
+import (
+	positions "go/token"
+	"testing"
+)
+
+func TestTestValueWidth(t *testing.T) {
+	for _, c := range []struct {
+		instr string
+		ok    bool
+	}{
+		{"set a: 0", true},
+		{"set a: 1", true},
+		{"set a: 2", true},
+		{"set a: 3", true},
+		{"set a: 4", false},
+		{"expect r is 3", true},
+		{"expect r is 4", false},
+		{"assert r is 3", true},
+		{"assert r is 4", false},
+	} {
+		src := `
+component Id(a[2])(r[2]) {
+    r[0]: nand(a[0], a[0])
+    r[1]: nand(a[1], a[1])
+}
+
+test Id {
+    component: Id
+
+    ` + c.instr + `
+}
+`
+		fileSet := positions.NewFileSet()
+		file := fileSet.AddFile("test.mercury", fileSet.Base(), len(src))
+		file.SetLinesForContent([]byte(src))
+		_, errs := BuildFromFile(file, []byte(src))
+		if ok := errs.Len() == 0; ok != c.ok {
+			t.Errorf("%s: BuildFromFile() succeeded = %v; want %v: %v", c.instr, ok, c.ok, errs)
+		}
+	}
+}
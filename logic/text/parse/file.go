@@ -22,6 +22,11 @@ parseLoop:
 			if component != nil {
 				nodes = append(nodes, component)
 			}
+		case tokens.TABLE:
+			table := p.parseTable()
+			if table != nil {
+				nodes = append(nodes, table)
+			}
 		case tokens.TEST:
 			test := p.parseTest()
 			if test != nil {
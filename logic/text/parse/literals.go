@@ -44,3 +44,15 @@ func (p *parser) parseNumber() *ast.Number {
 		Start: pos,
 	}
 }
+
+func (p *parser) parseString() *ast.String {
+	pos, tok, lit := p.scanner.Scan(scan.EMIT_NEW_LINES)
+	if tok != tokens.STRING {
+		p.errs.Add(p.file().Position(pos), fmt.Sprintf("expected string, got: %s", lit))
+		return nil
+	}
+	return &ast.String{
+		Value: lit,
+		Start: pos,
+	}
+}
@@ -8,6 +8,8 @@ import (
 func FuzzParser(f *testing.F) {
 	f.Add([]byte("component Nand(a, b) (r) {\nr: rand(a, b)\n}"))
 	f.Add([]byte("test NandTest {\ncomponent: Nand\nset a, b: 0, 1\nexpect r is 42\n}"))
+	f.Add([]byte("test VectorsTest {\ncomponent: Add\nvectors \"add.csv\"\n}"))
+	f.Add([]byte("table Not(a)(r) {\n0: 1\n1: 0\n}"))
 	f.Fuzz(func(t *testing.T, in []byte) {
 		fileSet := positions.NewFileSet()
 		file := fileSet.AddFile("fake.mercury", fileSet.Base(), len(in))
@@ -0,0 +1,119 @@
+package parse
+
+import (
+	"fmt"
+	positions "go/token"
+
+	"github.com/arneph/mercury/logic/text/ast"
+	"github.com/arneph/mercury/logic/text/scan"
+	"github.com/arneph/mercury/logic/text/tokens"
+)
+
+func (p *parser) parseTable() *ast.Table {
+	table, tok, lit := p.scanner.Scan(scan.SKIP_NEW_LINES)
+	if tok != tokens.TABLE {
+		p.errs.Add(p.file().Position(table), fmt.Sprintf("expected 'table', got: %s", lit))
+		return nil
+	}
+	name := p.parseIdentifier(scan.EMIT_NEW_LINES)
+	if name == nil {
+		return nil
+	}
+	inputsInfo := p.parseComponentInputsOrOutputs()
+	if inputsInfo.lParen == positions.NoPos {
+		return nil
+	}
+	outputsInfo := p.parseComponentInputsOrOutputs()
+	if outputsInfo.lParen == positions.NoPos {
+		return nil
+	}
+	lBrace, tok, lit := p.scanner.Scan(scan.EMIT_NEW_LINES)
+	if tok != tokens.LBRACE {
+		p.errs.Add(p.file().Position(lBrace), fmt.Sprintf("expected '{', got: %s", lit))
+		return nil
+	}
+	var rows []*ast.TableRow
+	var rBrace positions.Pos
+parseLoop:
+	for {
+		pos, tok, lit := p.scanner.Peek(scan.SKIP_NEW_LINES)
+		switch tok {
+		case tokens.RBRACE:
+			p.scanner.Scan(scan.EMIT_NEW_LINES)
+			rBrace = pos
+			break parseLoop
+		case tokens.IDENTIFIER, tokens.NUMBER:
+			row := p.parseTableRow()
+			if row == nil {
+				break
+			}
+			rows = append(rows, row)
+			if ok := p.parseNewLine(); ok {
+				continue parseLoop
+			}
+		default:
+			p.scanner.Scan(scan.EMIT_NEW_LINES)
+			p.errs.Add(p.file().Position(pos), fmt.Sprintf("unexpected token: %s", lit))
+		}
+		if ok := p.recoverToNewLine(); !ok {
+			return nil
+		}
+	}
+	return &ast.Table{
+		Table:        table,
+		Name:         name,
+		InputLParen:  inputsInfo.lParen,
+		Inputs:       inputsInfo.definitions,
+		InputRParen:  inputsInfo.rParen,
+		OutputLParen: outputsInfo.lParen,
+		Outputs:      outputsInfo.definitions,
+		OutputRParen: outputsInfo.rParen,
+		LBrace:       lBrace,
+		Rows:         rows,
+		RBrace:       rBrace,
+	}
+}
+
+func (p *parser) parseTableRow() *ast.TableRow {
+	for {
+		if _, tok, _ := p.scanner.Peek(scan.EMIT_NEW_LINES); tok != tokens.NEWLINE {
+			break
+		}
+		p.scanner.Scan(scan.EMIT_NEW_LINES)
+	}
+	inputs := p.parseTableValues()
+	if inputs == nil {
+		return nil
+	}
+	colon, tok, lit := p.scanner.Scan(scan.EMIT_NEW_LINES)
+	if tok != tokens.COLON {
+		p.errs.Add(p.file().Position(colon), fmt.Sprintf("expected ':', got: %s", lit))
+		return nil
+	}
+	outputs := p.parseTableValues()
+	if outputs == nil {
+		return nil
+	}
+	return &ast.TableRow{
+		Inputs:  inputs,
+		Colon:   colon,
+		Outputs: outputs,
+	}
+}
+
+func (p *parser) parseTableValues() []ast.Expr {
+	var values []ast.Expr
+	for {
+		value := p.parseLiteral()
+		if value == nil {
+			return nil
+		}
+		values = append(values, value)
+		_, tok, _ := p.scanner.Peek(scan.EMIT_NEW_LINES)
+		if tok != tokens.COMMA {
+			break
+		}
+		p.scanner.Scan(scan.EMIT_NEW_LINES)
+	}
+	return values
+}
@@ -69,6 +69,24 @@ parseLoop:
 			if ok := p.parseNewLine(); ok {
 				continue parseLoop
 			}
+		case tokens.VECTORS:
+			vectorsInstr := p.parseVectorsInstr()
+			if vectorsInstr == nil {
+				break
+			}
+			entries = append(entries, vectorsInstr)
+			if ok := p.parseNewLine(); ok {
+				continue parseLoop
+			}
+		case tokens.EQUIV:
+			equivInstr := p.parseEquivInstr()
+			if equivInstr == nil {
+				break
+			}
+			entries = append(entries, equivInstr)
+			if ok := p.parseNewLine(); ok {
+				continue parseLoop
+			}
 		default:
 			p.scanner.Scan(scan.EMIT_NEW_LINES)
 			p.errs.Add(p.file().Position(pos), fmt.Sprintf("unexpected token: %s", lit))
@@ -188,3 +206,46 @@ func (p *parser) parseExpectation() *ast.Expectation {
 		Constants: constants,
 	}
 }
+
+func (p *parser) parseVectorsInstr() *ast.VectorsInstr {
+	vectors, tok, lit := p.scanner.Scan(scan.SKIP_NEW_LINES)
+	if tok != tokens.VECTORS {
+		p.errs.Add(p.file().Position(vectors), fmt.Sprintf("expected 'vectors', got: %s", lit))
+		return nil
+	}
+	path := p.parseString()
+	if path == nil {
+		return nil
+	}
+	return &ast.VectorsInstr{
+		Vectors: vectors,
+		Path:    path,
+	}
+}
+
+func (p *parser) parseEquivInstr() *ast.EquivInstr {
+	equiv, tok, lit := p.scanner.Scan(scan.SKIP_NEW_LINES)
+	if tok != tokens.EQUIV {
+		p.errs.Add(p.file().Position(equiv), fmt.Sprintf("expected 'equiv', got: %s", lit))
+		return nil
+	}
+	lhs := p.parseIdentifier(scan.EMIT_NEW_LINES)
+	if lhs == nil {
+		return nil
+	}
+	comma, tok, lit := p.scanner.Scan(scan.EMIT_NEW_LINES)
+	if tok != tokens.COMMA {
+		p.errs.Add(p.file().Position(comma), fmt.Sprintf("expected ',', got: %s", lit))
+		return nil
+	}
+	rhs := p.parseIdentifier(scan.EMIT_NEW_LINES)
+	if rhs == nil {
+		return nil
+	}
+	return &ast.EquivInstr{
+		Equiv: equiv,
+		Lhs:   lhs,
+		Comma: comma,
+		Rhs:   rhs,
+	}
+}
@@ -95,6 +95,16 @@ func (s *Scanner) Scan(newLineMode NewLineMode) (pos positions.Pos, tok tokens.T
 	case ':':
 		tok = tokens.COLON
 		lit = ":"
+	case '"':
+		lit = "\""
+		tok = tokens.ERROR
+		for i := s.offset + 1; i < len(s.src) && s.src[i] != '\n'; i++ {
+			lit += string(s.src[i])
+			if s.src[i] == '"' {
+				tok = tokens.STRING
+				break
+			}
+		}
 	default:
 		lit = string(ch)
 		if isIdentifierStart(ch) {
@@ -122,6 +132,12 @@ func (s *Scanner) Scan(newLineMode NewLineMode) (pos positions.Pos, tok tokens.T
 				tok = tokens.FROM
 			case "to":
 				tok = tokens.TO
+			case "vectors":
+				tok = tokens.VECTORS
+			case "equiv":
+				tok = tokens.EQUIV
+			case "table":
+				tok = tokens.TABLE
 			default:
 				tok = tokens.IDENTIFIER
 			}
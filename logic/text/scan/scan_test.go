@@ -165,6 +165,18 @@ func TestIndividualTokens(t *testing.T) {
 			src:         []byte("to"),
 			expectedTok: tokens.TO,
 		},
+		{
+			src:         []byte("vectors"),
+			expectedTok: tokens.VECTORS,
+		},
+		{
+			src:         []byte("equiv"),
+			expectedTok: tokens.EQUIV,
+		},
+		{
+			src:         []byte("table"),
+			expectedTok: tokens.TABLE,
+		},
 		{
 			src:         []byte("0"),
 			expectedTok: tokens.NUMBER,
@@ -189,6 +201,18 @@ func TestIndividualTokens(t *testing.T) {
 			src:         []byte("0x"),
 			expectedTok: tokens.ERROR,
 		},
+		{
+			src:         []byte("\"adder.csv\""),
+			expectedTok: tokens.STRING,
+		},
+		{
+			src:         []byte("\"\""),
+			expectedTok: tokens.STRING,
+		},
+		{
+			src:         []byte("\"adder.csv"),
+			expectedTok: tokens.ERROR,
+		},
 	}
 	for _, testcase := range testcases {
 		fileSet := positions.NewFileSet()
@@ -381,7 +405,7 @@ func FuzzScanner(f *testing.F) {
 			if pos < file.Pos(0) || pos > file.Pos(len(in)) {
 				t.Fatalf("pos = %v; want between %v and %v", pos, file.Pos(0), file.Pos(len(in)))
 			}
-			if tok < tokens.ERROR || tok > tokens.TO {
+			if tok < tokens.ERROR || tok > tokens.TABLE {
 				t.Fatalf("tok = %v; want defined token value", tok)
 			}
 			if tok == tokens.EOF {
@@ -8,6 +8,7 @@ const (
 	NEWLINE
 	IDENTIFIER
 	NUMBER
+	STRING
 
 	// Operators and delimiters
 	ADD // +
@@ -38,4 +39,7 @@ const (
 	FOR
 	FROM
 	TO
+	VECTORS
+	EQUIV
+	TABLE
 )
@@ -0,0 +1,39 @@
+package logic
+
+type TriGate struct{}
+
+var Tri = TriGate{}
+
+func (g TriGate) Name() string {
+	return "tri"
+}
+
+func (g TriGate) InputNames() []string {
+	return []string{"en", "d"}
+}
+
+func (g TriGate) OutputNames() []string {
+	return []string{"r"}
+}
+
+func (c *Component) HasTriGates() bool {
+	return hasTriGates(c, make(map[*Component]bool))
+}
+
+func hasTriGates(c *Component, visited map[*Component]bool) bool {
+	if visited[c] {
+		return false
+	}
+	visited[c] = true
+	for _, instance := range c.Instances {
+		switch def := instance.Definition.(type) {
+		case TriGate:
+			return true
+		case *Component:
+			if hasTriGates(def, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
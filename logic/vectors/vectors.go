@@ -0,0 +1,226 @@
+package vectors
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+type Row struct {
+	Line    int
+	Inputs  []Pattern
+	Outputs []Pattern
+}
+
+type Pattern struct {
+	Value logic.Value
+	Care  []bool
+}
+
+func (p Pattern) Specified(i int) bool {
+	return p.Care != nil && p.Care[i]
+}
+
+func (p Pattern) String() string {
+	if p.Care == nil {
+		return "x"
+	}
+	n := new(big.Int)
+	var sb strings.Builder
+	partial := false
+	for i := len(p.Value) - 1; i >= 0; i-- {
+		switch {
+		case !p.Care[i]:
+			sb.WriteByte('x')
+			partial = true
+		case p.Value[i]:
+			sb.WriteByte('1')
+			n.SetBit(n, i, 1)
+		default:
+			sb.WriteByte('0')
+		}
+	}
+	if partial {
+		return sb.String()
+	}
+	return n.String()
+}
+
+type Reader struct {
+	file      *os.File
+	csv       *csv.Reader
+	component *logic.Component
+	columns   []column
+}
+
+type columnKind int
+
+const (
+	INPUT columnKind = iota
+	OUTPUT
+)
+
+type column struct {
+	kind  columnKind
+	index int
+	bus   *logic.Bus
+}
+
+func Open(path string, c *logic.Component) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{
+		file:      file,
+		csv:       csv.NewReader(file),
+		component: c,
+	}
+	r.csv.Comment = '#'
+	r.csv.TrimLeadingSpace = true
+	if err := r.readHeader(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
+
+func (r *Reader) readHeader() error {
+	record, err := r.csv.Read()
+	if err == io.EOF {
+		return fmt.Errorf("missing header")
+	} else if err != nil {
+		return err
+	}
+	line, _ := r.csv.FieldPos(0)
+	inputIndices := make(map[string]int)
+	for i, name := range r.component.InputBusNames {
+		inputIndices[name] = i
+	}
+	outputIndices := make(map[string]int)
+	for i, name := range r.component.OutputBusNames {
+		outputIndices[name] = i
+	}
+	seen := make(map[string]struct{})
+	for _, name := range record {
+		name = strings.TrimSpace(name)
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("line %d: repeated bus column: %s", line, name)
+		}
+		seen[name] = struct{}{}
+		if i, ok := inputIndices[name]; ok {
+			r.columns = append(r.columns, column{
+				kind:  INPUT,
+				index: i,
+				bus:   r.component.Buses[name],
+			})
+		} else if i, ok := outputIndices[name]; ok {
+			r.columns = append(r.columns, column{
+				kind:  OUTPUT,
+				index: i,
+				bus:   r.component.Buses[name],
+			})
+		} else {
+			return fmt.Errorf("line %d: bus column is neither input nor output of %s: %s", line, r.component.Name(), name)
+		}
+	}
+	return nil
+}
+
+func (r *Reader) Next() (*Row, error) {
+	record, err := r.csv.Read()
+	if err != nil {
+		return nil, err
+	}
+	line, _ := r.csv.FieldPos(0)
+	if len(record) != len(r.columns) {
+		return nil, fmt.Errorf("line %d: wrong number of cells: expected %d, got %d", line, len(r.columns), len(record))
+	}
+	row := &Row{
+		Line:    line,
+		Inputs:  make([]Pattern, len(r.component.InputBusNames)),
+		Outputs: make([]Pattern, len(r.component.OutputBusNames)),
+	}
+	for i, cell := range record {
+		col := r.columns[i]
+		value, err := parseCell(strings.TrimSpace(cell), col.bus.Wires())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bus %s: %v", line, col.bus.Name, err)
+		}
+		switch col.kind {
+		case INPUT:
+			row.Inputs[col.index] = value
+		case OUTPUT:
+			row.Outputs[col.index] = value
+		}
+	}
+	return row, nil
+}
+
+func parseCell(cell string, wires int) (Pattern, error) {
+	if cell == "x" || cell == "X" {
+		return Pattern{}, nil
+	} else if strings.HasPrefix(cell, "0b") || strings.HasPrefix(cell, "0B") {
+		return parseBinary(cell, wires)
+	}
+	digits, base := cell, 10
+	if strings.HasPrefix(cell, "0x") || strings.HasPrefix(cell, "0X") {
+		digits, base = cell[2:], 16
+	}
+	digits = strings.ReplaceAll(digits, "_", "")
+	n, ok := new(big.Int).SetString(digits, base)
+	if !ok || digits[0] == '+' || digits[0] == '-' {
+		return Pattern{}, fmt.Errorf("could not convert %q to value", cell)
+	} else if n.BitLen() > wires {
+		return Pattern{}, fmt.Errorf("value %q does not fit into %d wires", cell, wires)
+	}
+	p := newPattern(wires)
+	for i := range p.Value {
+		p.Value[i] = n.Bit(i) == 1
+	}
+	return p, nil
+}
+
+func parseBinary(cell string, wires int) (Pattern, error) {
+	digits := strings.ReplaceAll(cell[2:], "_", "")
+	if digits == "" {
+		return Pattern{}, fmt.Errorf("could not convert %q to value", cell)
+	}
+	p := newPattern(wires)
+	for i := 0; i < len(digits); i++ {
+		wire := len(digits) - 1 - i
+		switch digits[i] {
+		case '0':
+			continue
+		case '1', 'x', 'X':
+			if wire >= wires {
+				return Pattern{}, fmt.Errorf("value %q does not fit into %d wires", cell, wires)
+			}
+			p.Value[wire] = digits[i] == '1'
+			p.Care[wire] = digits[i] == '1'
+		default:
+			return Pattern{}, fmt.Errorf("could not convert %q to value: invalid binary digit %q", cell, digits[i])
+		}
+	}
+	return p, nil
+}
+
+func newPattern(wires int) Pattern {
+	p := Pattern{
+		Value: make(logic.Value, wires),
+		Care:  make([]bool, wires),
+	}
+	for i := range p.Care {
+		p.Care[i] = true
+	}
+	return p
+}
@@ -0,0 +1,180 @@
+package vectors
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeVectors(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "vectors.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("could not write vectors file: %v", err)
+	}
+	return path
+}
+
+func newAdder() *logic.Component {
+	return logic.NewComponent("Adder",
+		[]*logic.Bus{logic.NewBus("a", 4), logic.NewBus("b", 4)},
+		[]*logic.Bus{logic.NewBus("r", 4), logic.NewBus("c", 1)})
+}
+
+func TestReadsRows(t *testing.T) {
+	path := writeVectors(t, "r, a, b, c\n# comment\n0x9, 0b0100, 5, 0\nx, 15, 1, 1\n")
+	r, err := Open(path, newAdder())
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer r.Close()
+
+	row, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	assert.Equal(t, 3, row.Line)
+	assert.Equal(t, []string{"4", "5"}, patternStrings(row.Inputs))
+	assert.Equal(t, []string{"9", "0"}, patternStrings(row.Outputs))
+	assert.Equal(t, logic.Value{false, false, true, false}, row.Inputs[0].Value)
+
+	row, err = r.Next()
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	assert.Equal(t, 4, row.Line)
+	assert.Equal(t, []string{"15", "1"}, patternStrings(row.Inputs))
+	assert.Equal(t, []string{"x", "1"}, patternStrings(row.Outputs))
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestZeroPaddedDecimals(t *testing.T) {
+	path := writeVectors(t, "a, b, r\n010, 0x0f, 0_9\n")
+	r, err := Open(path, newAdder())
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer r.Close()
+
+	row, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	assert.Equal(t, []string{"10", "15"}, patternStrings(row.Inputs))
+	assert.Equal(t, []string{"9", "x"}, patternStrings(row.Outputs))
+}
+
+func TestMissingColumnsAreDontCare(t *testing.T) {
+	path := writeVectors(t, "a, c\n3, 1\n")
+	r, err := Open(path, newAdder())
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer r.Close()
+
+	row, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	assert.Equal(t, []string{"3", "x"}, patternStrings(row.Inputs))
+	assert.Equal(t, []string{"x", "1"}, patternStrings(row.Outputs))
+}
+
+func patternStrings(patterns []Pattern) []string {
+	strs := make([]string, len(patterns))
+	for i, p := range patterns {
+		strs[i] = p.String()
+	}
+	return strs
+}
+
+func TestBinaryDontCares(t *testing.T) {
+	path := writeVectors(t, "r, a\n0b1x0X, 0b0000_0110\n")
+	r, err := Open(path, newAdder())
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer r.Close()
+
+	row, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	assert.Equal(t, []string{"6", "x"}, patternStrings(row.Inputs))
+	assert.Equal(t, []string{"1x0x", "x"}, patternStrings(row.Outputs))
+	assert.Equal(t, []bool{false, true, false, true}, row.Outputs[0].Care)
+	assert.Equal(t, logic.Value{false, false, false, true}, row.Outputs[0].Value)
+}
+
+func TestWideBuses(t *testing.T) {
+	c := logic.NewComponent("Wide",
+		[]*logic.Bus{logic.NewBus("a", 100)},
+		[]*logic.Bus{logic.NewBus("r", 100)})
+	path := writeVectors(t, "a, r\n0x8000000000000000000000001, 633825300114114700748351602689\n0x10000000000000000000000000, 0\n")
+	r, err := Open(path, c)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer r.Close()
+
+	row, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	for _, p := range []Pattern{row.Inputs[0], row.Outputs[0]} {
+		for i, v := range p.Value {
+			if v != (i == 0 || i == 99) || !p.Specified(i) {
+				t.Fatalf("wire %d = %v; want %v", i, v, i == 0 || i == 99)
+			}
+		}
+	}
+	assert.Equal(t, "633825300114114700748351602689", row.Inputs[0].String())
+
+	if _, err := r.Next(); err == nil || err == io.EOF {
+		t.Errorf("Next() for 101-bit value = %v; want error", err)
+	}
+}
+
+func TestRejectsBadHeaders(t *testing.T) {
+	for _, content := range []string{
+		"",
+		"a, b, d\n",
+		"a, a\n",
+	} {
+		path := writeVectors(t, content)
+		if _, err := Open(path, newAdder()); err == nil {
+			t.Errorf("Open() for %q succeeded; want error", content)
+		}
+	}
+}
+
+func TestRejectsBadRows(t *testing.T) {
+	for _, content := range []string{
+		"a, b\n1\n",
+		"a, b\n1, 16\n",
+		"a, b\n1, y\n",
+		"a, b\n1, -1\n",
+		"a, b\n1, +1\n",
+		"a, b\n1, 0o7\n",
+		"a, b\n1, 0x\n",
+		"a, b\n1, 0b\n",
+		"a, b\n1, 0b102\n",
+		"a, b\n1, 0bx0000\n",
+		"a, b\n1, 0b10000\n",
+	} {
+		path := writeVectors(t, content)
+		r, err := Open(path, newAdder())
+		if err != nil {
+			t.Fatalf("Open() for %q failed: %v", content, err)
+		}
+		if _, err := r.Next(); err == nil || err == io.EOF {
+			t.Errorf("Next() for %q = %v; want error", content, err)
+		}
+		r.Close()
+	}
+}
@@ -0,0 +1,303 @@
+package verilog
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/arneph/mercury/logic"
+)
+
+func Write(w io.Writer, c *logic.Component) error {
+	var sb strings.Builder
+	written := make(map[*logic.Component]bool)
+	var writeHierarchy func(c *logic.Component)
+	writeHierarchy = func(c *logic.Component) {
+		if written[c] {
+			return
+		}
+		written[c] = true
+		for _, instance := range c.Instances {
+			if child, ok := instance.Definition.(*logic.Component); ok {
+				writeHierarchy(child)
+			}
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		newModuleWriter(&sb, c).writeModule(c)
+	}
+	writeHierarchy(c)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func WriteFlat(w io.Writer, c *logic.Component) error {
+	var sb strings.Builder
+	collapsed := c.Collapse(c.Name())
+	mw := newModuleWriter(&sb, c)
+	for name := range collapsed.Buses {
+		mw.usedNames[name] = true
+	}
+	for _, names := range [][]string{c.InputBusNames, c.OutputBusNames} {
+		for _, name := range names {
+			bus := c.Buses[name]
+			for i := 0; i < bus.Wires(); i++ {
+				collapsedName := logic.CollapsedWireName(bus, logic.WireIndex(i))
+				mw.exprs[collapsedName] = busWireExpr(logic.BusWire{
+					Bus:       bus,
+					WireIndex: logic.WireIndex(i),
+				})
+			}
+		}
+	}
+	mw.writeHeader(c)
+	mw.writeWires(collapsed)
+	mw.writeInstances(collapsed)
+	mw.writeFooter()
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+type moduleWriter struct {
+	sb            *strings.Builder
+	exprs         map[string]string
+	usedNames     map[string]bool
+	instanceNames map[string]int
+}
+
+func newModuleWriter(sb *strings.Builder, c *logic.Component) *moduleWriter {
+	usedNames := make(map[string]bool, len(c.Buses))
+	for name := range c.Buses {
+		usedNames[name] = true
+	}
+	return &moduleWriter{
+		sb:            sb,
+		exprs:         make(map[string]string),
+		usedNames:     usedNames,
+		instanceNames: make(map[string]int),
+	}
+}
+
+func (mw *moduleWriter) writeModule(c *logic.Component) {
+	mw.writeHeader(c)
+	mw.writeWires(c)
+	mw.writeInstances(c)
+	mw.writeFooter()
+}
+
+func (mw *moduleWriter) writeHeader(c *logic.Component) {
+	mw.sb.WriteString("module ")
+	mw.sb.WriteString(Identifier(c.Name()))
+	mw.sb.WriteString(" (\n")
+	ports := 0
+	for _, kind := range []struct {
+		direction string
+		names     []string
+	}{
+		{"input", c.InputBusNames},
+		{"output", c.OutputBusNames},
+	} {
+		for _, name := range kind.names {
+			if ports > 0 {
+				mw.sb.WriteString(",\n")
+			}
+			ports++
+			mw.sb.WriteString("    ")
+			mw.sb.WriteString(kind.direction)
+			mw.sb.WriteString(" ")
+			mw.sb.WriteString(declaration(c.Buses[name]))
+		}
+	}
+	if ports > 0 {
+		mw.sb.WriteString("\n")
+	}
+	mw.sb.WriteString(");\n")
+}
+
+func (mw *moduleWriter) writeWires(c *logic.Component) {
+	ports := make(map[string]bool)
+	for _, name := range c.InputBusNames {
+		ports[name] = true
+	}
+	for _, name := range c.OutputBusNames {
+		ports[name] = true
+	}
+	var names []string
+	for name := range c.Buses {
+		if _, ok := mw.exprs[name]; !ok && !ports[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	mw.sb.WriteString("\n")
+	for _, name := range names {
+		mw.sb.WriteString("    wire ")
+		mw.sb.WriteString(declaration(c.Buses[name]))
+		mw.sb.WriteString(";\n")
+	}
+}
+
+func declaration(bus *logic.Bus) string {
+	if bus.Wires() == 1 {
+		return Identifier(bus.Name)
+	}
+	return fmt.Sprintf("[%d:0] %s", bus.Wires()-1, Identifier(bus.Name))
+}
+
+func (mw *moduleWriter) writeInstances(c *logic.Component) {
+	if len(c.Instances) == 0 {
+		return
+	}
+	mw.sb.WriteString("\n")
+	for _, instance := range c.Instances {
+		mw.sb.WriteString("    ")
+		switch def := instance.Definition.(type) {
+		case *logic.Constants:
+			mw.writeConstants(def, instance)
+		case logic.NandGate:
+			mw.sb.WriteString("nand ")
+			mw.sb.WriteString(mw.instanceName(instance))
+			mw.sb.WriteString(" (")
+			mw.sb.WriteString(mw.expr(instance.Outputs[0]))
+			mw.sb.WriteString(", ")
+			mw.sb.WriteString(mw.expr(instance.Inputs[0]))
+			mw.sb.WriteString(", ")
+			mw.sb.WriteString(mw.expr(instance.Inputs[1]))
+			mw.sb.WriteString(");\n")
+		case logic.TriGate:
+			mw.sb.WriteString("bufif1 ")
+			mw.sb.WriteString(mw.instanceName(instance))
+			mw.sb.WriteString(" (")
+			mw.sb.WriteString(mw.expr(instance.Outputs[0]))
+			mw.sb.WriteString(", ")
+			mw.sb.WriteString(mw.expr(instance.Inputs[1]))
+			mw.sb.WriteString(", ")
+			mw.sb.WriteString(mw.expr(instance.Inputs[0]))
+			mw.sb.WriteString(");\n")
+		case *logic.Component:
+			mw.writeComponentInstance(def, instance)
+		default:
+			panic(fmt.Errorf("unexpected logic.Definition: %t", def))
+		}
+	}
+}
+
+func (mw *moduleWriter) writeConstants(def *logic.Constants, instance *logic.Instance) {
+	var bits []bool
+	for _, value := range def.Values() {
+		bits = append(bits, value...)
+	}
+	for i, output := range instance.Outputs {
+		if i > 0 {
+			mw.sb.WriteString("    ")
+		}
+		mw.sb.WriteString("assign ")
+		mw.sb.WriteString(mw.expr(output))
+		if bits[i] {
+			mw.sb.WriteString(" = 1'b1;\n")
+		} else {
+			mw.sb.WriteString(" = 1'b0;\n")
+		}
+	}
+}
+
+func (mw *moduleWriter) writeComponentInstance(def *logic.Component, instance *logic.Instance) {
+	mw.sb.WriteString(Identifier(def.Name()))
+	mw.sb.WriteString(" ")
+	mw.sb.WriteString(mw.instanceName(instance))
+	mw.sb.WriteString(" (")
+	ports := 0
+	for _, kind := range []struct {
+		names []string
+		wires []logic.BusWire
+	}{
+		{def.InputBusNames, instance.Inputs},
+		{def.OutputBusNames, instance.Outputs},
+	} {
+		offset := 0
+		for _, name := range kind.names {
+			width := def.Buses[name].Wires()
+			if ports > 0 {
+				mw.sb.WriteString(", ")
+			}
+			ports++
+			mw.sb.WriteString(".")
+			mw.sb.WriteString(Identifier(name))
+			mw.sb.WriteString("(")
+			mw.sb.WriteString(mw.concat(kind.wires[offset : offset+width]))
+			mw.sb.WriteString(")")
+			offset += width
+		}
+	}
+	mw.sb.WriteString(");\n")
+}
+
+func (mw *moduleWriter) instanceName(instance *logic.Instance) string {
+	base := "i"
+	if len(instance.Outputs) > 0 {
+		output := instance.Outputs[0]
+		base = "i_" + output.Bus.Name
+		if output.Bus.Wires() > 1 {
+			base += "_" + strconv.Itoa(int(output.WireIndex))
+		}
+	}
+	name := base
+	for mw.usedNames[name] {
+		mw.instanceNames[base]++
+		name = base + "_" + strconv.Itoa(mw.instanceNames[base])
+	}
+	mw.usedNames[name] = true
+	return Identifier(name)
+}
+
+func (mw *moduleWriter) expr(wire logic.BusWire) string {
+	if expr, ok := mw.exprs[wire.Bus.Name]; ok {
+		return expr
+	}
+	return busWireExpr(wire)
+}
+
+func busWireExpr(wire logic.BusWire) string {
+	if wire.Bus.Wires() == 1 {
+		return Identifier(wire.Bus.Name)
+	}
+	return Identifier(wire.Bus.Name) + "[" + strconv.Itoa(int(wire.WireIndex)) + "]"
+}
+
+func (mw *moduleWriter) concat(wires []logic.BusWire) string {
+	if len(wires) == 1 {
+		return mw.expr(wires[0])
+	}
+	full := wires[0].Bus.Wires() == len(wires)
+	for i, wire := range wires {
+		if wire.Bus != wires[0].Bus || int(wire.WireIndex) != i {
+			full = false
+			break
+		}
+	}
+	if full {
+		if _, ok := mw.exprs[wires[0].Bus.Name]; !ok {
+			return Identifier(wires[0].Bus.Name)
+		}
+	}
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i := len(wires) - 1; i >= 0; i-- {
+		sb.WriteString(mw.expr(wires[i]))
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func (mw *moduleWriter) writeFooter() {
+	mw.sb.WriteString("endmodule\n")
+}
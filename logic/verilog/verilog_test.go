@@ -0,0 +1,250 @@
+package verilog
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/internal/logictest"
+)
+
+const src = logictest.Gates + logictest.Add1 + logictest.Add4 + `
+component Swap(a[2])(r[2]) {
+    r[0]: Not(a[1])
+    r[1]: And(a[0], a[1])
+}
+
+component Top(a[2], 'b)(r[2], k, or) {
+    define x[2]
+    x: Swap(a)
+    r[1], r[0]: Swap(x[1], x[0])
+    k: 1
+    or: And(x[0], 'b)
+}
+`
+
+func TestWrite(t *testing.T) {
+	system := logictest.Build(t, src)
+	var sb strings.Builder
+	if err := Write(&sb, system.Components["Top"]); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	expected := `
+module Not (
+    input a,
+    output r
+);
+
+    nand i_r (r, a, a);
+endmodule
+
+module And (
+    input a,
+    input b,
+    output r
+);
+
+    wire i;
+
+    nand i_i (i, a, b);
+    Not i_r (.a(i), .r(r));
+endmodule
+
+module Swap (
+    input [1:0] a,
+    output [1:0] r
+);
+
+    Not i_r_0 (.a(a[1]), .r(r[0]));
+    And i_r_1 (.a(a[0]), .b(a[1]), .r(r[1]));
+endmodule
+
+module Top (
+    input [1:0] a,
+    input \'b ,
+    output [1:0] r,
+    output k,
+    output \or 
+);
+
+    wire [1:0] x;
+
+    Swap i_x_0 (.a(a), .r(x));
+    Swap i_r_1 (.a({x[0], x[1]}), .r({r[0], r[1]}));
+    assign k = 1'b1;
+    And i_or (.a(x[0]), .b(\'b ), .r(\or ));
+endmodule
+`[1:]
+	if actual := sb.String(); actual != expected {
+		t.Errorf("Write() = %q; want %q", actual, expected)
+	}
+}
+
+func TestWriteFlat(t *testing.T) {
+	system := logictest.Build(t, src)
+	var sb strings.Builder
+	if err := WriteFlat(&sb, system.Components["Top"]); err != nil {
+		t.Fatalf("WriteFlat() failed: %v", err)
+	}
+	expected := `
+module Top (
+    input [1:0] a,
+    input \'b ,
+    output [1:0] r,
+    output k,
+    output \or 
+);
+
+    wire And_i1_i;
+    wire And_i2_i;
+    wire And_i3_i;
+    wire x0;
+    wire x1;
+
+    nand i_x0 (x0, a[1], a[1]);
+    nand i_And_i1_i (And_i1_i, a[0], a[1]);
+    nand i_x1 (x1, And_i1_i, And_i1_i);
+    nand i_r1 (r[1], x0, x0);
+    nand i_And_i2_i (And_i2_i, x1, x0);
+    nand i_r0 (r[0], And_i2_i, And_i2_i);
+    assign k = 1'b1;
+    nand i_And_i3_i (And_i3_i, x0, \'b );
+    nand i_or (\or , And_i3_i, And_i3_i);
+endmodule
+`[1:]
+	if actual := sb.String(); actual != expected {
+		t.Errorf("WriteFlat() = %q; want %q", actual, expected)
+	}
+}
+
+var (
+	portPattern   = regexp.MustCompile(`^\s*(input|output) (?:\[(\d+):0\] )?(\w+)`)
+	nandPattern   = regexp.MustCompile(`^\s*nand (?:\\\S+ |\w+) \(([^,]+), ([^,]+), ([^,]+)\);$`)
+	assignPattern = regexp.MustCompile(`^\s*assign (\S+) = 1'b([01]);$`)
+)
+
+type netlist struct {
+	inputs, outputs []string
+	nands           [][3]string
+	assigns         map[string]bool
+}
+
+func parseFlat(t *testing.T, verilog string) *netlist {
+	n := &netlist{assigns: make(map[string]bool)}
+	for _, line := range strings.Split(verilog, "\n") {
+		if m := portPattern.FindStringSubmatch(line); m != nil {
+			wires := []string{m[3]}
+			if m[2] != "" {
+				width, _ := strconv.Atoi(m[2])
+				wires = nil
+				for i := 0; i <= width; i++ {
+					wires = append(wires, m[3]+"["+strconv.Itoa(i)+"]")
+				}
+			}
+			if m[1] == "input" {
+				n.inputs = append(n.inputs, wires...)
+			} else {
+				n.outputs = append(n.outputs, wires...)
+			}
+		} else if m := nandPattern.FindStringSubmatch(line); m != nil {
+			n.nands = append(n.nands, [3]string{m[1], m[2], m[3]})
+		} else if m := assignPattern.FindStringSubmatch(line); m != nil {
+			n.assigns[m[1]] = m[2] == "1"
+		} else if strings.HasPrefix(strings.TrimSpace(line), "nand") {
+			t.Fatalf("could not parse line: %q", line)
+		}
+	}
+	return n
+}
+
+func (n *netlist) evaluate(inputs []bool) []bool {
+	values := make(map[string]bool)
+	for i, input := range n.inputs {
+		values[input] = inputs[i]
+	}
+	for wire, value := range n.assigns {
+		values[wire] = value
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, nand := range n.nands {
+			r := !(values[nand[1]] && values[nand[2]])
+			if values[nand[0]] != r {
+				values[nand[0]] = r
+				changed = true
+			}
+		}
+	}
+	outputs := make([]bool, len(n.outputs))
+	for i, output := range n.outputs {
+		outputs[i] = values[output]
+	}
+	return outputs
+}
+
+func TestWriteTri(t *testing.T) {
+	a := logic.NewBus("a", 2)
+	en := logic.NewBus("en", 2)
+	r := logic.NewBus("r", 1)
+	c := logic.NewComponent("Shared", []*logic.Bus{a, en}, []*logic.Bus{r})
+	for i := 0; i < 2; i++ {
+		c.Instances = append(c.Instances, &logic.Instance{
+			Definition: logic.Tri,
+			Inputs:     []logic.BusWire{{Bus: en, WireIndex: logic.WireIndex(i)}, {Bus: a, WireIndex: logic.WireIndex(i)}},
+			Outputs:    []logic.BusWire{{Bus: r}},
+		})
+	}
+	var sb strings.Builder
+	if err := Write(&sb, c); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	for _, line := range []string{"bufif1 i_r (r, a[0], en[0]);", "bufif1 i_r_1 (r, a[1], en[1]);"} {
+		if !strings.Contains(sb.String(), line) {
+			t.Errorf("expected output to contain %q:\n%s", line, sb.String())
+		}
+	}
+}
+
+func TestWriteFlatRoundTrip(t *testing.T) {
+	system := logictest.Build(t, src)
+	var sb strings.Builder
+	if err := WriteFlat(&sb, system.Components["Add4"]); err != nil {
+		t.Fatalf("WriteFlat() failed: %v", err)
+	}
+	n := parseFlat(t, sb.String())
+	if len(n.inputs) != 9 || len(n.outputs) != 5 {
+		t.Fatalf("parsed %d inputs and %d outputs; want 9 and 5", len(n.inputs), len(n.outputs))
+	}
+	for m := 0; m < 1<<9; m++ {
+		inputs := make([]bool, 9)
+		for i := range inputs {
+			inputs[i] = (m>>i)&1 == 1
+		}
+		sum := m&0xf + (m>>4)&0xf + (m>>8)&1
+		outputs := n.evaluate(inputs)
+		for i, output := range outputs {
+			if output != ((sum>>i)&1 == 1) {
+				t.Errorf("Add4 output %d for input %#x = %v; want %v", i, m, output, !output)
+			}
+		}
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	for name, expected := range map[string]string{
+		"a":         "a",
+		"Add1_i3":   "Add1_i3",
+		"'a":        "\\'a ",
+		"or":        "\\or ",
+		"x$y":       "x$y",
+		"$x":        "\\$x ",
+		"Or":        "Or",
+		"endmodule": "\\endmodule ",
+	} {
+		if actual := Identifier(name); actual != expected {
+			t.Errorf("Identifier(%q) = %q; want %q", name, actual, expected)
+		}
+	}
+}
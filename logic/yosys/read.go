@@ -0,0 +1,447 @@
+package yosys
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/synth"
+)
+
+type object[T any] struct {
+	keys   []string
+	values map[string]T
+}
+
+func (o *object[T]) UnmarshalJSON(data []byte) error {
+	d := stdjson.NewDecoder(bytes.NewReader(data))
+	if t, err := d.Token(); err != nil {
+		return err
+	} else if t != stdjson.Delim('{') {
+		return fmt.Errorf("expected object")
+	}
+	o.values = make(map[string]T)
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		key := t.(string)
+		var value T
+		if err := d.Decode(&value); err != nil {
+			return err
+		}
+		if _, ok := o.values[key]; !ok {
+			o.keys = append(o.keys, key)
+		}
+		o.values[key] = value
+	}
+	_, err := d.Token()
+	return err
+}
+
+type netlist struct {
+	Modules object[*module] `json:"modules"`
+}
+
+type module struct {
+	Ports    object[*port]    `json:"ports"`
+	Cells    object[*cell]    `json:"cells"`
+	Netnames object[*netname] `json:"netnames"`
+}
+
+type port struct {
+	Direction string `json:"direction"`
+	Bits      []bit  `json:"bits"`
+	Upto      int    `json:"upto"`
+}
+
+type cell struct {
+	Type           string            `json:"type"`
+	PortDirections map[string]string `json:"port_directions"`
+	Connections    map[string][]bit  `json:"connections"`
+}
+
+type netname struct {
+	HideName int   `json:"hide_name"`
+	Bits     []bit `json:"bits"`
+	Upto     int   `json:"upto"`
+}
+
+type bit struct {
+	net      int
+	constant string
+}
+
+func (b *bit) UnmarshalJSON(data []byte) error {
+	if err := stdjson.Unmarshal(data, &b.net); err == nil {
+		return nil
+	}
+	return stdjson.Unmarshal(data, &b.constant)
+}
+
+func (b bit) String() string {
+	if b.constant != "" {
+		return strconv.Quote(b.constant)
+	}
+	return strconv.Itoa(b.net)
+}
+
+func Read(r io.Reader) (*logic.System, error) {
+	var n netlist
+	d := stdjson.NewDecoder(r)
+	if err := d.Decode(&n); err != nil {
+		return nil, err
+	}
+	system := logic.NewSystem()
+	builders := make(map[string]*moduleBuilder)
+	used := make(map[string]bool)
+	for _, name := range n.Modules.keys {
+		b, err := newModuleBuilder(name, n.Modules.values[name], used)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %v", name, err)
+		}
+		builders[name] = b
+		system.AddComponent(b.component)
+	}
+	for _, name := range n.Modules.keys {
+		if err := builders[name].build(builders); err != nil {
+			return nil, fmt.Errorf("module %s: %v", name, err)
+		}
+	}
+	return system, nil
+}
+
+type moduleBuilder struct {
+	module    *module
+	component *logic.Component
+	ports     map[string][]logic.BusWire
+	nets      map[int]logic.BusWire
+	drivers   map[int]string
+	constants [2]*logic.BusWire
+	buffers   []buffer
+	counts    map[string]int
+}
+
+type buffer struct {
+	source bit
+	output logic.BusWire
+}
+
+func newModuleBuilder(name string, m *module, usedComponents map[string]bool) (*moduleBuilder, error) {
+	b := &moduleBuilder{
+		module:  m,
+		ports:   make(map[string][]logic.BusWire),
+		nets:    make(map[int]logic.BusWire),
+		drivers: make(map[int]string),
+		counts:  make(map[string]int),
+	}
+	used := make(map[string]bool)
+	var inputs, outputs []*logic.Bus
+	for _, direction := range []string{"input", "output"} {
+		for _, portName := range m.Ports.keys {
+			p := m.Ports.values[portName]
+			if p.Direction != direction {
+				continue
+			}
+			if len(p.Bits) == 0 {
+				return nil, fmt.Errorf("port %s has no bits", portName)
+			}
+			bus := logic.NewBus(uniqueName(synth.Identifier(portName), used), len(p.Bits))
+			wires := make([]logic.BusWire, len(p.Bits))
+			for i, pb := range p.Bits {
+				wire := logic.BusWire{Bus: bus, WireIndex: logic.WireIndex(busIndex(i, len(p.Bits), p.Upto))}
+				wires[i] = wire
+				if direction == "input" {
+					if pb.constant != "" {
+						return nil, fmt.Errorf("input port %s has constant bit %s", portName, pb)
+					} else if _, ok := b.nets[pb.net]; ok {
+						return nil, fmt.Errorf("input port %s shares net %s with another input", portName, pb)
+					}
+					b.nets[pb.net] = wire
+					b.drivers[pb.net] = "input port " + portName
+				} else if _, ok := b.nets[pb.net]; ok || pb.constant != "" {
+					b.buffers = append(b.buffers, buffer{pb, wire})
+				} else {
+					b.nets[pb.net] = wire
+				}
+			}
+			b.ports[portName] = wires
+			if direction == "input" {
+				inputs = append(inputs, bus)
+			} else {
+				outputs = append(outputs, bus)
+			}
+		}
+	}
+	for _, portName := range m.Ports.keys {
+		if d := m.Ports.values[portName].Direction; d != "input" && d != "output" {
+			return nil, fmt.Errorf("unsupported direction of port %s: %s", portName, d)
+		}
+	}
+	b.component = logic.NewComponent(uniqueName(synth.Identifier(name), usedComponents), inputs, outputs)
+	for _, netName := range m.Netnames.keys {
+		nn := m.Netnames.values[netName]
+		if nn.HideName != 0 {
+			continue
+		}
+		var bits []int
+		for i, nb := range nn.Bits {
+			if _, ok := b.nets[nb.net]; !ok && nb.constant == "" {
+				bits = append(bits, i)
+			}
+		}
+		if len(bits) == 0 {
+			continue
+		}
+		bus := logic.NewBus(b.uniqueBusName(synth.Identifier(netName)), len(nn.Bits))
+		b.component.Buses[bus.Name] = bus
+		for _, i := range bits {
+			b.nets[nn.Bits[i].net] = logic.BusWire{Bus: bus, WireIndex: logic.WireIndex(busIndex(i, len(nn.Bits), nn.Upto))}
+		}
+	}
+	return b, nil
+}
+
+func busIndex(i, width, upto int) int {
+	if upto != 0 {
+		return width - 1 - i
+	}
+	return i
+}
+
+func uniqueName(name string, used map[string]bool) string {
+	unique := name
+	for i := 1; used[unique]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
+
+func (b *moduleBuilder) uniqueBusName(name string) string {
+	unique := name
+	for i := 1; ; i++ {
+		if _, ok := b.component.Buses[unique]; !ok {
+			return unique
+		}
+		unique = name + "_" + strconv.Itoa(i)
+	}
+}
+
+func (b *moduleBuilder) fresh(prefix string) logic.BusWire {
+	for {
+		name := prefix + strconv.Itoa(b.counts[prefix])
+		b.counts[prefix]++
+		if _, ok := b.component.Buses[name]; !ok {
+			bus := logic.NewBus(name, 1)
+			b.component.Buses[name] = bus
+			return logic.BusWire{Bus: bus, WireIndex: 0}
+		}
+	}
+}
+
+func (b *moduleBuilder) net(nb bit) (logic.BusWire, error) {
+	switch nb.constant {
+	case "":
+		break
+	case "0", "1":
+		value := 0
+		if nb.constant == "1" {
+			value = 1
+		}
+		if b.constants[value] == nil {
+			wire := b.fresh("const" + nb.constant + "_")
+			b.constants[value] = &wire
+		}
+		return *b.constants[value], nil
+	default:
+		return logic.BusWire{}, fmt.Errorf("unsupported bit value: %s", nb)
+	}
+	if wire, ok := b.nets[nb.net]; ok {
+		return wire, nil
+	}
+	wire := b.fresh("n" + strconv.Itoa(nb.net) + "_")
+	b.nets[nb.net] = wire
+	return wire, nil
+}
+
+func (b *moduleBuilder) drive(nb bit, driver string) (logic.BusWire, error) {
+	if nb.constant != "" {
+		return logic.BusWire{}, fmt.Errorf("%s drives constant %s", driver, nb)
+	} else if other, ok := b.drivers[nb.net]; ok {
+		return logic.BusWire{}, fmt.Errorf("net %s is driven by %s and %s", nb, other, driver)
+	}
+	b.drivers[nb.net] = driver
+	return b.net(nb)
+}
+
+func (b *moduleBuilder) build(builders map[string]*moduleBuilder) error {
+	for _, cellName := range b.module.Cells.keys {
+		if err := b.buildCell(cellName, b.module.Cells.values[cellName], builders); err != nil {
+			return fmt.Errorf("cell %s: %v", cellName, err)
+		}
+	}
+	for _, portName := range b.module.Ports.keys {
+		p := b.module.Ports.values[portName]
+		if p.Direction != "output" {
+			continue
+		}
+		for i, pb := range p.Bits {
+			if _, ok := b.drivers[pb.net]; !ok && pb.constant == "" {
+				return fmt.Errorf("output port %s has undriven bit %d", portName, i)
+			}
+		}
+	}
+	for _, buf := range b.buffers {
+		source, err := b.net(buf.source)
+		if err != nil {
+			return fmt.Errorf("output %s: %v", buf.output.Bus.Name, err)
+		}
+		inverted := b.fresh("'" + buf.output.Bus.Name)
+		b.nand(source, source, inverted)
+		b.nand(inverted, inverted, buf.output)
+	}
+	var values []logic.Value
+	var outputs []logic.BusWire
+	for value, wire := range b.constants {
+		if wire != nil {
+			values = append(values, logic.Value{value == 1})
+			outputs = append(outputs, *wire)
+		}
+	}
+	if len(values) > 0 {
+		b.component.Instances = append(b.component.Instances, &logic.Instance{
+			Definition: logic.NewConstants(values),
+			Outputs:    outputs,
+		})
+	}
+	return nil
+}
+
+var gateInputs = map[string][]string{
+	"$_NOT_":  {"A"},
+	"$_NAND_": {"A", "B"},
+	"$_AND_":  {"A", "B"},
+	"$_OR_":   {"A", "B"},
+	"$_XOR_":  {"A", "B"},
+}
+
+func (b *moduleBuilder) buildCell(name string, c *cell, builders map[string]*moduleBuilder) error {
+	if ports, ok := gateInputs[c.Type]; ok {
+		return b.buildGate(name, c, ports)
+	}
+	child, ok := builders[c.Type]
+	if !ok {
+		return fmt.Errorf("unsupported cell type: %s", c.Type)
+	}
+	for portName := range c.Connections {
+		if _, ok := child.module.Ports.values[portName]; !ok {
+			return fmt.Errorf("module %s has no port %s", c.Type, portName)
+		}
+	}
+	var inputs, outputs []logic.BusWire
+	for _, direction := range []string{"input", "output"} {
+		for _, portName := range child.module.Ports.keys {
+			p := child.module.Ports.values[portName]
+			if p.Direction != direction {
+				continue
+			}
+			bits, ok := c.Connections[portName]
+			if !ok {
+				return fmt.Errorf("unconnected port %s of module %s", portName, c.Type)
+			} else if len(bits) != len(p.Bits) {
+				return fmt.Errorf("port %s of module %s has %d bits, got %d", portName, c.Type, len(p.Bits), len(bits))
+			}
+			wires := make([]logic.BusWire, len(bits))
+			for i, cb := range bits {
+				var wire logic.BusWire
+				var err error
+				if direction == "input" {
+					wire, err = b.net(cb)
+				} else {
+					wire, err = b.drive(cb, "cell "+name)
+				}
+				if err != nil {
+					return err
+				}
+				wires[child.ports[portName][i].WireIndex] = wire
+			}
+			if direction == "input" {
+				inputs = append(inputs, wires...)
+			} else {
+				outputs = append(outputs, wires...)
+			}
+		}
+	}
+	b.component.Instances = append(b.component.Instances, &logic.Instance{
+		Definition: child.component,
+		Inputs:     inputs,
+		Outputs:    outputs,
+	})
+	return nil
+}
+
+func (b *moduleBuilder) buildGate(name string, c *cell, ports []string) error {
+	if len(c.Connections) != len(ports)+1 {
+		return fmt.Errorf("%s expects %d connections, got %d", c.Type, len(ports)+1, len(c.Connections))
+	}
+	inputs := make([]logic.BusWire, len(ports))
+	for i, portName := range ports {
+		bits, ok := c.Connections[portName]
+		if !ok || len(bits) != 1 {
+			return fmt.Errorf("%s expects one bit for port %s", c.Type, portName)
+		}
+		input, err := b.net(bits[0])
+		if err != nil {
+			return err
+		}
+		inputs[i] = input
+	}
+	bits, ok := c.Connections["Y"]
+	if !ok || len(bits) != 1 {
+		return fmt.Errorf("%s expects one bit for port Y", c.Type)
+	}
+	y, err := b.drive(bits[0], "cell "+name)
+	if err != nil {
+		return err
+	}
+	switch c.Type {
+	case "$_NOT_":
+		b.nand(inputs[0], inputs[0], y)
+	case "$_NAND_":
+		b.nand(inputs[0], inputs[1], y)
+	case "$_AND_":
+		i := b.fresh("'" + y.Bus.Name)
+		b.nand(inputs[0], inputs[1], i)
+		b.nand(i, i, y)
+	case "$_OR_":
+		a := b.fresh("'" + y.Bus.Name)
+		c := b.fresh("'" + y.Bus.Name)
+		b.nand(inputs[0], inputs[0], a)
+		b.nand(inputs[1], inputs[1], c)
+		b.nand(a, c, y)
+	case "$_XOR_":
+		i1 := b.fresh(y.Bus.Name + "_")
+		i2 := b.fresh(y.Bus.Name + "_")
+		i3 := b.fresh(y.Bus.Name + "_")
+		b.nand(inputs[0], inputs[1], i1)
+		b.nand(inputs[0], i1, i2)
+		b.nand(inputs[1], i1, i3)
+		b.nand(i2, i3, y)
+	default:
+		panic(fmt.Errorf("unexpected cell type: %s", c.Type))
+	}
+	return nil
+}
+
+func (b *moduleBuilder) nand(a, c, r logic.BusWire) {
+	b.component.Instances = append(b.component.Instances, &logic.Instance{
+		Definition: logic.Nand,
+		Inputs:     []logic.BusWire{a, c},
+		Outputs:    []logic.BusWire{r},
+	})
+}
@@ -0,0 +1,263 @@
+package yosys
+
+import (
+	positions "go/token"
+	"strings"
+	"testing"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/equiv"
+	"github.com/arneph/mercury/logic/internal/logictest"
+	"github.com/arneph/mercury/logic/simulation"
+	"github.com/arneph/mercury/logic/text"
+)
+
+const src = logictest.Not + `
+component FullAdder(a, b, cin)(s, cout) {
+    i1: nand(a, b)
+    i2: nand(a, i1)
+    i3: nand(b, i1)
+    t: nand(i2, i3)
+    j1: nand(t, cin)
+    j2: nand(t, j1)
+    j3: nand(cin, j1)
+    s: nand(j2, j3)
+    cout: nand(i1, j1)
+}
+
+component Adder2(a[2], b[2])(y[3]) {
+    define c
+    define zero
+    zero: 0
+    y[0], c: FullAdder(a[0], b[0], zero)
+    y[1], y[2]: FullAdder(a[1], b[1], c)
+}
+
+component Misc(d[2])(r[3], n) {
+    define nd[2]
+    nd[0]: Not(d[0])
+    nd[1]: Not(d[1])
+    r[0]: Not(nd[0])
+    r[1]: 1
+    r[2]: Not(nd[1])
+    n: nand(d[1], d[0])
+}
+`
+
+const netlistSrc = `{
+  "creator": "Yosys 0.38",
+  "modules": {
+    "full_adder": {
+      "attributes": {},
+      "ports": {
+        "a": {"direction": "input", "bits": [2]},
+        "b": {"direction": "input", "bits": [3]},
+        "cin": {"direction": "input", "bits": [4]},
+        "s": {"direction": "output", "bits": [5]},
+        "cout": {"direction": "output", "bits": [6]}
+      },
+      "cells": {
+        "$abc$1": {
+          "hide_name": 1,
+          "type": "$_XOR_",
+          "parameters": {},
+          "attributes": {},
+          "port_directions": {"A": "input", "B": "input", "Y": "output"},
+          "connections": {"A": [2], "B": [3], "Y": [7]}
+        },
+        "$abc$2": {
+          "hide_name": 1,
+          "type": "$_XOR_",
+          "port_directions": {"A": "input", "B": "input", "Y": "output"},
+          "connections": {"A": [7], "B": [4], "Y": [5]}
+        },
+        "$abc$3": {
+          "hide_name": 1,
+          "type": "$_AND_",
+          "port_directions": {"A": "input", "B": "input", "Y": "output"},
+          "connections": {"A": [2], "B": [3], "Y": [8]}
+        },
+        "$abc$4": {
+          "hide_name": 1,
+          "type": "$_AND_",
+          "port_directions": {"A": "input", "B": "input", "Y": "output"},
+          "connections": {"A": [7], "B": [4], "Y": [9]}
+        },
+        "$abc$5": {
+          "hide_name": 1,
+          "type": "$_OR_",
+          "port_directions": {"A": "input", "B": "input", "Y": "output"},
+          "connections": {"A": [8], "B": [9], "Y": [6]}
+        }
+      },
+      "netnames": {
+        "$abc$8": {"hide_name": 1, "bits": [8], "attributes": {}},
+        "a": {"hide_name": 0, "bits": [2], "attributes": {}},
+        "b": {"hide_name": 0, "bits": [3], "attributes": {}},
+        "cin": {"hide_name": 0, "bits": [4], "attributes": {}},
+        "cout": {"hide_name": 0, "bits": [6], "attributes": {}},
+        "s": {"hide_name": 0, "bits": [5], "attributes": {}},
+        "t": {"hide_name": 0, "bits": [7], "attributes": {}}
+      }
+    },
+    "adder2": {
+      "ports": {
+        "a": {"direction": "input", "bits": [2, 3]},
+        "b": {"direction": "input", "bits": [4, 5]},
+        "y": {"direction": "output", "bits": [6, 7, 8]}
+      },
+      "cells": {
+        "fa0": {
+          "type": "full_adder",
+          "connections": {"a": [2], "b": [4], "cin": ["0"], "s": [6], "cout": [9]}
+        },
+        "fa1": {
+          "type": "full_adder",
+          "connections": {"cout": [8], "s": [7], "cin": [9], "b": [5], "a": [3]}
+        }
+      },
+      "netnames": {
+        "carry": {"hide_name": 0, "bits": [9]}
+      }
+    },
+    "misc": {
+      "ports": {
+        "d": {"direction": "input", "bits": [2, 3], "upto": 1},
+        "r": {"direction": "output", "bits": [3, "1", 2]},
+        "n": {"direction": "output", "bits": [10]}
+      },
+      "cells": {
+        "g": {
+          "type": "$_NAND_",
+          "connections": {"A": [2], "B": [3], "Y": [10]}
+        }
+      },
+      "netnames": {}
+    }
+  }
+}`
+
+func TestRead(t *testing.T) {
+	expected := logictest.Build(t, src)
+	system, err := Read(strings.NewReader(netlistSrc))
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	for name, expectedName := range map[string]string{
+		"full_adder": "FullAdder",
+		"adder2":     "Adder2",
+		"misc":       "Misc",
+	} {
+		c, ok := system.Components[name]
+		if !ok {
+			t.Errorf("Read() did not return component %s", name)
+			continue
+		}
+		e := expected.Components[expectedName]
+		if err := logic.ComparePorts(c, e); err != nil {
+			t.Errorf("ComparePorts() for %s failed: %v", name, err)
+			continue
+		}
+		counterexample, err := equiv.Check(c, e)
+		if err != nil {
+			t.Errorf("equiv.Check() for %s failed: %v", name, err)
+		} else if counterexample != nil {
+			t.Errorf("%s is not equivalent to %s: %v", name, expectedName, counterexample)
+		}
+	}
+	fa := system.Components["full_adder"]
+	if got := strings.Join(fa.InputBusNames, ","); got != "a,b,cin" {
+		t.Errorf("full_adder inputs = %s; want a,b,cin", got)
+	}
+	if got := strings.Join(fa.OutputBusNames, ","); got != "s,cout" {
+		t.Errorf("full_adder outputs = %s; want s,cout", got)
+	}
+	if _, ok := fa.Buses["t"]; !ok {
+		t.Errorf("full_adder does not keep named net t")
+	}
+	for _, instance := range fa.Instances {
+		if _, ok := instance.Definition.(logic.NandGate); !ok {
+			t.Errorf("full_adder contains %s; want only nand gates", instance.Definition.Name())
+		}
+	}
+	if len(fa.Instances) != 4+4+2+2+3 {
+		t.Errorf("full_adder has %d gates; want 15", len(fa.Instances))
+	}
+	adder := system.Components["adder2"]
+	if _, ok := adder.Buses["carry"]; !ok {
+		t.Errorf("adder2 does not keep named net carry")
+	}
+	children := 0
+	for _, instance := range adder.Instances {
+		if instance.Definition == fa {
+			children++
+		}
+	}
+	if children != 2 {
+		t.Errorf("adder2 has %d full_adder instances; want 2", children)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, src := range []string{
+		`{"modules": [}`,
+		`{"modules": {"m": {"ports": {"a": {"direction": "inout", "bits": [2]}}}}}`,
+		`{"modules": {"m": {"ports": {"a": {"direction": "input", "bits": []}}}}}`,
+		`{"modules": {"m": {"ports": {"a": {"direction": "input", "bits": ["0"]}}}}}`,
+		`{"modules": {"m": {"ports": {"r": {"direction": "output", "bits": [2]}}}}}`,
+		`{"modules": {"m": {"ports": {"a": {"direction": "input", "bits": [2]}, "r": {"direction": "output", "bits": [3]}},
+		  "cells": {"c": {"type": "$_MUX_", "connections": {"A": [2], "B": [2], "S": [2], "Y": [3]}}}}}}`,
+		`{"modules": {"m": {"ports": {"a": {"direction": "input", "bits": [2]}, "r": {"direction": "output", "bits": [3]}},
+		  "cells": {"c": {"type": "$_NOT_", "connections": {"A": ["x"], "Y": [3]}}}}}}`,
+		`{"modules": {"m": {"ports": {"a": {"direction": "input", "bits": [2]}, "r": {"direction": "output", "bits": [3]}},
+		  "cells": {"c": {"type": "$_NOT_", "connections": {"A": [2], "Y": [2]}}}}}}`,
+		`{"modules": {"m": {"ports": {"a": {"direction": "input", "bits": [2]}, "r": {"direction": "output", "bits": [3]}},
+		  "cells": {"c": {"type": "$_AND_", "connections": {"A": [2], "Y": [3]}}}}}}`,
+		`{"modules": {"m": {"ports": {"a": {"direction": "input", "bits": [2]}, "r": {"direction": "output", "bits": [3]}},
+		  "cells": {"c": {"type": "m", "connections": {"a": [2, 2], "r": [3]}}}}}}`,
+	} {
+		if _, err := Read(strings.NewReader(src)); err == nil {
+			t.Errorf("Read() for %s succeeded; want error", src)
+		}
+	}
+}
+
+func TestRunTestsAgainstNetlist(t *testing.T) {
+	system, err := Read(strings.NewReader(netlistSrc))
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	tests := `
+test Adder2 {
+    component: adder2
+
+    set a, b: 3, 2
+    assert y is 5
+
+    set a, b: 3, 3
+    assert y is 6
+}
+
+test Misc {
+    component: misc
+
+    set d: 1
+    assert r, n is 3, 1
+
+    set d: 3
+    assert r, n is 7, 0
+}
+`
+	fileSet := positions.NewFileSet()
+	file := fileSet.AddFile("tests.mercury", fileSet.Base(), len(tests))
+	file.SetLinesForContent([]byte(tests))
+	system, errs := text.BuildFromFileIntoSystem(file, []byte(tests), system)
+	if errs.Len() > 0 {
+		t.Fatalf("BuildFromFileIntoSystem() failed: %v", errs)
+	}
+	for name, test := range system.Tests {
+		if outcome, errs := simulation.RunTest(test, file, simulation.RunOptions{}); outcome != simulation.PASS {
+			t.Errorf("test %s failed: %v: %v", name, outcome, errs)
+		}
+	}
+}
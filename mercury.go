@@ -1,53 +1,152 @@
 package main
 
 import (
+	"bytes"
+	stdjson "encoding/json"
+	"flag"
 	"fmt"
 	errors "go/scanner"
 	positions "go/token"
 	"os"
-	"sort"
+	"path/filepath"
+	"strings"
 
-	"github.com/arneph/mercury/logic/simulation"
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/aiger"
+	"github.com/arneph/mercury/logic/blif"
+	"github.com/arneph/mercury/logic/json"
 	"github.com/arneph/mercury/logic/text"
+	"github.com/arneph/mercury/logic/yosys"
 )
 
+type command struct {
+	usage string
+	run   func(args []string) int
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"test":   {"test [-import netlist] [-four-valued] [-settle-limit n] [-vcd dir [-vcd-internal]] [-parallel n] <file>", runTests},
+		"equiv":  {"equiv <file> <component> <component>", runEquiv},
+		"table":  {"table [-order ordering] <file> <component>", runTable},
+		"export": {"export [-format format] [-flat] <file> <component> [<component>]", runExport},
+		"synth":  {"synth [-name name] <file.pla> | synth <file> <table>", runSynth},
+		"dot":    {"dot [-flat] <file> <component>", runDot},
+		"chips":  {"chips [-format bom|kicad] <file> <component>", runChips},
+		"gen-go": {"gen-go [-package name] <file> <component>", runGenGo},
+		"timing": {"timing [-delay n] [-trace] [-vcd dir [-vcd-internal]] <file> <test>", runTiming},
+	}
+}
+
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Println("Expected path for input file.")
-		os.Exit(1)
-		return
+	args := os.Args[1:]
+	name := "test"
+	if len(args) > 0 {
+		if _, ok := commands[args[0]]; ok {
+			name = args[0]
+			args = args[1:]
+		}
 	}
-	path := os.Args[1]
+	os.Exit(commands[name].run(args))
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: mercury %s\n", commands[name].usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+func parseFlags(flags *flag.FlagSet, args []string, minPositional, maxPositional int) ([]string, bool) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, false
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(positional) < minPositional || len(positional) > maxPositional {
+		flags.Usage()
+		return nil, false
+	}
+	return positional, true
+}
+
+func loadSystem(path string) (*logic.System, *positions.File, bool) {
 	src, err := os.ReadFile(path)
 	if err != nil {
 		fmt.Printf("Could not read path: %v\n", err)
-		os.Exit(1)
-		return
+		return nil, nil, false
 	}
+	switch filepath.Ext(path) {
+	case ".blif":
+		system, err := blif.Read(bytes.NewReader(src))
+		if err != nil {
+			fmt.Printf("Could not read BLIF file: %v\n", err)
+			return nil, nil, false
+		}
+		return system, nil, true
+	case ".aag", ".aig":
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		c, err := aiger.Read(bytes.NewReader(src), name)
+		if err != nil {
+			fmt.Printf("Could not read AIGER file: %v\n", err)
+			return nil, nil, false
+		}
+		system := logic.NewSystem()
+		system.AddComponent(c)
+		return system, nil, true
+	case ".json":
+		var system *logic.System
+		if isYosysNetlist(src) {
+			system, err = yosys.Read(bytes.NewReader(src))
+		} else {
+			system, err = json.Unmarshal(src)
+		}
+		if err != nil {
+			fmt.Printf("Could not read JSON file: %v\n", err)
+			return nil, nil, false
+		}
+		return system, nil, true
+	}
+	return buildSystem(path, src, logic.NewSystem())
+}
+
+func buildSystem(path string, src []byte, system *logic.System) (*logic.System, *positions.File, bool) {
 	fileSet := positions.NewFileSet()
 	file := fileSet.AddFile(path, fileSet.Base(), len(src))
 	file.SetLinesForContent(src)
 
-	system, errs := text.BuildFromFile(file, src)
+	system, errs := text.BuildFromFileIntoSystem(file, src, system)
 	if errs.Len() > 0 {
 		errs.RemoveMultiples()
 		errors.PrintError(os.Stderr, errs)
-		return
-	}
-	testNames := make([]string, 0, len(system.Tests))
-	for name := range system.Tests {
-		testNames = append(testNames, name)
-	}
-	sort.Strings(testNames)
-	for _, name := range testNames {
-		test := system.Tests[name]
-		fmt.Printf("test %-20s ", name)
-		errs := simulation.RunTest(test, file)
-		if errs.Len() == 0 {
-			fmt.Println("PASS")
-		} else {
-			fmt.Println("FAIL")
-			errors.PrintError(os.Stderr, errs)
-		}
+		return nil, nil, false
+	}
+	return system, file, true
+}
+
+func isYosysNetlist(src []byte) bool {
+	var top map[string]stdjson.RawMessage
+	if err := stdjson.Unmarshal(src, &top); err != nil {
+		return false
+	}
+	_, ok := top["modules"]
+	return ok
+}
+
+func lookupComponent(system *logic.System, name string) (*logic.Component, bool) {
+	c, ok := system.Components[name]
+	if !ok {
+		fmt.Printf("Undefined component: %s\n", name)
 	}
+	return c, ok
 }
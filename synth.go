@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arneph/mercury/logic/synth"
+)
+
+func runSynth(args []string) int {
+	flags := newFlagSet("synth")
+	name := flags.String("name", "", "component name for PLA files, defaults to the file name")
+	exactLimit := flags.Int("exact-limit", synth.DefaultExactLimit, "maximum number of inputs for exact minimization of PLA files")
+	args, ok := parseFlags(flags, args, 1, 2)
+	if !ok {
+		return 1
+	}
+	if *exactLimit < 0 || *exactLimit > synth.MaxExactLimit {
+		fmt.Printf("Invalid exact limit: %d (maximum %d)\n", *exactLimit, synth.MaxExactLimit)
+		return 1
+	}
+	if filepath.Ext(args[0]) != ".pla" {
+		if len(args) != 2 {
+			flags.Usage()
+			return 1
+		}
+		system, _, ok := loadSystem(args[0])
+		if !ok {
+			return 1
+		}
+		c, ok := lookupComponent(system, args[1])
+		if !ok {
+			return 1
+		}
+		fmt.Print(c)
+		return 0
+	} else if len(args) != 1 {
+		flags.Usage()
+		return 1
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		fmt.Printf("Could not read path: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+	if *name == "" {
+		*name = synth.Identifier(strings.TrimSuffix(filepath.Base(args[0]), ".pla"))
+	}
+	table, err := synth.ReadPLA(f, *name)
+	if err != nil {
+		fmt.Printf("Could not read PLA file: %v\n", err)
+		return 1
+	}
+	opts := synth.DefaultOptions()
+	opts.ExactLimit = *exactLimit
+	c, err := synth.SynthesizeWithOptions(table, opts)
+	if err != nil {
+		fmt.Printf("Could not synthesize %s: %v\n", *name, err)
+		return 1
+	}
+	fmt.Print(c)
+	return 0
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/arneph/mercury/logic/bdd"
+)
+
+func runTable(args []string) int {
+	flags := newFlagSet("table")
+	order := flags.String("order", "fanin", "BDD variable ordering: declaration, interleaved or fanin")
+	args, ok := parseFlags(flags, args, 2, 2)
+	if !ok {
+		return 1
+	}
+	var ordering bdd.Ordering
+	switch *order {
+	case "declaration":
+		ordering = bdd.DECLARATION_ORDER
+	case "interleaved":
+		ordering = bdd.INTERLEAVED_ORDER
+	case "fanin":
+		ordering = bdd.FANIN_ORDER
+	default:
+		fmt.Printf("Unknown variable ordering: %s\n", *order)
+		return 1
+	}
+	system, _, ok := loadSystem(args[0])
+	if !ok {
+		return 1
+	}
+	c, ok := lookupComponent(system, args[1])
+	if !ok {
+		return 1
+	}
+	circuit, err := bdd.Build(c, ordering)
+	if err != nil {
+		fmt.Printf("Could not build BDD: %v\n", err)
+		return 1
+	}
+	if err := circuit.WriteTruthTable(os.Stdout); err != nil {
+		fmt.Printf("Could not write truth table: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	errors "go/scanner"
+	positions "go/token"
+	"os"
+	"sort"
+
+	"github.com/arneph/mercury/logic"
+	"github.com/arneph/mercury/logic/simulation"
+)
+
+func runTests(args []string) int {
+	flags := newFlagSet("test")
+	imports := flags.String("import", "", "netlist file whose components the tests may use")
+	fourValued := flags.Bool("four-valued", false, "simulate with X for uninitialized and Z for undriven wires")
+	settleLimit := flags.Int("settle-limit", simulation.DefaultSettleLimit, "maximum number of iterations to settle after each input change")
+	vcdDir := flags.String("vcd", "", "directory to write a VCD waveform of each test to")
+	vcdInternal := flags.Bool("vcd-internal", false, "include internal wires in VCD waveforms")
+	parallel := flags.Int("parallel", 1, "number of tests to run concurrently")
+	args, ok := parseFlags(flags, args, 1, 1)
+	if !ok {
+		return 1
+	}
+	if *parallel < 1 {
+		fmt.Printf("Invalid number of parallel tests: %d\n", *parallel)
+		return 1
+	} else if *settleLimit < 1 {
+		fmt.Printf("Invalid settle limit: %d\n", *settleLimit)
+		return 1
+	}
+	runner := simulation.NewRunner(simulation.RunOptions{
+		Options:     simulation.Options{SettleLimit: *settleLimit, FourValued: *fourValued},
+		VCDDir:      *vcdDir,
+		VCDInternal: *vcdInternal,
+	})
+	system, file, ok := loadTestSystem(args[0], *imports)
+	if !ok {
+		return 1
+	}
+	testNames := make([]string, 0, len(system.Tests))
+	for name := range system.Tests {
+		testNames = append(testNames, name)
+	}
+	sort.Strings(testNames)
+	type result struct {
+		outcome simulation.Outcome
+		errs    errors.ErrorList
+	}
+	results := make([]chan result, len(testNames))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+	go func() {
+		slots := make(chan struct{}, *parallel)
+		for i, name := range testNames {
+			slots <- struct{}{}
+			go func() {
+				defer func() { <-slots }()
+				outcome, errs := runner.RunTest(system.Tests[name], file)
+				results[i] <- result{outcome, errs}
+			}()
+		}
+	}()
+	exitCode := 0
+	for i, name := range testNames {
+		fmt.Printf("test %-20s ", name)
+		r := <-results[i]
+		fmt.Println(r.outcome)
+		if r.outcome != simulation.PASS {
+			errors.PrintError(os.Stderr, r.errs)
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+func loadTestSystem(path, imports string) (*logic.System, *positions.File, bool) {
+	if imports == "" {
+		return loadSystem(path)
+	}
+	imported, _, ok := loadSystem(imports)
+	if !ok {
+		return nil, nil, false
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("Could not read path: %v\n", err)
+		return nil, nil, false
+	}
+	return buildSystem(path, src, imported)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/arneph/mercury/logic/simulation"
+)
+
+func runTiming(args []string) int {
+	flags := newFlagSet("timing")
+	delay := flags.Int("delay", simulation.DefaultDelay, "propagation delay of each gate")
+	trace := flags.Bool("trace", false, "print every wire transition")
+	vcdDir := flags.String("vcd", "", "directory to write a VCD waveform of the test to")
+	vcdInternal := flags.Bool("vcd-internal", false, "include internal wires in the VCD waveform")
+	args, ok := parseFlags(flags, args, 2, 2)
+	if !ok {
+		return 1
+	}
+	system, file, ok := loadSystem(args[0])
+	if !ok {
+		return 1
+	}
+	test, ok := system.Tests[args[1]]
+	if !ok {
+		fmt.Printf("Undefined test: %s\n", args[1])
+		return 1
+	}
+	timing, err := simulation.TimeTest(test, *delay, simulation.RunOptions{VCDDir: *vcdDir, VCDInternal: *vcdInternal})
+	if timing != nil {
+		for i, step := range timing.Steps {
+			location := fmt.Sprintf("step %d", i+1)
+			if file != nil {
+				location = file.Position(step.Pos).String()
+			}
+			if err != nil && i == len(timing.Steps)-1 {
+				fmt.Printf("%s: %v\n", location, err)
+				return 1
+			}
+			fmt.Printf("%s: settled after %d, %d transitions", location, simulation.SettleTime(step.Start, step.Transitions), len(step.Transitions))
+			var glitches []string
+			for _, w := range simulation.Glitches(step.Transitions) {
+				if slices.Contains(timing.Netlist.Outputs, w) {
+					glitches = append(glitches, timing.Netlist.WireName(w))
+				}
+			}
+			if len(glitches) > 0 {
+				fmt.Printf(", glitches on %s", strings.Join(glitches, ", "))
+			}
+			fmt.Println()
+			if *trace {
+				for _, t := range step.Transitions {
+					v := 0
+					if t.Value {
+						v = 1
+					}
+					fmt.Printf("    %6d  %s = %d\n", t.Time, timing.Netlist.WireName(t.Wire), v)
+				}
+			}
+		}
+	}
+	if err != nil {
+		fmt.Printf("Timing simulation of %s failed: %v\n", test.Name(), err)
+		return 1
+	}
+	return 0
+}